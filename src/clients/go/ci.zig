@@ -34,10 +34,10 @@ pub fn tests(shell: *Shell, gpa: std.mem.Allocator) !void {
     // Building the server before running the integrated tests:
     try shell.zig("build -Drelease -Dconfig=production", .{});
     try shell.exec("go test", .{});
-    {
-        log.info("testing `types` package helpers", .{});
+    inline for (.{ "types", "expiry", "statement", "timeseries", "idempotency", "userdata", "amount", "registry", "chart", "reversal", "journal", "transaction", "fx", "split", "netting" }) |package| {
+        log.info("testing `{s}` package helpers", .{package});
 
-        try shell.pushd("./pkg/types");
+        try shell.pushd("./pkg/" ++ package);
         defer shell.popd();
 
         try shell.exec("go test", .{});
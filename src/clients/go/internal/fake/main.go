@@ -0,0 +1,765 @@
+// Package fake implements an in-memory subset of the TigerBeetle state machine.
+//
+// It exists so that the helper packages built on top of the client can be tested without a
+// running cluster. It follows the semantics of `src/state_machine.zig` for the operations it
+// supports, but it is not a replacement for testing against a real replica.
+package fake
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+type pendingStatus uint8
+
+const (
+	pendingNone pendingStatus = iota
+	pendingPending
+	pendingPosted
+	pendingVoided
+	pendingExpired
+)
+
+type state struct {
+	accounts      map[types.Uint128]types.Account
+	transfers     map[types.Uint128]types.Transfer
+	pendingStatus map[types.Uint128]pendingStatus
+	// Transfer IDs in commit (timestamp) order.
+	ordered []types.Uint128
+	// Historical balances for accounts with the `History` flag, in commit order.
+	balances map[types.Uint128][]types.AccountBalance
+}
+
+func (s *state) clone() state {
+	clone := state{
+		accounts:      make(map[types.Uint128]types.Account, len(s.accounts)),
+		transfers:     make(map[types.Uint128]types.Transfer, len(s.transfers)),
+		pendingStatus: make(map[types.Uint128]pendingStatus, len(s.pendingStatus)),
+		// The slices below are append-only, so sharing the backing arrays is safe.
+		ordered:  s.ordered,
+		balances: make(map[types.Uint128][]types.AccountBalance, len(s.balances)),
+	}
+	for k, v := range s.accounts {
+		clone.accounts[k] = v
+	}
+	for k, v := range s.transfers {
+		clone.transfers[k] = v
+	}
+	for k, v := range s.pendingStatus {
+		clone.pendingStatus[k] = v
+	}
+	for k, v := range s.balances {
+		clone.balances[k] = v
+	}
+	return clone
+}
+
+// Cluster is an in-memory, single-replica cluster.
+// It is safe to use from multiple goroutines.
+type Cluster struct {
+	mutex     sync.Mutex
+	clock     uint64
+	timestamp uint64
+	state     state
+	closed    bool
+}
+
+// New returns an empty cluster whose clock starts at `now`.
+func New(now time.Time) *Cluster {
+	return &Cluster{
+		clock: uint64(now.UnixNano()),
+		state: state{
+			accounts:      make(map[types.Uint128]types.Account),
+			transfers:     make(map[types.Uint128]types.Transfer),
+			pendingStatus: make(map[types.Uint128]pendingStatus),
+			balances:      make(map[types.Uint128][]types.AccountBalance),
+		},
+	}
+}
+
+// Now returns the cluster's wall clock.
+func (c *Cluster) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return time.Unix(0, int64(c.clock))
+}
+
+// Advance moves the cluster's wall clock forward, expiring any pending transfers whose timeout
+// has elapsed.
+func (c *Cluster) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.clock += uint64(d)
+	c.expire()
+}
+
+func (c *Cluster) tick() uint64 {
+	c.timestamp++
+	if c.timestamp < c.clock {
+		c.timestamp = c.clock
+	}
+	return c.timestamp
+}
+
+func (c *Cluster) expire() {
+	for _, id := range c.state.ordered {
+		if c.state.pendingStatus[id] != pendingPending {
+			continue
+		}
+		p := c.state.transfers[id]
+		if p.Timeout == 0 || p.Timestamp+uint64(p.Timeout)*uint64(time.Second) > c.clock {
+			continue
+		}
+		dr := c.state.accounts[p.DebitAccountID]
+		cr := c.state.accounts[p.CreditAccountID]
+		dr.DebitsPending = sub(dr.DebitsPending, p.Amount)
+		cr.CreditsPending = sub(cr.CreditsPending, p.Amount)
+		c.state.accounts[dr.ID] = dr
+		c.state.accounts[cr.ID] = cr
+		c.state.pendingStatus[id] = pendingExpired
+	}
+}
+
+func (c *Cluster) CreateAccounts(accounts []types.Account) ([]types.AccountEventResult, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil, errors.ErrClientClosed{}
+	}
+	if len(accounts) == 0 {
+		return nil, errors.ErrEmptyBatch{}
+	}
+	c.expire()
+
+	results := []types.AccountEventResult{}
+	c.batch(len(accounts), func(i int) bool {
+		return accounts[i].AccountFlags().Linked
+	}, func(i int) uint32 {
+		return uint32(c.createAccount(accounts[i]))
+	}, func(i int, result uint32) {
+		results = append(results, types.AccountEventResult{
+			Index:  uint32(i),
+			Result: types.CreateAccountResult(result),
+		})
+	})
+	return results, nil
+}
+
+func (c *Cluster) CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil, errors.ErrClientClosed{}
+	}
+	if len(transfers) == 0 {
+		return nil, errors.ErrEmptyBatch{}
+	}
+	c.expire()
+
+	results := []types.TransferEventResult{}
+	c.batch(len(transfers), func(i int) bool {
+		return transfers[i].TransferFlags().Linked
+	}, func(i int) uint32 {
+		return uint32(c.createTransfer(transfers[i]))
+	}, func(i int, result uint32) {
+		results = append(results, types.TransferEventResult{
+			Index:  uint32(i),
+			Result: types.CreateTransferResult(result),
+		})
+	})
+	return results, nil
+}
+
+// batch applies `count` events, rolling back linked chains atomically.
+// Result codes 1 and 2 are `linked_event_failed` and `linked_event_chain_open` for both
+// accounts and transfers.
+func (c *Cluster) batch(
+	count int,
+	linked func(i int) bool,
+	apply func(i int) uint32,
+	report func(i int, result uint32),
+) {
+	const linkedEventFailed = 1
+	const linkedEventChainOpen = 2
+
+	chainStart := -1
+	var chainSnapshot state
+	var chainTimestamp uint64
+	chainFailed := false
+	chainResults := map[int]uint32{}
+
+	for i := 0; i < count; i++ {
+		isLinked := linked(i)
+		if chainStart == -1 && isLinked {
+			chainStart = i
+			chainSnapshot = c.state.clone()
+			chainTimestamp = c.timestamp
+			chainFailed = false
+			chainResults = map[int]uint32{}
+		}
+
+		var result uint32
+		if chainStart != -1 && chainFailed {
+			result = linkedEventFailed
+		} else if chainStart != -1 && isLinked && i == count-1 {
+			result = linkedEventChainOpen
+		} else {
+			result = apply(i)
+		}
+
+		if chainStart == -1 {
+			if result != 0 {
+				report(i, result)
+			}
+			continue
+		}
+
+		if result != 0 && !chainFailed {
+			chainFailed = true
+			c.state = chainSnapshot
+			c.timestamp = chainTimestamp
+		}
+		chainResults[i] = result
+
+		if !isLinked || i == count-1 {
+			// End of the chain.
+			if chainFailed {
+				for j := chainStart; j <= i; j++ {
+					result, ok := chainResults[j]
+					if !ok || result == 0 {
+						result = linkedEventFailed
+					}
+					report(j, result)
+				}
+			}
+			chainStart = -1
+		}
+	}
+}
+
+func (c *Cluster) createAccount(a types.Account) types.CreateAccountResult {
+	flags := a.AccountFlags()
+	if a.Reserved != 0 {
+		return types.AccountReservedField
+	}
+	if a.Flags&^uint16(0xf) != 0 {
+		return types.AccountReservedFlag
+	}
+	if a.ID == (types.Uint128{}) {
+		return types.AccountIDMustNotBeZero
+	}
+	if a.ID == maxUint128 {
+		return types.AccountIDMustNotBeIntMax
+	}
+	if flags.DebitsMustNotExceedCredits && flags.CreditsMustNotExceedDebits {
+		return types.AccountFlagsAreMutuallyExclusive
+	}
+	if a.DebitsPending != (types.Uint128{}) {
+		return types.AccountDebitsPendingMustBeZero
+	}
+	if a.DebitsPosted != (types.Uint128{}) {
+		return types.AccountDebitsPostedMustBeZero
+	}
+	if a.CreditsPending != (types.Uint128{}) {
+		return types.AccountCreditsPendingMustBeZero
+	}
+	if a.CreditsPosted != (types.Uint128{}) {
+		return types.AccountCreditsPostedMustBeZero
+	}
+	if a.Ledger == 0 {
+		return types.AccountLedgerMustNotBeZero
+	}
+	if a.Code == 0 {
+		return types.AccountCodeMustNotBeZero
+	}
+	if a.Timestamp != 0 {
+		return types.AccountTimestampMustBeZero
+	}
+
+	if e, ok := c.state.accounts[a.ID]; ok {
+		switch {
+		case a.Flags != e.Flags:
+			return types.AccountExistsWithDifferentFlags
+		case a.UserData128 != e.UserData128:
+			return types.AccountExistsWithDifferentUserData128
+		case a.UserData64 != e.UserData64:
+			return types.AccountExistsWithDifferentUserData64
+		case a.UserData32 != e.UserData32:
+			return types.AccountExistsWithDifferentUserData32
+		case a.Ledger != e.Ledger:
+			return types.AccountExistsWithDifferentLedger
+		case a.Code != e.Code:
+			return types.AccountExistsWithDifferentCode
+		}
+		return types.AccountExists
+	}
+
+	a.Timestamp = c.tick()
+	c.state.accounts[a.ID] = a
+	return types.AccountOK
+}
+
+func (c *Cluster) createTransfer(t types.Transfer) types.CreateTransferResult {
+	flags := t.TransferFlags()
+	if t.Timestamp != 0 {
+		return types.TransferTimestampMustBeZero
+	}
+	if t.Flags&^uint16(0x3f) != 0 {
+		return types.TransferReservedFlag
+	}
+	if t.ID == (types.Uint128{}) {
+		return types.TransferIDMustNotBeZero
+	}
+	if t.ID == maxUint128 {
+		return types.TransferIDMustNotBeIntMax
+	}
+	if flags.PostPendingTransfer || flags.VoidPendingTransfer {
+		return c.postOrVoidPendingTransfer(t)
+	}
+
+	if t.DebitAccountID == (types.Uint128{}) {
+		return types.TransferDebitAccountIDMustNotBeZero
+	}
+	if t.DebitAccountID == maxUint128 {
+		return types.TransferDebitAccountIDMustNotBeIntMax
+	}
+	if t.CreditAccountID == (types.Uint128{}) {
+		return types.TransferCreditAccountIDMustNotBeZero
+	}
+	if t.CreditAccountID == maxUint128 {
+		return types.TransferCreditAccountIDMustNotBeIntMax
+	}
+	if t.CreditAccountID == t.DebitAccountID {
+		return types.TransferAccountsMustBeDifferent
+	}
+	if t.PendingID != (types.Uint128{}) {
+		return types.TransferPendingIDMustBeZero
+	}
+	if !flags.Pending && t.Timeout != 0 {
+		return types.TransferTimeoutReservedForPendingTransfer
+	}
+	if !flags.BalancingDebit && !flags.BalancingCredit && t.Amount == (types.Uint128{}) {
+		return types.TransferAmountMustNotBeZero
+	}
+	if t.Ledger == 0 {
+		return types.TransferLedgerMustNotBeZero
+	}
+	if t.Code == 0 {
+		return types.TransferCodeMustNotBeZero
+	}
+
+	dr, ok := c.state.accounts[t.DebitAccountID]
+	if !ok {
+		return types.TransferDebitAccountNotFound
+	}
+	cr, ok := c.state.accounts[t.CreditAccountID]
+	if !ok {
+		return types.TransferCreditAccountNotFound
+	}
+	if dr.Ledger != cr.Ledger {
+		return types.TransferAccountsMustHaveTheSameLedger
+	}
+	if t.Ledger != dr.Ledger {
+		return types.TransferTransferMustHaveTheSameLedgerAsAccounts
+	}
+
+	if e, ok := c.state.transfers[t.ID]; ok {
+		switch {
+		case t.Flags != e.Flags:
+			return types.TransferExistsWithDifferentFlags
+		case t.DebitAccountID != e.DebitAccountID:
+			return types.TransferExistsWithDifferentDebitAccountID
+		case t.CreditAccountID != e.CreditAccountID:
+			return types.TransferExistsWithDifferentCreditAccountID
+		case t.Amount != e.Amount:
+			return types.TransferExistsWithDifferentAmount
+		case t.UserData128 != e.UserData128:
+			return types.TransferExistsWithDifferentUserData128
+		case t.UserData64 != e.UserData64:
+			return types.TransferExistsWithDifferentUserData64
+		case t.UserData32 != e.UserData32:
+			return types.TransferExistsWithDifferentUserData32
+		case t.Timeout != e.Timeout:
+			return types.TransferExistsWithDifferentTimeout
+		case t.Code != e.Code:
+			return types.TransferExistsWithDifferentCode
+		}
+		return types.TransferExists
+	}
+
+	amount := t.Amount
+	if flags.BalancingDebit || flags.BalancingCredit {
+		if amount == (types.Uint128{}) {
+			amount = types.ToUint128(^uint64(0))
+		}
+	}
+	if flags.BalancingDebit {
+		amount = minimum(amount, saturatingSub(dr.CreditsPosted, add(dr.DebitsPosted, dr.DebitsPending)))
+		if amount == (types.Uint128{}) {
+			return types.TransferExceedsCredits
+		}
+	}
+	if flags.BalancingCredit {
+		amount = minimum(amount, saturatingSub(cr.DebitsPosted, add(cr.CreditsPosted, cr.CreditsPending)))
+		if amount == (types.Uint128{}) {
+			return types.TransferExceedsDebits
+		}
+	}
+
+	if flags.Pending {
+		if overflows(amount, dr.DebitsPending) {
+			return types.TransferOverflowsDebitsPending
+		}
+		if overflows(amount, cr.CreditsPending) {
+			return types.TransferOverflowsCreditsPending
+		}
+	}
+	if overflows(amount, dr.DebitsPosted) {
+		return types.TransferOverflowsDebitsPosted
+	}
+	if overflows(amount, cr.CreditsPosted) {
+		return types.TransferOverflowsCreditsPosted
+	}
+	if overflows(amount, add(dr.DebitsPending, dr.DebitsPosted)) {
+		return types.TransferOverflowsDebits
+	}
+	if overflows(amount, add(cr.CreditsPending, cr.CreditsPosted)) {
+		return types.TransferOverflowsCredits
+	}
+	if dr.AccountFlags().DebitsMustNotExceedCredits &&
+		greater(add(add(dr.DebitsPending, dr.DebitsPosted), amount), dr.CreditsPosted) {
+		return types.TransferExceedsCredits
+	}
+	if cr.AccountFlags().CreditsMustNotExceedDebits &&
+		greater(add(add(cr.CreditsPending, cr.CreditsPosted), amount), cr.DebitsPosted) {
+		return types.TransferExceedsDebits
+	}
+
+	t.Amount = amount
+	t.Timestamp = c.tick()
+	if flags.Pending {
+		dr.DebitsPending = add(dr.DebitsPending, amount)
+		cr.CreditsPending = add(cr.CreditsPending, amount)
+		c.state.pendingStatus[t.ID] = pendingPending
+	} else {
+		dr.DebitsPosted = add(dr.DebitsPosted, amount)
+		cr.CreditsPosted = add(cr.CreditsPosted, amount)
+	}
+	c.commit(t, dr, cr)
+	return types.TransferOK
+}
+
+func (c *Cluster) postOrVoidPendingTransfer(t types.Transfer) types.CreateTransferResult {
+	flags := t.TransferFlags()
+	if flags.PostPendingTransfer && flags.VoidPendingTransfer {
+		return types.TransferFlagsAreMutuallyExclusive
+	}
+	if flags.Pending || flags.BalancingDebit || flags.BalancingCredit {
+		return types.TransferFlagsAreMutuallyExclusive
+	}
+	if t.PendingID == (types.Uint128{}) {
+		return types.TransferPendingIDMustNotBeZero
+	}
+	if t.PendingID == maxUint128 {
+		return types.TransferPendingIDMustNotBeIntMax
+	}
+	if t.PendingID == t.ID {
+		return types.TransferPendingIDMustBeDifferent
+	}
+	if t.Timeout != 0 {
+		return types.TransferTimeoutReservedForPendingTransfer
+	}
+
+	p, ok := c.state.transfers[t.PendingID]
+	if !ok {
+		return types.TransferPendingTransferNotFound
+	}
+	if !p.TransferFlags().Pending {
+		return types.TransferPendingTransferNotPending
+	}
+	if t.DebitAccountID != (types.Uint128{}) && t.DebitAccountID != p.DebitAccountID {
+		return types.TransferPendingTransferHasDifferentDebitAccountID
+	}
+	if t.CreditAccountID != (types.Uint128{}) && t.CreditAccountID != p.CreditAccountID {
+		return types.TransferPendingTransferHasDifferentCreditAccountID
+	}
+	if t.Ledger != 0 && t.Ledger != p.Ledger {
+		return types.TransferPendingTransferHasDifferentLedger
+	}
+	if t.Code != 0 && t.Code != p.Code {
+		return types.TransferPendingTransferHasDifferentCode
+	}
+
+	amount := t.Amount
+	if amount == (types.Uint128{}) {
+		amount = p.Amount
+	}
+	if greater(amount, p.Amount) {
+		return types.TransferExceedsPendingTransferAmount
+	}
+	if flags.VoidPendingTransfer && amount != p.Amount {
+		return types.TransferPendingTransferHasDifferentAmount
+	}
+
+	if e, ok := c.state.transfers[t.ID]; ok {
+		switch {
+		case t.Flags != e.Flags:
+			return types.TransferExistsWithDifferentFlags
+		case amount != e.Amount:
+			return types.TransferExistsWithDifferentAmount
+		case t.PendingID != e.PendingID:
+			return types.TransferExistsWithDifferentPendingID
+		}
+		return types.TransferExists
+	}
+
+	switch c.state.pendingStatus[p.ID] {
+	case pendingPosted:
+		return types.TransferPendingTransferAlreadyPosted
+	case pendingVoided:
+		return types.TransferPendingTransferAlreadyVoided
+	case pendingExpired:
+		return types.TransferPendingTransferExpired
+	}
+
+	dr := c.state.accounts[p.DebitAccountID]
+	cr := c.state.accounts[p.CreditAccountID]
+	dr.DebitsPending = sub(dr.DebitsPending, p.Amount)
+	cr.CreditsPending = sub(cr.CreditsPending, p.Amount)
+	if flags.PostPendingTransfer {
+		dr.DebitsPosted = add(dr.DebitsPosted, amount)
+		cr.CreditsPosted = add(cr.CreditsPosted, amount)
+		c.state.pendingStatus[p.ID] = pendingPosted
+	} else {
+		c.state.pendingStatus[p.ID] = pendingVoided
+	}
+
+	posted := types.Transfer{
+		ID:              t.ID,
+		DebitAccountID:  p.DebitAccountID,
+		CreditAccountID: p.CreditAccountID,
+		Amount:          amount,
+		PendingID:       t.PendingID,
+		UserData128:     t.UserData128,
+		UserData64:      t.UserData64,
+		UserData32:      t.UserData32,
+		Ledger:          p.Ledger,
+		Code:            p.Code,
+		Flags:           t.Flags,
+		Timestamp:       c.tick(),
+	}
+	if posted.UserData128 == (types.Uint128{}) {
+		posted.UserData128 = p.UserData128
+	}
+	if posted.UserData64 == 0 {
+		posted.UserData64 = p.UserData64
+	}
+	if posted.UserData32 == 0 {
+		posted.UserData32 = p.UserData32
+	}
+	c.commit(posted, dr, cr)
+	return types.TransferOK
+}
+
+func (c *Cluster) commit(t types.Transfer, dr types.Account, cr types.Account) {
+	c.state.transfers[t.ID] = t
+	c.state.ordered = append(c.state.ordered, t.ID)
+	c.state.accounts[dr.ID] = dr
+	c.state.accounts[cr.ID] = cr
+
+	for _, account := range []types.Account{dr, cr} {
+		if !account.AccountFlags().History {
+			continue
+		}
+		c.state.balances[account.ID] = append(c.state.balances[account.ID], types.AccountBalance{
+			DebitsPending:  account.DebitsPending,
+			DebitsPosted:   account.DebitsPosted,
+			CreditsPending: account.CreditsPending,
+			CreditsPosted:  account.CreditsPosted,
+			Timestamp:      t.Timestamp,
+		})
+	}
+}
+
+func (c *Cluster) LookupAccounts(accountIDs []types.Uint128) ([]types.Account, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil, errors.ErrClientClosed{}
+	}
+	if len(accountIDs) == 0 {
+		return nil, errors.ErrEmptyBatch{}
+	}
+	c.expire()
+
+	results := []types.Account{}
+	for _, id := range accountIDs {
+		if account, ok := c.state.accounts[id]; ok {
+			results = append(results, account)
+		}
+	}
+	return results, nil
+}
+
+func (c *Cluster) LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil, errors.ErrClientClosed{}
+	}
+	if len(transferIDs) == 0 {
+		return nil, errors.ErrEmptyBatch{}
+	}
+	c.expire()
+
+	results := []types.Transfer{}
+	for _, id := range transferIDs {
+		if transfer, ok := c.state.transfers[id]; ok {
+			results = append(results, transfer)
+		}
+	}
+	return results, nil
+}
+
+// BatchMax is the maximum number of results returned by a single query, as in the real client.
+const BatchMax = 8190
+
+func filterValid(filter types.AccountFilter) bool {
+	flags := filter.AccountFilterFlags()
+	return filter.AccountID != (types.Uint128{}) && filter.AccountID != maxUint128 &&
+		filter.TimestampMin != ^uint64(0) &&
+		filter.TimestampMax != ^uint64(0) &&
+		(filter.TimestampMax == 0 || filter.TimestampMin <= filter.TimestampMax) &&
+		filter.Limit != 0 &&
+		(flags.Debits || flags.Credits) &&
+		filter.Flags&^uint32(0x7) == 0 &&
+		filter.Reserved == [24]uint8{}
+}
+
+func (c *Cluster) scan(filter types.AccountFilter) []types.Transfer {
+	if !filterValid(filter) {
+		return []types.Transfer{}
+	}
+	flags := filter.AccountFilterFlags()
+	max := filter.TimestampMax
+	if max == 0 {
+		max = ^uint64(0)
+	}
+
+	matches := []types.Transfer{}
+	for _, id := range c.state.ordered {
+		t := c.state.transfers[id]
+		if t.Timestamp < filter.TimestampMin || t.Timestamp > max {
+			continue
+		}
+		if (flags.Debits && t.DebitAccountID == filter.AccountID) ||
+			(flags.Credits && t.CreditAccountID == filter.AccountID) {
+			matches = append(matches, t)
+		}
+	}
+	if flags.Reversed {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Timestamp > matches[j].Timestamp
+		})
+	}
+
+	limit := int(filter.Limit)
+	if limit > BatchMax {
+		limit = BatchMax
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func (c *Cluster) GetAccountTransfers(filter types.AccountFilter) ([]types.Transfer, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil, errors.ErrClientClosed{}
+	}
+	c.expire()
+
+	return c.scan(filter), nil
+}
+
+func (c *Cluster) GetAccountBalances(filter types.AccountFilter) ([]types.AccountBalance, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil, errors.ErrClientClosed{}
+	}
+	c.expire()
+
+	account, ok := c.state.accounts[filter.AccountID]
+	if !ok || !account.AccountFlags().History {
+		return []types.AccountBalance{}, nil
+	}
+
+	balances := c.state.balances[filter.AccountID]
+	results := []types.AccountBalance{}
+	for _, transfer := range c.scan(filter) {
+		index := sort.Search(len(balances), func(i int) bool {
+			return balances[i].Timestamp >= transfer.Timestamp
+		})
+		results = append(results, balances[index])
+	}
+	return results, nil
+}
+
+func (c *Cluster) Nop() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return errors.ErrClientClosed{}
+	}
+	return nil
+}
+
+func (c *Cluster) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = true
+}
+
+var maxUint128 = types.BytesToUint128([16]byte{
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+})
+
+func add(a, b types.Uint128) types.Uint128 {
+	return a.Add(b)
+}
+
+func sub(a, b types.Uint128) types.Uint128 {
+	return a.Sub(b)
+}
+
+func saturatingSub(a, b types.Uint128) types.Uint128 {
+	difference, err := a.SubChecked(b)
+	if err != nil {
+		return types.Uint128{}
+	}
+	return difference
+}
+
+func overflows(a, b types.Uint128) bool {
+	_, err := a.AddChecked(b)
+	return err != nil
+}
+
+func greater(a, b types.Uint128) bool {
+	return a.Cmp(b) > 0
+}
+
+func minimum(a, b types.Uint128) types.Uint128 {
+	if greater(a, b) {
+		return b
+	}
+	return a
+}
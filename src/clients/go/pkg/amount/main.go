@@ -0,0 +1,343 @@
+// Package amount represents transfer amounts and balances as decimal values of an asset.
+//
+// TigerBeetle amounts are integers of an asset's minor unit, such as cents or satoshis. An Amount
+// pairs those units with the asset's scale, the number of decimal places of its major unit, so
+// that 1234 units at scale 2 is "12.34". A LedgerRegistry declares the asset and scale of each
+// ledger.
+package amount
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// ScaleMax is the largest scale, as 10^38 is the largest power of ten that fits in a Uint128.
+const ScaleMax = 38
+
+// Amount is a non-negative decimal value: Units / 10^Scale.
+type Amount struct {
+	units types.Uint128
+	scale uint8
+}
+
+// RoundingMode selects how a value with more decimal places than the scale is rounded.
+type RoundingMode uint8
+
+const (
+	// Exact rejects values that would need rounding.
+	Exact RoundingMode = iota
+	// RoundDown truncates, towards zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundHalfUp rounds to the nearest value, and halves away from zero.
+	RoundHalfUp
+	// RoundHalfEven rounds to the nearest value, and halves to the even neighbor (banker's rounding).
+	RoundHalfEven
+)
+
+type ErrInvalidScale struct {
+	Scale int
+}
+
+func (e ErrInvalidScale) Error() string {
+	return fmt.Sprintf("Scale %d is greater than %d.", e.Scale, ScaleMax)
+}
+
+type ErrScaleMismatch struct {
+	A uint8
+	B uint8
+}
+
+func (e ErrScaleMismatch) Error() string {
+	return fmt.Sprintf("Amounts have different scales %d and %d.", e.A, e.B)
+}
+
+type ErrInexact struct{}
+
+func (e ErrInexact) Error() string { return "Amount cannot be represented exactly at this scale." }
+
+type ErrOverflow struct{}
+
+func (e ErrOverflow) Error() string { return "Amount does not fit in a Uint128." }
+
+type ErrUnderflow struct{}
+
+func (e ErrUnderflow) Error() string { return "Amount cannot be negative." }
+
+type ErrDivisionByZero struct{}
+
+func (e ErrDivisionByZero) Error() string { return "Denominator must not be zero." }
+
+type ErrSyntax struct {
+	Value string
+}
+
+func (e ErrSyntax) Error() string {
+	return fmt.Sprintf("Invalid amount %q.", e.Value)
+}
+
+// New returns the amount of `units` minor units at the given scale.
+func New(units types.Uint128, scale uint8) (Amount, error) {
+	if scale > ScaleMax {
+		return Amount{}, ErrInvalidScale{Scale: int(scale)}
+	}
+	return Amount{units: units, scale: scale}, nil
+}
+
+// Parse parses a non-negative decimal, such as "12.34", at the given scale.
+// Values with more decimal places than the scale are rounded with the given mode.
+func Parse(s string, scale uint8, mode RoundingMode) (Amount, error) {
+	if scale > ScaleMax {
+		return Amount{}, ErrInvalidScale{Scale: int(scale)}
+	}
+	whole, fraction := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		whole, fraction = s[:dot], s[dot+1:]
+		if fraction == "" {
+			return Amount{}, ErrSyntax{Value: s}
+		}
+	}
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) {
+		return Amount{}, ErrSyntax{Value: s}
+	}
+
+	// Parse all the digits as units at the scale of the fraction, then rescale.
+	if len(fraction) <= ScaleMax {
+		units, err := types.ParseUint128(whole+fraction, 10)
+		if err == nil {
+			return Amount{units: units, scale: uint8(len(fraction))}.Rescale(scale, mode)
+		}
+	}
+	// Too many digits for a Uint128, although they may still round to one.
+	var value big.Int
+	value.SetString(whole+fraction, 10)
+	units, err := rescaleBig(&value, len(fraction), int(scale), mode)
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{units: units, scale: scale}, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Units returns the amount in minor units, as used for `Transfer.Amount`.
+func (a Amount) Units() types.Uint128 {
+	return a.units
+}
+
+func (a Amount) Scale() uint8 {
+	return a.scale
+}
+
+func (a Amount) IsZero() bool {
+	return a.units.IsZero()
+}
+
+// String formats the amount with exactly Scale decimal places, e.g. "12.30".
+func (a Amount) String() string {
+	digits := a.units.DecimalString()
+	scale := int(a.scale)
+	if scale == 0 {
+		return digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// Rescale returns the amount at another scale, rounding with the given mode if the scale is
+// reduced, or failing if the units overflow when the scale is increased.
+func (a Amount) Rescale(scale uint8, mode RoundingMode) (Amount, error) {
+	if scale > ScaleMax {
+		return Amount{}, ErrInvalidScale{Scale: int(scale)}
+	}
+	if scale >= a.scale {
+		units, err := a.units.MulChecked(pow10(scale - a.scale))
+		if err != nil {
+			return Amount{}, ErrOverflow{}
+		}
+		return Amount{units: units, scale: scale}, nil
+	}
+
+	divisor := pow10(a.scale - scale)
+	quotient, remainder := a.units.QuoRem(divisor)
+	if !remainder.IsZero() {
+		up := false
+		switch mode {
+		case Exact:
+			return Amount{}, ErrInexact{}
+		case RoundDown:
+		case RoundUp:
+			up = true
+		case RoundHalfUp, RoundHalfEven:
+			// Compare the remainder with half of the divisor, without overflowing.
+			half := divisor.Rsh(1)
+			switch remainder.Cmp(half) {
+			case 1:
+				up = true
+			case 0:
+				lo := quotient.Bytes()[0]
+				up = mode == RoundHalfUp || lo%2 == 1
+			}
+		}
+		if up {
+			var err error
+			quotient, err = quotient.AddChecked(types.ToUint128(1))
+			if err != nil {
+				return Amount{}, ErrOverflow{}
+			}
+		}
+	}
+	return Amount{units: quotient, scale: scale}, nil
+}
+
+func rescaleBig(value *big.Int, from int, to int, mode RoundingMode) (types.Uint128, error) {
+	if to >= from {
+		value.Mul(value, pow10Big(to-from))
+		return uint128FromBig(value)
+	}
+	return quo(value, pow10Big(from-to), mode)
+}
+
+// MulDiv returns `units * numerator / denominator`, rounded with the given mode, for example to
+// convert units at an exchange rate. The product may exceed a Uint128. A zero denominator returns
+// ErrDivisionByZero.
+func MulDiv(
+	units types.Uint128,
+	numerator types.Uint128,
+	denominator types.Uint128,
+	mode RoundingMode,
+) (types.Uint128, error) {
+	if denominator.IsZero() {
+		return types.Uint128{}, ErrDivisionByZero{}
+	}
+	value := units.BigInt()
+	factor := numerator.BigInt()
+	divisor := denominator.BigInt()
+	value.Mul(&value, &factor)
+	return quo(&value, &divisor, mode)
+}
+
+func quo(value *big.Int, divisor *big.Int, mode RoundingMode) (types.Uint128, error) {
+	quotient, remainder := new(big.Int).QuoRem(value, divisor, new(big.Int))
+	if remainder.Sign() != 0 {
+		twice := new(big.Int).Lsh(remainder, 1)
+		up := false
+		switch mode {
+		case Exact:
+			return types.Uint128{}, ErrInexact{}
+		case RoundDown:
+		case RoundUp:
+			up = true
+		case RoundHalfUp, RoundHalfEven:
+			switch twice.Cmp(divisor) {
+			case 1:
+				up = true
+			case 0:
+				up = mode == RoundHalfUp || quotient.Bit(0) == 1
+			}
+		}
+		if up {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return uint128FromBig(quotient)
+}
+
+func uint128FromBig(value *big.Int) (types.Uint128, error) {
+	result, err := types.BigIntToUint128Checked(*value)
+	if err != nil {
+		return types.Uint128{}, ErrOverflow{}
+	}
+	return result, nil
+}
+
+func pow10Big(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// pow10 returns 10^n, for n <= ScaleMax.
+func pow10(n uint8) types.Uint128 {
+	result := types.ToUint128(1)
+	for i := uint8(0); i < n; i++ {
+		result = result.Mul(types.ToUint128(10))
+	}
+	return result
+}
+
+func (a Amount) checkScale(b Amount) error {
+	if a.scale != b.scale {
+		return ErrScaleMismatch{A: a.scale, B: b.scale}
+	}
+	return nil
+}
+
+// Add returns `a + b`. Both amounts must have the same scale.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if err := a.checkScale(b); err != nil {
+		return Amount{}, err
+	}
+	units, err := a.units.AddChecked(b.units)
+	if err != nil {
+		return Amount{}, ErrOverflow{}
+	}
+	return Amount{units: units, scale: a.scale}, nil
+}
+
+// Sub returns `a - b`, or ErrUnderflow if b is greater. Both amounts must have the same scale.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if err := a.checkScale(b); err != nil {
+		return Amount{}, err
+	}
+	units, err := a.units.SubChecked(b.units)
+	if err != nil {
+		return Amount{}, ErrUnderflow{}
+	}
+	return Amount{units: units, scale: a.scale}, nil
+}
+
+// Mul returns `a * n`.
+func (a Amount) Mul(n uint64) (Amount, error) {
+	units, err := a.units.MulChecked(types.ToUint128(n))
+	if err != nil {
+		return Amount{}, ErrOverflow{}
+	}
+	return Amount{units: units, scale: a.scale}, nil
+}
+
+// Cmp compares the values of two amounts, which may have different scales, and returns -1, 0,
+// or +1.
+func (a Amount) Cmp(b Amount) int {
+	if a.scale == b.scale {
+		return a.units.Cmp(b.units)
+	}
+	x, y := a.units.BigInt(), b.units.BigInt()
+	if a.scale < b.scale {
+		x.Mul(&x, pow10Big(int(b.scale-a.scale)))
+	} else {
+		y.Mul(&y, pow10Big(int(a.scale-b.scale)))
+	}
+	return x.Cmp(&y)
+}
+
+// UnitsAt returns the amount in minor units of an asset with the given scale, or ErrInexact if
+// the amount has more decimal places than the scale.
+func (a Amount) UnitsAt(scale uint8) (types.Uint128, error) {
+	result, err := a.Rescale(scale, Exact)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	return result.units, nil
+}
@@ -0,0 +1,239 @@
+package amount
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func mustParse(t *testing.T, s string, scale uint8) Amount {
+	amount, err := Parse(s, scale, Exact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amount
+}
+
+func maxAmount(t *testing.T, scale uint8) Amount {
+	var bytes [16]byte
+	for i := range bytes {
+		bytes[i] = 0xff
+	}
+	amount, err := New(types.BytesToUint128(bytes), scale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amount
+}
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		input    string
+		scale    uint8
+		mode     RoundingMode
+		expected string
+		units    uint64
+	}{
+		{"12.34", 2, Exact, "12.34", 1234},
+		{"12.3", 2, Exact, "12.30", 1230},
+		{"12", 2, Exact, "12.00", 1200},
+		{"0.05", 2, Exact, "0.05", 5},
+		{"007", 0, Exact, "7", 7},
+		{"1.005", 2, RoundDown, "1.00", 100},
+		{"1.001", 2, RoundUp, "1.01", 101},
+		{"1.005", 2, RoundHalfUp, "1.01", 101},
+		{"1.004", 2, RoundHalfUp, "1.00", 100},
+		{"1.005", 2, RoundHalfEven, "1.00", 100},
+		{"1.015", 2, RoundHalfEven, "1.02", 102},
+		{"1.0051", 2, RoundHalfEven, "1.01", 101},
+		{"0.5", 0, RoundHalfEven, "0", 0},
+	}
+	for _, test := range tests {
+		amount, err := Parse(test.input, test.scale, test.mode)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.input, err)
+		}
+		assert.Equal(t, test.expected, amount.String())
+		assert.Equal(t, types.ToUint128(test.units), amount.Units())
+		assert.Equal(t, test.scale, amount.Scale())
+	}
+
+	// More digits than fit in a Uint128, which still round into one.
+	amount, err := Parse("1."+strings.Repeat("9", 50), 2, RoundDown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "1.99", amount.String())
+
+	for _, input := range []string{"", ".", "1.", ".5", "-1", "+1", "1e3", "1,000", "1_000", " 1"} {
+		_, err := Parse(input, 2, Exact)
+		var syntax ErrSyntax
+		assert.True(t, errors.As(err, &syntax))
+	}
+	_, err = Parse("1.005", 2, Exact)
+	assert.Equal(t, ErrInexact{}, err)
+	_, err = Parse("340282366920938463463374607431768211456", 0, Exact)
+	assert.Equal(t, ErrOverflow{}, err)
+	_, err = Parse("1", ScaleMax+1, Exact)
+	assert.Equal(t, ErrInvalidScale{Scale: ScaleMax + 1}, err)
+}
+
+func Test_Arithmetic(t *testing.T) {
+	a, b := mustParse(t, "10.50", 2), mustParse(t, "0.75", 2)
+
+	sum, err := a.Add(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "11.25", sum.String())
+
+	difference, err := a.Sub(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "9.75", difference.String())
+	_, err = b.Sub(a)
+	assert.Equal(t, ErrUnderflow{}, err)
+
+	product, err := b.Mul(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "2.25", product.String())
+
+	_, err = a.Add(mustParse(t, "1", 0))
+	assert.Equal(t, ErrScaleMismatch{A: 2, B: 0}, err)
+
+	max := maxAmount(t, 0)
+	_, err = max.Add(mustParse(t, "1", 0))
+	assert.Equal(t, ErrOverflow{}, err)
+	_, err = max.Mul(2)
+	assert.Equal(t, ErrOverflow{}, err)
+
+	assert.Equal(t, 1, a.Cmp(b))
+	assert.Equal(t, 0, a.Cmp(mustParse(t, "10.5", 1)))
+	assert.Equal(t, -1, mustParse(t, "10.4999", 4).Cmp(a))
+	assert.Equal(t, 1, max.Cmp(mustParse(t, "1", ScaleMax)))
+}
+
+func Test_Rescale(t *testing.T) {
+	amount := mustParse(t, "1.23", 2)
+
+	units, err := amount.UnitsAt(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(123_000_000), units)
+	_, err = amount.UnitsAt(1)
+	assert.Equal(t, ErrInexact{}, err)
+
+	rounded, err := amount.Rescale(1, RoundHalfEven)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "1.2", rounded.String())
+
+	_, err = maxAmount(t, 0).Rescale(1, Exact)
+	assert.Equal(t, ErrOverflow{}, err)
+	rounded, err = maxAmount(t, ScaleMax).Rescale(0, RoundUp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "4", rounded.String())
+
+	tiny := mustParse(t, "0."+strings.Repeat("0", ScaleMax-1)+"1", ScaleMax)
+	assert.Equal(t, "0.00000000000000000000000000000000000001", tiny.String())
+}
+
+func Test_LedgerRegistry(t *testing.T) {
+	registry := NewLedgerRegistry()
+	if err := registry.Register(1, Asset{Code: "USD", Scale: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(2, Asset{Code: "BTC", Scale: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(1, Asset{Code: "USD", Scale: 2}); err != nil {
+		t.Fatal(err)
+	}
+	err := registry.Register(1, Asset{Code: "EUR", Scale: 2})
+	assert.Equal(t, ErrLedgerRegistered{Ledger: 1, Asset: Asset{Code: "USD", Scale: 2}}, err)
+	assert.Equal(t, ErrInvalidLedger{}, registry.Register(0, Asset{Code: "USD", Scale: 2}))
+
+	formatted, err := registry.Format(2, types.ToUint128(150_000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "0.00150000 BTC", formatted)
+
+	units, err := registry.Parse(1, "12.34")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(1234), units)
+	_, err = registry.Parse(1, "12.345")
+	assert.Equal(t, ErrInexact{}, err)
+
+	units, err = registry.Units(2, mustParse(t, "0.5", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(50_000_000), units)
+
+	balances, err := registry.Balances(types.Account{
+		Ledger:        1,
+		DebitsPosted:  types.ToUint128(1050),
+		CreditsPosted: types.ToUint128(99),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "10.50", balances.DebitsPosted.String())
+	assert.Equal(t,
+		"USD debits_pending=0.00 debits_posted=10.50 credits_pending=0.00 credits_posted=0.99",
+		balances.String(),
+	)
+
+	_, err = registry.Balances(types.Account{Ledger: 3})
+	assert.Equal(t, ErrUnknownLedger{Ledger: 3}, err)
+}
+
+func Test_MulDiv(t *testing.T) {
+	rate := func(units uint64, mode RoundingMode) (types.Uint128, error) {
+		return MulDiv(types.ToUint128(units), types.ToUint128(10845), types.ToUint128(10000), mode)
+	}
+	units, err := rate(10000, Exact)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, types.ToUint128(10845), units)
+
+	_, err = rate(10, Exact)
+	assert.Equal(t, ErrInexact{}, err)
+	units, _ = rate(10, RoundDown)
+	assert.Equal(t, types.ToUint128(10), units)
+	units, _ = rate(10, RoundUp)
+	assert.Equal(t, types.ToUint128(11), units)
+
+	// 2 * 1.25 = 2.5, and 6 * 1.25 = 7.5.
+	half := func(units uint64, mode RoundingMode) types.Uint128 {
+		result, err := MulDiv(types.ToUint128(units), types.ToUint128(5), types.ToUint128(4), mode)
+		assert.Equal(t, nil, err)
+		return result
+	}
+	assert.Equal(t, types.ToUint128(3), half(2, RoundHalfUp))
+	assert.Equal(t, types.ToUint128(2), half(2, RoundHalfEven))
+	assert.Equal(t, types.ToUint128(8), half(6, RoundHalfEven))
+
+	// The product may exceed a Uint128, but not the result.
+	max := types.Uint128{}.Sub(types.ToUint128(1))
+	units, err = MulDiv(max, types.ToUint128(3), types.ToUint128(3), Exact)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, max, units)
+	_, err = MulDiv(max, types.ToUint128(3), types.ToUint128(2), RoundDown)
+	assert.Equal(t, ErrOverflow{}, err)
+
+	_, err = MulDiv(max, types.ToUint128(3), types.Uint128{}, RoundDown)
+	assert.Equal(t, ErrDivisionByZero{}, err)
+}
@@ -0,0 +1,152 @@
+package amount
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Asset describes the unit of a ledger's amounts.
+type Asset struct {
+	// Code identifies the asset, e.g. "USD" or "BTC".
+	Code string
+	// Scale is the number of decimal places of the asset's minor unit, e.g. 2 for cents.
+	Scale uint8
+}
+
+type ErrUnknownLedger struct {
+	Ledger uint32
+}
+
+func (e ErrUnknownLedger) Error() string {
+	return fmt.Sprintf("Ledger %d is not registered.", e.Ledger)
+}
+
+// ErrInvalidLedger is returned when registering ledger zero, which no account can have.
+type ErrInvalidLedger struct{}
+
+func (e ErrInvalidLedger) Error() string { return "Ledger must not be zero." }
+
+type ErrLedgerRegistered struct {
+	Ledger uint32
+	Asset  Asset
+}
+
+func (e ErrLedgerRegistered) Error() string {
+	return fmt.Sprintf("Ledger %d is already registered as %s with scale %d.",
+		e.Ledger, e.Asset.Code, e.Asset.Scale)
+}
+
+// LedgerRegistry declares the asset of each ledger. It is safe for concurrent use.
+type LedgerRegistry struct {
+	mutex   sync.RWMutex
+	ledgers map[uint32]Asset
+}
+
+func NewLedgerRegistry() *LedgerRegistry {
+	return &LedgerRegistry{ledgers: map[uint32]Asset{}}
+}
+
+// Register declares the asset of a ledger. Registering the same asset again is a no-op, while
+// registering a different one returns ErrLedgerRegistered.
+func (r *LedgerRegistry) Register(ledger uint32, asset Asset) error {
+	if ledger == 0 {
+		return ErrInvalidLedger{}
+	}
+	if asset.Scale > ScaleMax {
+		return ErrInvalidScale{Scale: int(asset.Scale)}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if registered, ok := r.ledgers[ledger]; ok && registered != asset {
+		return ErrLedgerRegistered{Ledger: ledger, Asset: registered}
+	}
+	r.ledgers[ledger] = asset
+	return nil
+}
+
+// Asset returns the asset of a ledger, or ErrUnknownLedger.
+func (r *LedgerRegistry) Asset(ledger uint32) (Asset, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	asset, ok := r.ledgers[ledger]
+	if !ok {
+		return Asset{}, ErrUnknownLedger{Ledger: ledger}
+	}
+	return asset, nil
+}
+
+// Amount returns `units` minor units of the ledger's asset as an Amount.
+func (r *LedgerRegistry) Amount(ledger uint32, units types.Uint128) (Amount, error) {
+	asset, err := r.Asset(ledger)
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{units: units, scale: asset.Scale}, nil
+}
+
+// Units converts an amount to minor units of the ledger's asset, for `Transfer.Amount`. It fails
+// rather than round if the amount has more decimal places than the asset.
+func (r *LedgerRegistry) Units(ledger uint32, amount Amount) (types.Uint128, error) {
+	asset, err := r.Asset(ledger)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	return amount.UnitsAt(asset.Scale)
+}
+
+// Parse parses a decimal of the ledger's asset, such as "12.34", into minor units.
+func (r *LedgerRegistry) Parse(ledger uint32, s string) (types.Uint128, error) {
+	asset, err := r.Asset(ledger)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	amount, err := Parse(s, asset.Scale, Exact)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	return amount.units, nil
+}
+
+// Format renders `units` minor units of the ledger's asset, such as "12.34 USD".
+func (r *LedgerRegistry) Format(ledger uint32, units types.Uint128) (string, error) {
+	asset, err := r.Asset(ledger)
+	if err != nil {
+		return "", err
+	}
+	return Amount{units: units, scale: asset.Scale}.String() + " " + asset.Code, nil
+}
+
+// Balances are an account's balances as amounts of its ledger's asset.
+type Balances struct {
+	Asset          Asset
+	DebitsPending  Amount
+	DebitsPosted   Amount
+	CreditsPending Amount
+	CreditsPosted  Amount
+}
+
+// Balances returns the balances of an account, using the asset of its ledger.
+func (r *LedgerRegistry) Balances(account types.Account) (Balances, error) {
+	asset, err := r.Asset(account.Ledger)
+	if err != nil {
+		return Balances{}, err
+	}
+	amount := func(units types.Uint128) Amount {
+		return Amount{units: units, scale: asset.Scale}
+	}
+	return Balances{
+		Asset:          asset,
+		DebitsPending:  amount(account.DebitsPending),
+		DebitsPosted:   amount(account.DebitsPosted),
+		CreditsPending: amount(account.CreditsPending),
+		CreditsPosted:  amount(account.CreditsPosted),
+	}, nil
+}
+
+func (b Balances) String() string {
+	return fmt.Sprintf("%s debits_pending=%s debits_posted=%s credits_pending=%s credits_posted=%s",
+		b.Asset.Code, b.DebitsPending, b.DebitsPosted, b.CreditsPending, b.CreditsPosted)
+}
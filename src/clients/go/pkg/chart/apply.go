@@ -0,0 +1,207 @@
+package chart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to plan and apply charts.
+type Client interface {
+	CreateAccounts(accounts []types.Account) ([]types.AccountEventResult, error)
+	LookupAccounts(accountIDs []types.Uint128) ([]types.Account, error)
+}
+
+// batchMax is the maximum number of events per request.
+const batchMax = 8190
+
+type Status uint8
+
+const (
+	// The account does not exist yet, and would be created (Plan only).
+	Missing Status = iota
+	// The account was created (Apply only).
+	Created
+	// The account exists as declared.
+	Converged
+	// The account exists with different fields.
+	Drifted
+	// The account could not be created, see Change.Result (Apply only).
+	Failed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Missing:
+		return "Missing"
+	case Created:
+		return "Created"
+	case Converged:
+		return "Converged"
+	case Drifted:
+		return "Drifted"
+	case Failed:
+		return "Failed"
+	}
+	return fmt.Sprintf("Status(%d)", uint8(s))
+}
+
+// Change is the status of one entry of the chart.
+type Change struct {
+	Name    string
+	Account types.Account
+	Status  Status
+	// The result of creating the account, if Apply did not create it.
+	Result types.CreateAccountResult
+	// The fields that drifted, submitted by the chart and stored by the cluster. Apply looks up
+	// the drifted accounts to compare every field, as Result only reports the first one.
+	Differences []types.FieldDiff
+}
+
+// Report lists a change for each entry, in the chart's order.
+type Report struct {
+	Changes []Change
+}
+
+// Converged returns whether every account exists as declared, or was created.
+func (r Report) Converged() bool {
+	for _, change := range r.Changes {
+		if change.Status != Created && change.Status != Converged {
+			return false
+		}
+	}
+	return true
+}
+
+// Drifted returns the changes of accounts that exist with different fields.
+func (r Report) Drifted() []Change {
+	var drifted []Change
+	for _, change := range r.Changes {
+		if change.Status == Drifted {
+			drifted = append(drifted, change)
+		}
+	}
+	return drifted
+}
+
+// String renders the report with a line per entry, and a line per difference.
+func (r Report) String() string {
+	var builder strings.Builder
+	for _, change := range r.Changes {
+		fmt.Fprintf(&builder, "%s %s (%s)", change.Status, change.Name, change.Account.ID.DecimalString())
+		if change.Status == Failed || change.Status == Drifted && len(change.Differences) == 0 {
+			fmt.Fprintf(&builder, ": %s", change.Result)
+		}
+		builder.WriteString("\n")
+		for _, difference := range change.Differences {
+			fmt.Fprintf(&builder, "  %s: chart %s, cluster %s\n",
+				difference.Field, difference.Submitted, difference.Stored)
+		}
+	}
+	return builder.String()
+}
+
+// Plan compares the chart with the accounts in the cluster, without creating anything.
+func Plan(client Client, chart *Chart) (Report, error) {
+	accounts, err := chart.Accounts()
+	if err != nil {
+		return Report{}, err
+	}
+
+	existing := make(map[types.Uint128]types.Account, len(accounts))
+	for start := 0; start < len(accounts); start += batchMax {
+		end := start + batchMax
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+		ids := make([]types.Uint128, 0, end-start)
+		for _, account := range accounts[start:end] {
+			ids = append(ids, account.ID)
+		}
+		found, err := client.LookupAccounts(ids)
+		if err != nil {
+			return Report{}, err
+		}
+		for _, account := range found {
+			existing[account.ID] = account
+		}
+	}
+
+	report := Report{Changes: make([]Change, len(accounts))}
+	for i, account := range accounts {
+		change := Change{Name: chart.Entries[i].Name, Account: account, Status: Missing}
+		if stored, ok := existing[account.ID]; ok {
+			change.Differences = types.DiffAccounts(account, stored)
+			if len(change.Differences) == 0 {
+				change.Status = Converged
+			} else {
+				change.Status = Drifted
+			}
+		}
+		report.Changes[i] = change
+	}
+	return report, nil
+}
+
+// Apply creates the chart's accounts that do not exist yet. It is safe to run repeatedly: the
+// accounts that already exist are reported as converged or drifted, and are never changed.
+// The error is only set if the chart is invalid or a batch could not be submitted.
+func Apply(client Client, chart *Chart) (Report, error) {
+	accounts, err := chart.Accounts()
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Changes: make([]Change, len(accounts))}
+	for i, account := range accounts {
+		report.Changes[i] = Change{
+			Name:    chart.Entries[i].Name,
+			Account: account,
+			Status:  Created,
+			Result:  types.AccountOK,
+		}
+	}
+	for start := 0; start < len(accounts); start += batchMax {
+		end := start + batchMax
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+		results, err := client.CreateAccounts(accounts[start:end])
+		if err != nil {
+			return Report{}, err
+		}
+		var drifted []types.Uint128
+		for _, result := range results {
+			change := &report.Changes[start+int(result.Index)]
+			change.Result = result.Result
+			switch {
+			case result.Result == types.AccountExists:
+				change.Status = Converged
+			case result.Result.ExistsWithDifferent():
+				change.Status = Drifted
+				drifted = append(drifted, change.Account.ID)
+			default:
+				change.Status = Failed
+			}
+		}
+		if len(drifted) == 0 {
+			continue
+		}
+		stored, err := client.LookupAccounts(drifted)
+		if err != nil {
+			return Report{}, err
+		}
+		found := make(map[types.Uint128]types.Account, len(stored))
+		for _, account := range stored {
+			found[account.ID] = account
+		}
+		for i := start; i < end; i++ {
+			change := &report.Changes[i]
+			if account, ok := found[change.Account.ID]; ok && change.Status == Drifted {
+				change.Differences = types.DiffAccounts(change.Account, account)
+			}
+		}
+	}
+	return report, nil
+}
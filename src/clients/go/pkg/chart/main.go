@@ -0,0 +1,136 @@
+// Package chart bootstraps accounts from a declarative chart of accounts.
+//
+// A chart lists the accounts that every environment needs, such as operator, liquidity, and
+// control accounts, with their ledger, code, flags, and user data. Apply creates them
+// idempotently: accounts that already exist as declared have converged, while accounts that
+// exist with different fields are reported as drift rather than changed, as accounts are
+// immutable. Plan reports the same without creating anything.
+//
+// Charts are read from JSON (see Load). YAML is not supported, as the client has no
+// dependencies; convert YAML charts to JSON first.
+package chart
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Entry declares one account of a chart.
+type Entry struct {
+	// Name identifies the entry within the chart, and derives its ID if ID is zero.
+	Name        string             `json:"name"`
+	ID          types.Uint128      `json:"id"`
+	Ledger      uint32             `json:"ledger"`
+	Code        uint16             `json:"code"`
+	Flags       types.AccountFlags `json:"flags"`
+	UserData128 types.Uint128      `json:"user_data_128"`
+	UserData64  uint64             `json:"user_data_64"`
+	UserData32  uint32             `json:"user_data_32"`
+}
+
+type Chart struct {
+	// Namespace derives the IDs of entries without one, see types.UUIDv8. It must be set if any
+	// entry's ID is zero, and must never change, as the same name in a different namespace
+	// derives a different ID.
+	Namespace types.Uint128
+	Entries   []Entry
+}
+
+type ErrInvalidEntry struct {
+	Name   string
+	Reason string
+}
+
+func (e ErrInvalidEntry) Error() string {
+	return fmt.Sprintf("Invalid chart entry %q: %s.", e.Name, e.Reason)
+}
+
+// Load reads a chart from JSON. The namespace is a UUID, IDs and `user_data_128` are decimal
+// strings or numbers, and flags are lists of names:
+//
+//	{
+//	  "namespace": "3f0e1e5c-7a1b-4c55-9d43-6d0a6f0c2b8e",
+//	  "accounts": [
+//	    {"name": "operator", "id": "1000", "ledger": 1, "code": 1},
+//	    {"name": "liquidity_usd", "ledger": 1, "code": 2, "flags": ["history"], "user_data_32": 7}
+//	  ]
+//	}
+//
+// Unknown fields are rejected.
+func Load(r io.Reader) (*Chart, error) {
+	var declared struct {
+		Namespace string  `json:"namespace"`
+		Accounts  []Entry `json:"accounts"`
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&declared); err != nil {
+		return nil, fmt.Errorf("chart: %w", err)
+	}
+
+	chart := &Chart{Entries: declared.Accounts}
+	if declared.Namespace != "" {
+		namespace, err := types.Uint128FromUUID(declared.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		chart.Namespace = namespace
+	}
+	if _, err := chart.Accounts(); err != nil {
+		return nil, err
+	}
+	return chart, nil
+}
+
+// ID returns the ID of an entry: its own, or else the one derived from its name.
+func (c *Chart) ID(entry Entry) types.Uint128 {
+	if !entry.ID.IsZero() {
+		return entry.ID
+	}
+	return types.UUIDv8(c.Namespace, []byte(entry.Name))
+}
+
+// Accounts returns the accounts of the chart's entries, in order, after checking that their
+// names and IDs are unique.
+func (c *Chart) Accounts() ([]types.Account, error) {
+	accounts := make([]types.Account, len(c.Entries))
+	names := make(map[string]struct{}, len(c.Entries))
+	ids := make(map[types.Uint128]string, len(c.Entries))
+	for i, entry := range c.Entries {
+		invalid := func(reason string, args ...interface{}) error {
+			return ErrInvalidEntry{Name: entry.Name, Reason: fmt.Sprintf(reason, args...)}
+		}
+		if entry.Name == "" {
+			return nil, invalid("name must be set")
+		}
+		if _, ok := names[entry.Name]; ok {
+			return nil, invalid("name is not unique")
+		}
+		names[entry.Name] = struct{}{}
+		if entry.ID.IsZero() && c.Namespace.IsZero() {
+			return nil, invalid("either the entry's ID or the chart's namespace must be set")
+		}
+		if entry.Flags.Linked {
+			return nil, invalid("linked accounts are not supported")
+		}
+
+		id := c.ID(entry)
+		if other, ok := ids[id]; ok {
+			return nil, invalid("ID %s is also used by %q", id, other)
+		}
+		ids[id] = entry.Name
+		accounts[i] = types.Account{
+			ID:          id,
+			UserData128: entry.UserData128,
+			UserData64:  entry.UserData64,
+			UserData32:  entry.UserData32,
+			Ledger:      entry.Ledger,
+			Code:        entry.Code,
+			Flags:       entry.Flags.ToUint16(),
+		}
+	}
+	return accounts, nil
+}
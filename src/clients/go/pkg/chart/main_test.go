@@ -0,0 +1,155 @@
+package chart
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+const declaration = `{
+  "namespace": "3f0e1e5c-7a1b-4c55-9d43-6d0a6f0c2b8e",
+  "accounts": [
+    {"name": "operator", "id": "1000", "ledger": 1, "code": 1},
+    {"name": "liquidity_usd", "ledger": 1, "code": 2, "flags": ["history"], "user_data_32": 7},
+    {"name": "control", "ledger": 1, "code": 3, "user_data_128": "340282366920938463463374607431768211455"}
+  ]
+}`
+
+func load(t *testing.T, declaration string) *Chart {
+	chart, err := Load(strings.NewReader(declaration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chart
+}
+
+func Test_Load(t *testing.T) {
+	chart := load(t, declaration)
+	accounts, err := chart.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, accounts, 3)
+	assert.Equal(t, types.ToUint128(1000), accounts[0].ID)
+	namespace, err := types.Uint128FromUUID("3f0e1e5c-7a1b-4c55-9d43-6d0a6f0c2b8e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.UUIDv8(namespace, []byte("liquidity_usd")), accounts[1].ID)
+	assert.Equal(t, types.AccountFlags{History: true}.ToUint16(), accounts[1].Flags)
+	assert.Equal(t, uint32(7), accounts[1].UserData32)
+	assert.Equal(t, "340282366920938463463374607431768211455", accounts[2].UserData128.DecimalString())
+
+	tests := []struct {
+		declaration string
+		reason      string
+	}{
+		{`{"accounts": [{"name": "a", "ledger": 1, "code": 1}]}`, "namespace"},
+		{`{"accounts": [{"name": "a", "id": 1}, {"name": "a", "id": 2}]}`, "not unique"},
+		{`{"accounts": [{"name": "a", "id": 1}, {"name": "b", "id": 1}]}`, "also used"},
+		{`{"accounts": [{"name": "a", "id": 1, "flags": ["linked"]}]}`, "linked"},
+		{`{"accounts": [{"id": 1}]}`, "name"},
+	}
+	for _, test := range tests {
+		_, err := Load(strings.NewReader(test.declaration))
+		var invalid ErrInvalidEntry
+		if !errors.As(err, &invalid) || !strings.Contains(invalid.Reason, test.reason) {
+			t.Fatalf("Expected %s to be invalid with %q, got %v", test.declaration, test.reason, err)
+		}
+	}
+	for _, declaration := range []string{
+		`{"accounts": [{"name": "a", "id": 1, "flags": ["unknown"]}]}`,
+		`{"accounts": [{"name": "a", "id": 1, "balance": 5}]}`,
+		`{"namespace": "not a uuid", "accounts": []}`,
+	} {
+		if _, err := Load(strings.NewReader(declaration)); err == nil {
+			t.Fatalf("Expected %s to be rejected", declaration)
+		}
+	}
+}
+
+func Test_PlanApply(t *testing.T) {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	chart := load(t, declaration)
+
+	// An account created out of band, with a different code.
+	accounts, err := chart.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	drifted := accounts[2]
+	drifted.Code = 4
+	if _, err := cluster.CreateAccounts([]types.Account{drifted}); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := Plan(cluster, chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Missing, plan.Changes[0].Status)
+	assert.Equal(t, Missing, plan.Changes[1].Status)
+	assert.Equal(t, Drifted, plan.Changes[2].Status)
+	assert.Equal(t, []types.FieldDiff{{Field: "code", Submitted: "3", Stored: "4"}}, plan.Changes[2].Differences)
+	assert.True(t, !plan.Converged())
+
+	// Planning creates nothing.
+	found, err := cluster.LookupAccounts([]types.Uint128{accounts[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, found, 0)
+
+	report, err := Apply(cluster, chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Created, report.Changes[0].Status)
+	assert.Equal(t, Created, report.Changes[1].Status)
+	assert.Equal(t, Drifted, report.Changes[2].Status)
+	assert.Equal(t, types.AccountExistsWithDifferentCode, report.Changes[2].Result)
+	assert.Equal(t, []types.FieldDiff{{Field: "code", Submitted: "3", Stored: "4"}}, report.Changes[2].Differences)
+	assert.Len(t, report.Drifted(), 1)
+
+	// Applying again converges, except for the drift.
+	report, err = Apply(cluster, chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Converged, report.Changes[0].Status)
+	assert.Equal(t, Converged, report.Changes[1].Status)
+	assert.Equal(t, Drifted, report.Changes[2].Status)
+	assert.Equal(t,
+		"Converged operator (1000)\n"+
+			"Converged liquidity_usd ("+accounts[1].ID.DecimalString()+")\n"+
+			"Drifted control ("+accounts[2].ID.DecimalString()+")\n"+
+			"  code: chart 3, cluster 4\n",
+		report.String(),
+	)
+
+	plan, err = Plan(cluster, load(t, strings.Replace(declaration, `"flags": ["history"]`,
+		`"flags": ["history", "debits_must_not_exceed_credits"]`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []types.FieldDiff{{
+		Field:     "flags",
+		Submitted: "debits_must_not_exceed_credits|history",
+		Stored:    "history",
+	}}, plan.Changes[1].Differences)
+
+	// Failures are reported, rather than returned.
+	invalid := load(t, `{"accounts": [{"name": "a", "id": 1, "ledger": 0, "code": 1}]}`)
+	report, err = Apply(cluster, invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Failed, report.Changes[0].Status)
+	assert.Equal(t, types.AccountLedgerMustNotBeZero, report.Changes[0].Result)
+	assert.Equal(t, "Failed a (1): AccountLedgerMustNotBeZero\n", report.String())
+}
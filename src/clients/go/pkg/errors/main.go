@@ -1,5 +1,10 @@
 package errors
 
+import (
+	"fmt"
+	"strings"
+)
+
 type ErrUnexpected struct{}
 
 func (s ErrUnexpected) Error() string { return "Unexpected internal error." }
@@ -51,3 +56,122 @@ func (s ErrEmptyBatch) Error() string { return "Empty batch." }
 type ErrMaximumBatchSizeExceeded struct{}
 
 func (s ErrMaximumBatchSizeExceeded) Error() string { return "Maximum batch size exceeded." }
+
+type ErrAccountNotFound struct{}
+
+func (s ErrAccountNotFound) Error() string { return "Account not found." }
+
+type ErrAccountHistoryDisabled struct{}
+
+func (s ErrAccountHistoryDisabled) Error() string {
+	return "Account was not created with the history flag."
+}
+
+type ErrInvalidTimestamp struct{}
+
+func (s ErrInvalidTimestamp) Error() string { return "Time is out of range for a timestamp." }
+
+type ErrInvalidTimeout struct{}
+
+func (s ErrInvalidTimeout) Error() string {
+	return "Timeout must be between zero and 2^32-1 seconds."
+}
+
+type ErrInvalidAccountFilter struct {
+	Reason string
+}
+
+func (s ErrInvalidAccountFilter) Error() string { return "Invalid account filter: " + s.Reason + "." }
+
+type ErrUint128Overflow struct{}
+
+func (s ErrUint128Overflow) Error() string { return "Uint128 arithmetic overflow." }
+
+type ErrUint128DivisionByZero struct{}
+
+func (s ErrUint128DivisionByZero) Error() string { return "Uint128 division by zero." }
+
+type ErrUint128Negative struct{}
+
+func (s ErrUint128Negative) Error() string { return "Uint128 cannot represent a negative value." }
+
+type ErrInvalidBinaryLength struct {
+	Expected int
+	Got      int
+}
+
+func (s ErrInvalidBinaryLength) Error() string {
+	return fmt.Sprintf("Invalid binary length: expected %d bytes, got %d.", s.Expected, s.Got)
+}
+
+type ErrUnknownName struct {
+	Type string
+	Name string
+}
+
+func (s ErrUnknownName) Error() string {
+	return fmt.Sprintf("Unknown %s name %q.", s.Type, s.Name)
+}
+
+type ErrFlagsMismatch struct {
+	Type string
+}
+
+func (s ErrFlagsMismatch) Error() string {
+	return fmt.Sprintf("%s flags and flag names do not match.", s.Type)
+}
+
+type ErrInvalidSQLValue struct {
+	Encoding string
+	Type     string
+}
+
+func (s ErrInvalidSQLValue) Error() string {
+	return fmt.Sprintf("Cannot convert %s to or from Uint128 with encoding %s.", s.Type, s.Encoding)
+}
+
+type ErrNilSQLTarget struct{}
+
+func (s ErrNilSQLTarget) Error() string { return "Cannot scan into a nil Uint128." }
+
+type ErrInvalidUUID struct {
+	Value string
+}
+
+func (s ErrInvalidUUID) Error() string {
+	return fmt.Sprintf("Invalid UUID %q.", s.Value)
+}
+
+type ErrIDEntropy struct {
+	Err error
+}
+
+func (s ErrIDEntropy) Error() string {
+	return fmt.Sprintf("Failed to read random bits for an ID: %v.", s.Err)
+}
+
+func (s ErrIDEntropy) Unwrap() error { return s.Err }
+
+type ErrIDOverflow struct{}
+
+func (s ErrIDOverflow) Error() string {
+	return "Random bits of an ID overflowed within the same millisecond."
+}
+
+type ErrReservedFlags struct {
+	Type  string
+	Flags uint64
+}
+
+func (s ErrReservedFlags) Error() string {
+	return fmt.Sprintf("%s has reserved bits set (%#x).", s.Type, s.Flags)
+}
+
+type ErrFlagsMutuallyExclusive struct {
+	Type  string
+	Flags []string
+}
+
+func (s ErrFlagsMutuallyExclusive) Error() string {
+	return fmt.Sprintf("%s %s are mutually exclusive.", s.Type, strings.Join(s.Flags, " and "))
+}
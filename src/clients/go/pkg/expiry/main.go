@@ -0,0 +1,469 @@
+// Package expiry watches pending transfers and reports when they expire.
+//
+// Pending transfers created with a `Timeout` are expired by the cluster in the background, and
+// clients are not notified. A Watcher tracks registered pending transfer IDs, computes the
+// expected expiry from `Timestamp + Timeout`, and once that instant has passed looks up the
+// transfer's accounts. If the debit account's `DebitsPending` or the credit account's
+// `CreditsPending` is less than the transfer's amount, its hold was released, and the watcher
+// learns how by attempting to void it, which can then only fail:
+//
+//   - `TransferPendingTransferExpired` confirms that the cluster expired the transfer.
+//   - `TransferPendingTransferAlreadyPosted` and `TransferPendingTransferAlreadyVoided` report
+//     that the transfer was resolved by someone else before it could expire.
+//
+// Otherwise, the transfer is still held, or the accounts have other pending transfers that hide
+// its release, and it is checked again on the next poll. The local clock may run ahead of the
+// cluster's, so the watcher never voids a hold that it has not confirmed as released, unless
+// Options.Void is set: then a successful void reports that the watcher itself released the hold
+// (`VoidedByWatcher`).
+//
+// In every case, the reserved amount is no longer held once the event is delivered.
+package expiry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used by the Watcher.
+type Client interface {
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+	LookupAccounts(accountIDs []types.Uint128) ([]types.Account, error)
+	LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error)
+}
+
+// batchMax is the maximum number of events per request.
+const batchMax = 8190
+
+type Outcome uint8
+
+const (
+	// The cluster expired the pending transfer.
+	Expired Outcome = iota
+	// The pending transfer was posted before it expired.
+	Posted
+	// The pending transfer was voided by another client before it expired.
+	Voided
+	// The pending transfer had not expired yet, and was voided by the watcher's probe (only with
+	// Options.Void).
+	VoidedByWatcher
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Expired:
+		return "Expired"
+	case Posted:
+		return "Posted"
+	case Voided:
+		return "Voided"
+	case VoidedByWatcher:
+		return "VoidedByWatcher"
+	}
+	return fmt.Sprintf("Outcome(%d)", uint8(o))
+}
+
+// Event reports that a registered pending transfer no longer holds any amount.
+type Event struct {
+	// The pending transfer, as looked up when it was registered.
+	Transfer  types.Transfer
+	ExpiresAt time.Time
+	Outcome   Outcome
+}
+
+type ErrTransferNotFound struct {
+	ID types.Uint128
+}
+
+func (e ErrTransferNotFound) Error() string {
+	return fmt.Sprintf("Transfer %s was not found.", e.ID)
+}
+
+type ErrTransferNotPending struct {
+	ID types.Uint128
+}
+
+func (e ErrTransferNotPending) Error() string {
+	return fmt.Sprintf("Transfer %s is not a pending transfer.", e.ID)
+}
+
+type ErrTransferWithoutTimeout struct {
+	ID types.Uint128
+}
+
+func (e ErrTransferWithoutTimeout) Error() string {
+	return fmt.Sprintf("Pending transfer %s has no timeout and never expires.", e.ID)
+}
+
+// ErrClosed is returned by a background poll that was interrupted by Close() while the events
+// channel was full. The events not delivered yet are delivered by the next Poll().
+type ErrClosed struct{}
+
+func (e ErrClosed) Error() string { return "Watcher was closed while delivering events." }
+
+type ErrUnexpectedResult struct {
+	ID     types.Uint128
+	Result types.CreateTransferResult
+}
+
+func (e ErrUnexpectedResult) Error() string {
+	return fmt.Sprintf("Unexpected result probing pending transfer %s: %s.", e.ID, e.Result)
+}
+
+type Options struct {
+	// How often Start() polls for due transfers. Defaults to one second.
+	PollInterval time.Duration
+	// How long to wait after the expected expiry before probing, to allow for clock skew and
+	// for the cluster's expiry pulse to run. Defaults to one second.
+	Grace time.Duration
+	// The capacity of the events channel. Defaults to 1024.
+	// Polling blocks while the channel is full, until Close() is called.
+	Buffer int
+	// Called with the errors of background polls started with Start(). Defaults to ignoring
+	// them, as polls are retried on the next interval.
+	OnError func(error)
+	// The wall clock. Defaults to time.Now.
+	Now func() time.Time
+	// Whether to void the due transfers whose release the account balances do not confirm, e.g.
+	// as other pending transfers of the same accounts are still held. Their holds are released
+	// once the expected expiry (plus grace) has passed by the local clock, even if the cluster's
+	// clock is behind, so only set it if such holds are no longer needed.
+	Void bool
+}
+
+// Watcher tracks pending transfers until they expire.
+// It is safe to use from multiple goroutines.
+type Watcher struct {
+	client  Client
+	options Options
+	events  chan Event
+
+	mutex   sync.Mutex
+	watched map[types.Uint128]Event
+	// Events that were resolved, but not delivered because the watcher was closed.
+	undelivered []Event
+
+	// Serializes Poll(), so that a transfer is never reported twice.
+	polling sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewWatcher(client Client, options Options) *Watcher {
+	if options.PollInterval == 0 {
+		options.PollInterval = time.Second
+	}
+	if options.Grace == 0 {
+		options.Grace = time.Second
+	}
+	if options.Buffer == 0 {
+		options.Buffer = 1024
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	if options.OnError == nil {
+		options.OnError = func(error) {}
+	}
+
+	return &Watcher{
+		client:  client,
+		options: options,
+		events:  make(chan Event, options.Buffer),
+		watched: make(map[types.Uint128]Event),
+	}
+}
+
+// Events returns the channel on which expiry events are delivered.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Register starts watching the given pending transfers.
+// Registering a transfer that is already watched has no effect.
+func (w *Watcher) Register(pendingIDs ...types.Uint128) error {
+	for start := 0; start < len(pendingIDs); start += batchMax {
+		end := start + batchMax
+		if end > len(pendingIDs) {
+			end = len(pendingIDs)
+		}
+
+		transfers, err := w.client.LookupTransfers(pendingIDs[start:end])
+		if err != nil {
+			return err
+		}
+
+		found := make(map[types.Uint128]types.Transfer, len(transfers))
+		for _, transfer := range transfers {
+			found[transfer.ID] = transfer
+		}
+
+		// Validate the whole batch before watching any of it.
+		for _, id := range pendingIDs[start:end] {
+			transfer, ok := found[id]
+			if !ok {
+				return ErrTransferNotFound{ID: id}
+			}
+			if !transfer.TransferFlags().Pending {
+				return ErrTransferNotPending{ID: id}
+			}
+			if transfer.Timeout == 0 {
+				return ErrTransferWithoutTimeout{ID: id}
+			}
+		}
+
+		w.mutex.Lock()
+		for _, transfer := range found {
+			if _, ok := w.watched[transfer.ID]; ok {
+				continue
+			}
+			timeout := time.Duration(transfer.Timeout) * time.Second
+			w.watched[transfer.ID] = Event{
+				Transfer:  transfer,
+				ExpiresAt: time.Unix(0, int64(transfer.Timestamp)).Add(timeout),
+			}
+		}
+		w.mutex.Unlock()
+	}
+	return nil
+}
+
+// Unregister stops watching the given pending transfers.
+func (w *Watcher) Unregister(pendingIDs ...types.Uint128) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, id := range pendingIDs {
+		delete(w.watched, id)
+	}
+}
+
+// Watching returns the number of pending transfers being watched.
+func (w *Watcher) Watching() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.watched)
+}
+
+// Poll checks every watched transfer whose expiry (plus grace) has passed, and delivers an event
+// for each one that was resolved. It blocks while the events channel is full.
+func (w *Watcher) Poll() error {
+	return w.poll(nil)
+}
+
+// poll is Poll(), but gives up delivering events once `stop` is closed.
+func (w *Watcher) poll(stop <-chan struct{}) error {
+	w.polling.Lock()
+	defer w.polling.Unlock()
+
+	w.mutex.Lock()
+	undelivered := w.undelivered
+	w.undelivered = nil
+	w.mutex.Unlock()
+	if err := w.deliver(undelivered, stop); err != nil {
+		return err
+	}
+
+	now := w.options.Now()
+	due := []Event{}
+	w.mutex.Lock()
+	for _, event := range w.watched {
+		if !now.Before(event.ExpiresAt.Add(w.options.Grace)) {
+			due = append(due, event)
+		}
+	}
+	w.mutex.Unlock()
+
+	for start := 0; start < len(due); start += batchMax {
+		end := start + batchMax
+		if end > len(due) {
+			end = len(due)
+		}
+		if err := w.probe(due[start:end], stop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProbeID returns the ID of the voiding transfer that probes a pending transfer, so that a
+// retried probe finds its own void.
+func ProbeID(pendingID types.Uint128) types.Uint128 {
+	return types.UUIDv8(pendingID, []byte("expiry/void"))
+}
+
+func (w *Watcher) probe(due []Event, stop <-chan struct{}) error {
+	released, err := w.released(due)
+	if err != nil {
+		return err
+	}
+	var probed []Event
+	var probes []types.Transfer
+	for i, event := range due {
+		if !released[i] && !w.options.Void {
+			continue
+		}
+		probed = append(probed, event)
+		probes = append(probes, types.Transfer{
+			ID:        ProbeID(event.Transfer.ID),
+			PendingID: event.Transfer.ID,
+			Flags:     types.TransferFlags{VoidPendingTransfer: true}.ToUint16(),
+		})
+	}
+	if len(probes) == 0 {
+		return nil
+	}
+
+	results, err := w.client.CreateTransfers(probes)
+	if err != nil {
+		return err
+	}
+
+	// Only failed events are returned, so every other probe voided its pending transfer.
+	outcomes := make([]Outcome, len(probed))
+	for i := range outcomes {
+		outcomes[i] = VoidedByWatcher
+	}
+	// The events with an unexpected result are still watched, and the first is reported once
+	// the others are delivered.
+	unexpected := make(map[int]bool)
+	var unexpectedErr error
+	for _, result := range results {
+		switch result.Result {
+		case types.TransferExists:
+			// A probe that was retried, e.g. after a network error, already voided the transfer.
+		case types.TransferPendingTransferExpired:
+			outcomes[result.Index] = Expired
+		case types.TransferPendingTransferAlreadyPosted:
+			outcomes[result.Index] = Posted
+		case types.TransferPendingTransferAlreadyVoided:
+			outcomes[result.Index] = Voided
+		default:
+			unexpected[int(result.Index)] = true
+			if unexpectedErr == nil {
+				unexpectedErr = ErrUnexpectedResult{ID: probed[result.Index].Transfer.ID, Result: result.Result}
+			}
+		}
+	}
+
+	resolved := make([]Event, 0, len(probed))
+	w.mutex.Lock()
+	for i, event := range probed {
+		if unexpected[i] {
+			continue
+		}
+		// Skip transfers that were unregistered while probing.
+		if _, watched := w.watched[event.Transfer.ID]; watched {
+			delete(w.watched, event.Transfer.ID)
+			event.Outcome = outcomes[i]
+			resolved = append(resolved, event)
+		}
+	}
+	w.mutex.Unlock()
+	if err := w.deliver(resolved, stop); err != nil {
+		return err
+	}
+	return unexpectedErr
+}
+
+// released returns, for each event, whether the balances of its accounts confirm that its hold
+// was released: an account whose pending balance is less than the amount no longer holds it.
+func (w *Watcher) released(due []Event) ([]bool, error) {
+	var ids []types.Uint128
+	for _, event := range due {
+		ids = append(ids, event.Transfer.DebitAccountID, event.Transfer.CreditAccountID)
+	}
+	accounts := make(map[types.Uint128]types.Account, len(ids))
+	for start := 0; start < len(ids); start += batchMax {
+		end := start + batchMax
+		if end > len(ids) {
+			end = len(ids)
+		}
+		found, err := w.client.LookupAccounts(ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range found {
+			accounts[account.ID] = account
+		}
+	}
+
+	released := make([]bool, len(due))
+	for i, event := range due {
+		transfer := event.Transfer
+		if debit, ok := accounts[transfer.DebitAccountID]; ok &&
+			debit.DebitsPending.Cmp(transfer.Amount) < 0 {
+			released[i] = true
+		}
+		if credit, ok := accounts[transfer.CreditAccountID]; ok &&
+			credit.CreditsPending.Cmp(transfer.Amount) < 0 {
+			released[i] = true
+		}
+	}
+	return released, nil
+}
+
+// deliver sends the events in order. If `stop` is closed first, it keeps the events that were
+// not sent for the next poll.
+func (w *Watcher) deliver(events []Event, stop <-chan struct{}) error {
+	for i, event := range events {
+		select {
+		case w.events <- event:
+		case <-stop:
+			w.mutex.Lock()
+			w.undelivered = append(events[i:len(events):len(events)], w.undelivered...)
+			w.mutex.Unlock()
+			return ErrClosed{}
+		}
+	}
+	return nil
+}
+
+// Start polls in the background until Close() is called.
+// Polling errors are reported to Options.OnError, and retried on the next interval.
+func (w *Watcher) Start() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stop != nil {
+		return
+	}
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(w.options.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := w.poll(stop); err != nil {
+					select {
+					case <-stop:
+					default:
+						w.options.OnError(err)
+					}
+				}
+			}
+		}
+	}(w.stop, w.done)
+}
+
+// Close stops background polling started with Start(), without waiting for the events channel
+// to be drained. The events channel is not closed, since Poll() may still be called directly.
+func (w *Watcher) Close() {
+	w.mutex.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
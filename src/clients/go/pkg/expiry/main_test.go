@@ -0,0 +1,380 @@
+package expiry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func setup(t *testing.T) (*fake.Cluster, types.Account, types.Account) {
+	cluster := fake.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	accountA := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+	accountB := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+	results, err := cluster.CreateAccounts([]types.Account{accountA, accountB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+	return cluster, accountA, accountB
+}
+
+func createPending(t *testing.T, client Client, debit, credit types.Account, timeout uint32) types.Transfer {
+	transfer := types.Transfer{
+		ID:              types.ID(),
+		DebitAccountID:  debit.ID,
+		CreditAccountID: credit.ID,
+		Amount:          types.ToUint128(100),
+		Timeout:         timeout,
+		Ledger:          1,
+		Code:            1,
+		Flags:           types.TransferFlags{Pending: true}.ToUint16(),
+	}
+	results, err := client.CreateTransfers([]types.Transfer{transfer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+	return transfer
+}
+
+func Test_Watcher_Expired(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	pending := createPending(t, cluster, accountA, accountB, 10)
+
+	watcher := NewWatcher(cluster, Options{Now: cluster.Now})
+	if err := watcher.Register(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, watcher.Watching())
+
+	// Not due yet:
+	cluster.Advance(5 * time.Second)
+	if err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(watcher.Events()))
+	assert.Equal(t, 1, watcher.Watching())
+
+	cluster.Advance(10 * time.Second)
+	if err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(watcher.Events()))
+	event := <-watcher.Events()
+	assert.Equal(t, pending.ID, event.Transfer.ID)
+	assert.Equal(t, Expired, event.Outcome)
+	assert.Equal(t, time.Unix(0, int64(event.Transfer.Timestamp)).Add(10*time.Second), event.ExpiresAt)
+	assert.Equal(t, 0, watcher.Watching())
+
+	// The hold was released by the cluster.
+	accounts, err := cluster.LookupAccounts([]types.Uint128{accountA.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(0), accounts[0].DebitsPending)
+}
+
+func Test_Watcher_Resolved(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	posted := createPending(t, cluster, accountA, accountB, 10)
+	voided := createPending(t, cluster, accountA, accountB, 10)
+
+	watcher := NewWatcher(cluster, Options{Now: cluster.Now})
+	if err := watcher.Register(posted.ID, voided.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := cluster.CreateTransfers([]types.Transfer{
+		{
+			ID:        types.ID(),
+			PendingID: posted.ID,
+			Flags:     types.TransferFlags{PostPendingTransfer: true}.ToUint16(),
+		},
+		{
+			ID:        types.ID(),
+			PendingID: voided.ID,
+			Flags:     types.TransferFlags{VoidPendingTransfer: true}.ToUint16(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	cluster.Advance(time.Minute)
+	if err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(watcher.Events()))
+	outcomes := map[types.Uint128]Outcome{}
+	for i := 0; i < 2; i++ {
+		event := <-watcher.Events()
+		outcomes[event.Transfer.ID] = event.Outcome
+	}
+	assert.Equal(t, Posted, outcomes[posted.ID])
+	assert.Equal(t, Voided, outcomes[voided.ID])
+}
+
+func Test_Watcher_ClockSkew(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	pending := createPending(t, cluster, accountA, accountB, 10)
+
+	// The watcher's clock runs ahead of the cluster, but the hold is not released, so the
+	// transfer is still watched.
+	skewed := func() time.Time { return cluster.Now().Add(time.Minute) }
+	watcher := NewWatcher(cluster, Options{Now: skewed})
+	if err := watcher.Register(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(watcher.Events()))
+	assert.Equal(t, 1, watcher.Watching())
+	accounts, err := cluster.LookupAccounts([]types.Uint128{accountA.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(100), accounts[0].DebitsPending)
+
+	// Unless voiding is opted in, in which case the probe voids the transfer itself.
+	watcher = NewWatcher(cluster, Options{Now: skewed, Void: true})
+	if err := watcher.Register(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	event := <-watcher.Events()
+	assert.Equal(t, VoidedByWatcher, event.Outcome)
+
+	accounts, err = cluster.LookupAccounts([]types.Uint128{accountA.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(0), accounts[0].DebitsPending)
+	assert.Equal(t, types.ToUint128(0), accounts[0].DebitsPosted)
+}
+
+// faulty creates the transfers, but fails the probe of `pendingID`, or the whole request.
+type faulty struct {
+	*fake.Cluster
+	pendingID types.Uint128
+	err       error
+}
+
+func (c faulty) CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error) {
+	var results []types.TransferEventResult
+	var created []types.Transfer
+	var indexes []uint32
+	for i, transfer := range transfers {
+		if transfer.PendingID == c.pendingID {
+			results = append(results, types.TransferEventResult{Index: uint32(i), Result: types.TransferExceedsCredits})
+			continue
+		}
+		created = append(created, transfer)
+		indexes = append(indexes, uint32(i))
+	}
+	if len(created) == 0 && c.err == nil {
+		return results, nil
+	}
+	createdResults, err := c.Cluster.CreateTransfers(created)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range createdResults {
+		result.Index = indexes[result.Index]
+		results = append(results, result)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return results, nil
+}
+
+func Test_Watcher_Retry(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	pending := createPending(t, cluster, accountA, accountB, 10)
+	failing := createPending(t, cluster, accountA, accountB, 10)
+
+	// The request voids the transfer, but its reply is lost.
+	lost := faulty{Cluster: cluster, err: errors.New("lost")}
+	skewed := func() time.Time { return cluster.Now().Add(time.Minute) }
+	watcher := NewWatcher(lost, Options{Now: skewed, Void: true})
+	if err := watcher.Register(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, lost.err, watcher.Poll())
+	assert.Equal(t, 1, watcher.Watching())
+
+	// The retried probe finds its own void.
+	watcher = NewWatcher(cluster, Options{Now: skewed, Void: true})
+	if err := watcher.Register(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	event := <-watcher.Events()
+	assert.Equal(t, VoidedByWatcher, event.Outcome)
+
+	// An unexpected result keeps its transfer watched, but the others of the batch are resolved.
+	other := createPending(t, cluster, accountA, accountB, 10)
+	watcher = NewWatcher(faulty{Cluster: cluster, pendingID: failing.ID}, Options{Now: skewed, Void: true})
+	if err := watcher.Register(failing.ID, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrUnexpectedResult{ID: failing.ID, Result: types.TransferExceedsCredits}, watcher.Poll())
+	assert.Equal(t, 1, watcher.Watching())
+	event = <-watcher.Events()
+	assert.Equal(t, other.ID, event.Transfer.ID)
+	assert.Equal(t, VoidedByWatcher, event.Outcome)
+}
+
+func Test_Watcher_Unregister(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	pending := createPending(t, cluster, accountA, accountB, 10)
+
+	watcher := NewWatcher(cluster, Options{Now: cluster.Now})
+	if err := watcher.Register(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Unregister(pending.ID)
+
+	cluster.Advance(time.Minute)
+	if err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(watcher.Events()))
+}
+
+func Test_Watcher_RegisterInvalid(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	forever := createPending(t, cluster, accountA, accountB, 0)
+	posted := types.Transfer{
+		ID:              types.ID(),
+		DebitAccountID:  accountA.ID,
+		CreditAccountID: accountB.ID,
+		Amount:          types.ToUint128(1),
+		Ledger:          1,
+		Code:            1,
+	}
+	results, err := cluster.CreateTransfers([]types.Transfer{posted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	watcher := NewWatcher(cluster, Options{Now: cluster.Now})
+	missing := types.ID()
+	assert.Equal(t, ErrTransferNotFound{ID: missing}, watcher.Register(missing))
+	assert.Equal(t, ErrTransferNotPending{ID: posted.ID}, watcher.Register(posted.ID))
+	assert.Equal(t, ErrTransferWithoutTimeout{ID: forever.ID}, watcher.Register(forever.ID))
+	assert.Equal(t, 0, watcher.Watching())
+}
+
+func Test_Watcher_Start(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	pending := createPending(t, cluster, accountA, accountB, 1)
+
+	watcher := NewWatcher(cluster, Options{
+		Now:          cluster.Now,
+		PollInterval: time.Millisecond,
+	})
+	if err := watcher.Register(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Start()
+	defer watcher.Close()
+
+	cluster.Advance(time.Minute)
+	select {
+	case event := <-watcher.Events():
+		assert.Equal(t, Expired, event.Outcome)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the expiry event")
+	}
+}
+
+func Test_Watcher_CloseWhileFull(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	first := createPending(t, cluster, accountA, accountB, 1)
+	second := createPending(t, cluster, accountA, accountB, 1)
+
+	watcher := NewWatcher(cluster, Options{
+		Now:          cluster.Now,
+		PollInterval: time.Millisecond,
+		Buffer:       1,
+	})
+	if err := watcher.Register(first.ID, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	cluster.Advance(time.Minute)
+	watcher.Start()
+
+	// Nobody drains the channel, so the background poll blocks on the second event.
+	for watcher.Watching() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	closed := make(chan struct{})
+	go func() {
+		watcher.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out closing the watcher")
+	}
+
+	// The undelivered events are delivered by the next poll.
+	polled := make(chan error, 1)
+	go func() { polled <- watcher.Poll() }()
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-watcher.Events():
+			assert.Equal(t, Expired, event.Outcome)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the undelivered events")
+		}
+	}
+	if err := <-polled; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Watcher_OnError(t *testing.T) {
+	cluster, accountA, accountB := setup(t)
+	pending := createPending(t, cluster, accountA, accountB, 1)
+
+	errs := make(chan error, 1)
+	// The probe fails unexpectedly.
+	watcher := NewWatcher(faulty{Cluster: cluster, pendingID: pending.ID}, Options{
+		Now:          cluster.Now,
+		PollInterval: time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	if err := watcher.Register(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	cluster.Advance(time.Minute)
+	watcher.Start()
+	defer watcher.Close()
+
+	select {
+	case err := <-errs:
+		_, ok := err.(ErrUnexpectedResult)
+		assert.True(t, ok)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the polling error")
+	}
+}
@@ -0,0 +1,256 @@
+// Package fx exchanges amounts between accounts of different ledgers.
+//
+// A transfer cannot move an amount across ledgers (`TransferAccountsMustHaveTheSameLedger`), so
+// an exchange is a chain of linked transfers through a liquidity account of each ledger: the
+// source account pays the amount in to the liquidity account of its ledger, and the liquidity
+// account of the other ledger pays the converted amount out to the destination account. An
+// optional fee is paid from the source account too. The chain is atomic: either every leg is
+// created, or none is.
+//
+// The legs form a transaction (see package transaction), so an exchange can be looked up from
+// its ID, and submitting the same exchange again is idempotent. The exchange's legs are compared
+// with those of the existing transaction, as the cluster only reports that the first leg exists.
+package fx
+
+import (
+	"fmt"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/amount"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/transaction"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to exchange amounts.
+type Client interface {
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+	LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error)
+	LookupAccounts(accountIDs []types.Uint128) ([]types.Account, error)
+}
+
+// Rate converts units of the source ledger to units of the destination ledger, as the fraction
+// Numerator / Denominator. The rate is between the ledgers' units, so it includes any difference
+// in their scales: at 1.0845 USD per EUR, with both ledgers in cents, the rate is 10845 / 10000.
+type Rate struct {
+	Numerator   uint64
+	Denominator uint64
+}
+
+// Convert returns the amount in the destination ledger's units, rounded with the given mode.
+func (r Rate) Convert(units types.Uint128, mode amount.RoundingMode) (types.Uint128, error) {
+	if r.Numerator == 0 || r.Denominator == 0 {
+		return types.Uint128{}, ErrInvalidRate{Rate: r}
+	}
+	return amount.MulDiv(units, types.ToUint128(r.Numerator), types.ToUint128(r.Denominator), mode)
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%d/%d", r.Numerator, r.Denominator)
+}
+
+// Fee is paid from the source account, in the source ledger's units.
+type Fee struct {
+	AccountID types.Uint128
+	Amount    types.Uint128
+}
+
+type Options struct {
+	// The exchange's transaction ID. If zero, Exchange sets it with types.ID.
+	ID types.Uint128
+	// The code of every leg.
+	Code uint16
+	// The rounding of the converted amount. The zero value, amount.Exact, rejects amounts that
+	// do not convert exactly.
+	Rounding amount.RoundingMode
+	// An optional fee leg.
+	Fee *Fee
+	// The `UserData128`, `UserData64`, and `UserData32` of every leg, such as the rate's source.
+	UserData128 types.Uint128
+	UserData64  uint64
+	UserData32  uint32
+}
+
+type ErrInvalidRate struct {
+	Rate Rate
+}
+
+func (e ErrInvalidRate) Error() string {
+	return fmt.Sprintf("Invalid exchange rate %s.", e.Rate)
+}
+
+// ErrInvalidAmount is returned when the amount to exchange, or its conversion, is zero.
+type ErrInvalidAmount struct {
+	Reason string
+}
+
+func (e ErrInvalidAmount) Error() string {
+	return fmt.Sprintf("Invalid amount: %s.", e.Reason)
+}
+
+// ErrInvalidFee is returned when the fee has no account or no amount.
+type ErrInvalidFee struct {
+	Reason string
+}
+
+func (e ErrInvalidFee) Error() string {
+	return fmt.Sprintf("Invalid fee: %s.", e.Reason)
+}
+
+type ErrNoLiquidityAccount struct {
+	Ledger uint32
+}
+
+func (e ErrNoLiquidityAccount) Error() string {
+	return fmt.Sprintf("No liquidity account for ledger %d.", e.Ledger)
+}
+
+// ErrSameLedger is returned for exchanges within a ledger, which need a single transfer.
+type ErrSameLedger struct {
+	Ledger uint32
+}
+
+func (e ErrSameLedger) Error() string {
+	return fmt.Sprintf("Both accounts are on ledger %d.", e.Ledger)
+}
+
+// Leg is a transfer of an exchange, and the result of creating it.
+type Leg struct {
+	Transfer types.Transfer
+	Result   types.CreateTransferResult
+}
+
+// Result is the outcome of an exchange: the amounts, and a result per leg, in order: in, out,
+// and the fee if any.
+type Result struct {
+	ID        types.Uint128
+	AmountIn  types.Uint128
+	AmountOut types.Uint128
+	Legs      []Leg
+	// Whether the exchange already existed with the same legs. Resubmitting an exchange fails the
+	// chain at its first leg with `TransferExists`, and the other legs with
+	// `TransferLinkedEventFailed`.
+	Exists bool
+}
+
+// OK returns whether every leg was created, or the exchange already existed with the same legs.
+func (r Result) OK() bool {
+	if r.Exists {
+		return true
+	}
+	for _, leg := range r.Legs {
+		if leg.Result != types.TransferOK {
+			return false
+		}
+	}
+	return true
+}
+
+// Exchange converts `amountIn` units from the account `from` to the account `to`, on a different
+// ledger, through the liquidity accounts of their ledgers, keyed by ledger.
+//
+// The error is only set if the exchange is invalid, if an account is not found (wrapping
+// errors.ErrAccountNotFound with its ID), if the exchange could not be submitted, or if it already
+// exists with different legs (transaction.ErrConflict); the outcome of each leg is reported in
+// the Result.
+func Exchange(
+	client Client,
+	from types.Uint128,
+	to types.Uint128,
+	amountIn types.Uint128,
+	rate Rate,
+	liquidityAccounts map[uint32]types.Uint128,
+	options Options,
+) (Result, error) {
+	if amountIn.IsZero() {
+		return Result{}, ErrInvalidAmount{Reason: "the amount in must not be zero"}
+	}
+	if fee := options.Fee; fee != nil {
+		if fee.AccountID.IsZero() {
+			return Result{}, ErrInvalidFee{Reason: "the account ID must not be zero"}
+		}
+		if fee.Amount.IsZero() {
+			return Result{}, ErrInvalidFee{Reason: "the amount must not be zero"}
+		}
+	}
+	amountOut, err := rate.Convert(amountIn, options.Rounding)
+	if err != nil {
+		return Result{}, err
+	}
+	if amountOut.IsZero() {
+		return Result{}, ErrInvalidAmount{Reason: "the amount out must not round to zero"}
+	}
+
+	accounts, err := client.LookupAccounts([]types.Uint128{from, to})
+	if err != nil {
+		return Result{}, err
+	}
+	ledgers := make(map[types.Uint128]uint32, len(accounts))
+	for _, account := range accounts {
+		ledgers[account.ID] = account.Ledger
+	}
+	for _, id := range []types.Uint128{from, to} {
+		if _, ok := ledgers[id]; !ok {
+			return Result{}, fmt.Errorf("account %s: %w", id, errors.ErrAccountNotFound{})
+		}
+	}
+	ledgerIn, ledgerOut := ledgers[from], ledgers[to]
+	if ledgerIn == ledgerOut {
+		return Result{}, ErrSameLedger{Ledger: ledgerIn}
+	}
+	liquidityIn, ok := liquidityAccounts[ledgerIn]
+	if !ok {
+		return Result{}, ErrNoLiquidityAccount{Ledger: ledgerIn}
+	}
+	liquidityOut, ok := liquidityAccounts[ledgerOut]
+	if !ok {
+		return Result{}, ErrNoLiquidityAccount{Ledger: ledgerOut}
+	}
+
+	leg := func(debit types.Uint128, credit types.Uint128, units types.Uint128, ledger uint32) types.Transfer {
+		return types.Transfer{
+			DebitAccountID:  debit,
+			CreditAccountID: credit,
+			Amount:          units,
+			UserData128:     options.UserData128,
+			UserData64:      options.UserData64,
+			UserData32:      options.UserData32,
+			Ledger:          ledger,
+			Code:            options.Code,
+		}
+	}
+	legs := []types.Transfer{
+		leg(from, liquidityIn, amountIn, ledgerIn),
+		leg(liquidityOut, to, amountOut, ledgerOut),
+	}
+	if options.Fee != nil {
+		legs = append(legs, leg(from, options.Fee.AccountID, options.Fee.Amount, ledgerIn))
+	}
+
+	id := options.ID
+	if id.IsZero() {
+		id = types.ID()
+	}
+	legs, err = transaction.Legs(id, legs)
+	if err != nil {
+		return Result{}, err
+	}
+	results, err := client.CreateTransfers(legs)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{ID: id, AmountIn: amountIn, AmountOut: amountOut, Legs: make([]Leg, len(legs))}
+	for i, transfer := range legs {
+		result.Legs[i] = Leg{Transfer: transfer, Result: types.TransferOK}
+	}
+	for _, event := range results {
+		result.Legs[event.Index].Result = event.Result
+	}
+	if first := result.Legs[0].Result; first == types.TransferExists || first.ExistsWithDifferent() {
+		if err := transaction.New(client).Verify(id, legs); err != nil {
+			return result, err
+		}
+		result.Exists = true
+	}
+	return result, nil
+}
@@ -0,0 +1,160 @@
+package fx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/amount"
+	tb_errors "github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/transaction"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+const (
+	usd uint32 = 1
+	eur uint32 = 2
+)
+
+var (
+	alice        = types.ToUint128(1)
+	bob          = types.ToUint128(2)
+	fees         = types.ToUint128(3)
+	liquidityUSD = types.ToUint128(10)
+	liquidityEUR = types.ToUint128(20)
+	// Bob's EUR account can only be credited up to its debits.
+	limited = types.ToUint128(4)
+
+	liquidity = map[uint32]types.Uint128{usd: liquidityUSD, eur: liquidityEUR}
+	// 0.92 EUR per USD.
+	rate = Rate{Numerator: 92, Denominator: 100}
+)
+
+func setup(t *testing.T) *fake.Cluster {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	results, err := cluster.CreateAccounts([]types.Account{
+		{ID: alice, Ledger: usd, Code: 1},
+		{ID: fees, Ledger: usd, Code: 1},
+		{ID: liquidityUSD, Ledger: usd, Code: 2},
+		{ID: bob, Ledger: eur, Code: 1},
+		{ID: limited, Ledger: eur, Code: 1, Flags: types.AccountFlagCreditsMustNotExceedDebits.ToUint16()},
+		{ID: liquidityEUR, Ledger: eur, Code: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+	return cluster
+}
+
+func Test_Exchange(t *testing.T) {
+	cluster := setup(t)
+	id := types.ToUint128(1000)
+	result, err := Exchange(cluster, alice, bob, types.ToUint128(1001), rate, liquidity, Options{
+		ID:       id,
+		Code:     7,
+		Rounding: amount.RoundDown,
+		Fee:      &Fee{AccountID: fees, Amount: types.ToUint128(5)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, result.OK())
+	assert.Equal(t, id, result.ID)
+	// 1001 * 0.92 = 920.92, rounded down.
+	assert.Equal(t, types.ToUint128(920), result.AmountOut)
+	assert.Len(t, result.Legs, 3)
+	assert.Equal(t, transaction.LegID(id, 0), result.Legs[0].Transfer.ID)
+	assert.Equal(t, liquidityUSD, result.Legs[0].Transfer.CreditAccountID)
+	assert.Equal(t, liquidityEUR, result.Legs[1].Transfer.DebitAccountID)
+	assert.Equal(t, eur, result.Legs[1].Transfer.Ledger)
+	assert.Equal(t, fees, result.Legs[2].Transfer.CreditAccountID)
+
+	accounts, err := cluster.LookupAccounts([]types.Uint128{alice, bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(1006), accounts[0].DebitsPosted)
+	assert.Equal(t, types.ToUint128(920), accounts[1].CreditsPosted)
+
+	// Submitting the same exchange again is idempotent.
+	again, err := Exchange(cluster, alice, bob, types.ToUint128(1001), rate, liquidity, Options{
+		ID:       id,
+		Code:     7,
+		Rounding: amount.RoundDown,
+		Fee:      &Fee{AccountID: fees, Amount: types.ToUint128(5)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, again.OK())
+	assert.True(t, again.Exists)
+	assert.Equal(t, types.TransferExists, again.Legs[0].Result)
+	assert.Equal(t, types.TransferLinkedEventFailed, again.Legs[1].Result)
+
+	// Submitting it again with a different fee conflicts, although only the last leg differs.
+	conflicting, err := Exchange(cluster, alice, bob, types.ToUint128(1001), rate, liquidity, Options{
+		ID:       id,
+		Code:     7,
+		Rounding: amount.RoundDown,
+		Fee:      &Fee{AccountID: fees, Amount: types.ToUint128(6)},
+	})
+	assert.Equal(t, transaction.ErrConflict{ID: id, Index: 2, Fields: []types.FieldDiff{
+		{Field: "amount", Submitted: "6", Stored: "5"},
+	}}, err)
+	assert.True(t, !conflicting.OK())
+}
+
+func Test_Exchange_Failed(t *testing.T) {
+	cluster := setup(t)
+	result, err := Exchange(cluster, alice, limited, types.ToUint128(100), rate, liquidity, Options{Code: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, !result.OK())
+	assert.Equal(t, types.TransferLinkedEventFailed, result.Legs[0].Result)
+	assert.Equal(t, types.TransferExceedsDebits, result.Legs[1].Result)
+
+	accounts, err := cluster.LookupAccounts([]types.Uint128{alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.Uint128{}, accounts[0].DebitsPosted)
+}
+
+func Test_Exchange_Invalid(t *testing.T) {
+	cluster := setup(t)
+	exchange := func(from types.Uint128, to types.Uint128, rate Rate, liquidity map[uint32]types.Uint128) error {
+		_, err := Exchange(cluster, from, to, types.ToUint128(1001), rate, liquidity, Options{Code: 7})
+		return err
+	}
+	assert.Equal(t, amount.ErrInexact{}, exchange(alice, bob, rate, liquidity))
+	assert.Equal(t, ErrInvalidRate{Rate: Rate{Numerator: 1}}, exchange(alice, bob, Rate{Numerator: 1}, liquidity))
+	err := exchange(alice, types.ToUint128(99), Rate{1, 1}, liquidity)
+	assert.True(t, errors.Is(err, tb_errors.ErrAccountNotFound{}))
+	assert.Equal(t, "account "+types.ToUint128(99).String()+": Account not found.", err.Error())
+	assert.Equal(t, ErrSameLedger{Ledger: usd}, exchange(alice, fees, Rate{1, 1}, liquidity))
+	assert.Equal(t, ErrNoLiquidityAccount{Ledger: eur}, exchange(alice, bob, Rate{1, 1}, map[uint32]types.Uint128{usd: liquidityUSD}))
+
+	_, err = Exchange(cluster, alice, bob, types.Uint128{}, rate, liquidity, Options{Code: 7})
+	_, ok := err.(ErrInvalidAmount)
+	assert.True(t, ok)
+	// 1 * 0.92 rounds down to zero.
+	_, err = Exchange(cluster, alice, bob, types.ToUint128(1), rate, liquidity, Options{Code: 7, Rounding: amount.RoundDown})
+	_, ok = err.(ErrInvalidAmount)
+	assert.True(t, ok)
+
+	// A fee needs an account and an amount.
+	_, err = Exchange(cluster, alice, bob, types.ToUint128(1000), rate, liquidity, Options{
+		Code: 7,
+		Fee:  &Fee{Amount: types.ToUint128(5)},
+	})
+	assert.Equal(t, ErrInvalidFee{Reason: "the account ID must not be zero"}, err)
+	_, err = Exchange(cluster, alice, bob, types.ToUint128(1000), rate, liquidity, Options{
+		Code: 7,
+		Fee:  &Fee{AccountID: fees},
+	})
+	assert.Equal(t, ErrInvalidFee{Reason: "the amount must not be zero"}, err)
+}
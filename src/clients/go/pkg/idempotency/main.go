@@ -0,0 +1,124 @@
+// Package idempotency maps string idempotency keys to stable transfer IDs.
+//
+// Retrying a request with the same key must resubmit the same `Transfer.ID`, even across process
+// restarts, so that the cluster deduplicates it and answers `TransferExists`. IDs are derived by
+// hashing the key (see types.UUIDv8), and the mapping is persisted in a Store, which rejects a
+// derived ID that is already claimed by a different key.
+//
+// Reusing a key for a different transfer is detected by the cluster itself: the resubmitted
+// transfer has the same ID but differs in some field, and fails with one of the
+// `TransferExistsWithDifferent*` results. CreateTransfers reports these as ErrKeyReused.
+package idempotency
+
+import (
+	"fmt"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to create transfers.
+type Client interface {
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+}
+
+// Keys derives and records the transfer IDs of idempotency keys.
+type Keys struct {
+	store     Store
+	namespace types.Uint128
+}
+
+// New returns Keys that derive IDs within the given namespace, and record them in the store.
+// Use a distinct namespace (for example, a random UUID) for each source of keys, and never
+// change it, as the same key in different namespaces derives different IDs.
+func New(store Store, namespace types.Uint128) *Keys {
+	return &Keys{store: store, namespace: namespace}
+}
+
+// ID returns the transfer ID for a key: the recorded ID if there is one, or else a newly derived
+// and recorded ID.
+func (k *Keys) ID(key string) (types.Uint128, error) {
+	id, found, err := k.store.Lookup(key)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	if found {
+		return id, nil
+	}
+	id = types.UUIDv8(k.namespace, []byte(key))
+	if err := k.store.Claim(key, id); err != nil {
+		return types.Uint128{}, err
+	}
+	return id, nil
+}
+
+// Request is a transfer to create under an idempotency key.
+// The transfer's ID is ignored and replaced with the key's ID.
+type Request struct {
+	Key      string
+	Transfer types.Transfer
+}
+
+// Result is the outcome of a Request.
+type Result struct {
+	Key string
+	ID  types.Uint128
+	// TransferOK, or TransferExists when the request was a retry.
+	Result types.CreateTransferResult
+	// Nil if the transfer was created now or before, see Result.
+	Err error
+}
+
+type ErrKeyReused struct {
+	Key string
+	// One of the `TransferExistsWithDifferent*` results.
+	Result types.CreateTransferResult
+}
+
+func (e ErrKeyReused) Error() string {
+	return fmt.Sprintf("Idempotency key %q was reused with a different payload (%s).", e.Key, e.Result)
+}
+
+type ErrTransferFailed struct {
+	Key    string
+	Result types.CreateTransferResult
+}
+
+func (e ErrTransferFailed) Error() string {
+	return fmt.Sprintf("Transfer for idempotency key %q failed: %s.", e.Key, e.Result)
+}
+
+// CreateTransfers creates the transfers of the requests in a single batch, with their IDs
+// derived from their keys, and returns a result for each request, in order.
+// The error is only set if the IDs could not be derived, or if the batch could not be submitted;
+// the outcome of each transfer is reported in its Result.
+func (k *Keys) CreateTransfers(client Client, requests []Request) ([]Result, error) {
+	transfers := make([]types.Transfer, len(requests))
+	results := make([]Result, len(requests))
+	for i, request := range requests {
+		id, err := k.ID(request.Key)
+		if err != nil {
+			return nil, err
+		}
+		transfers[i] = request.Transfer
+		transfers[i].ID = id
+		results[i] = Result{Key: request.Key, ID: id, Result: types.TransferOK}
+	}
+
+	eventResults, err := client.CreateTransfers(transfers)
+	if err != nil {
+		return nil, err
+	}
+	for _, eventResult := range eventResults {
+		result := &results[eventResult.Index]
+		result.Result = eventResult.Result
+		if eventResult.Result == types.TransferExists {
+			continue
+		}
+		if eventResult.Result.ExistsWithDifferent() {
+			result.Err = ErrKeyReused{Key: result.Key, Result: eventResult.Result}
+		} else {
+			result.Err = ErrTransferFailed{Key: result.Key, Result: eventResult.Result}
+		}
+	}
+	return results, nil
+}
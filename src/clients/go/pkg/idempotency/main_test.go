@@ -0,0 +1,224 @@
+package idempotency
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+var namespace = types.UUIDv8(types.UUIDNamespaceURL, []byte("https://example.com/payments"))
+
+func Test_CreateTransfers(t *testing.T) {
+	cluster := fake.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	debit := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+	credit := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+	results, err := cluster.CreateAccounts([]types.Account{debit, credit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	transfer := func(amount uint64) types.Transfer {
+		return types.Transfer{
+			DebitAccountID:  debit.ID,
+			CreditAccountID: credit.ID,
+			Amount:          types.ToUint128(amount),
+			Ledger:          1,
+			Code:            1,
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.jsonl")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := New(store, namespace)
+	first, err := keys.CreateTransfers(cluster, []Request{
+		{Key: "payment-1", Transfer: transfer(10)},
+		{Key: "payment-2", Transfer: transfer(0)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.TransferOK, first[0].Result)
+	assert.Equal(t, nil, first[0].Err)
+	assert.Equal(t, ErrTransferFailed{Key: "payment-2", Result: types.TransferAmountMustNotBeZero}, first[1].Err)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart, a retry resubmits the same ID.
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	keys = New(store, namespace)
+	retry, err := keys.CreateTransfers(cluster, []Request{
+		{Key: "payment-1", Transfer: transfer(10)},
+		{Key: "payment-1", Transfer: transfer(11)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, first[0].ID, retry[0].ID)
+	assert.Equal(t, types.TransferExists, retry[0].Result)
+	assert.Equal(t, nil, retry[0].Err)
+	assert.Equal(t, ErrKeyReused{Key: "payment-1", Result: types.TransferExistsWithDifferentAmount}, retry[1].Err)
+
+	transfers, err := cluster.LookupTransfers([]types.Uint128{first[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, transfers, 1)
+	assert.Equal(t, types.ToUint128(10), transfers[0].Amount)
+}
+
+func Test_MemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	a, b := types.ID(), types.ID()
+	if err := store.Claim("a", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Claim("a", a); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrKeyClaimed{Key: "a", ID: a}, store.Claim("a", b))
+	assert.Equal(t, ErrIDCollision{ID: a, Key: "a"}, store.Claim("b", a))
+
+	id, found, err := store.Lookup("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, found)
+	assert.Equal(t, a, id)
+	_, found, _ = store.Lookup("b")
+	assert.True(t, !found)
+
+	// Recorded IDs take precedence over derived ones.
+	keys := New(store, namespace)
+	id, err = keys.ID("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, a, id)
+	id, err = keys.ID("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.UUIDv8(namespace, []byte("b")), id)
+}
+
+func Test_FileStore_Recovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.jsonl")
+	a := types.ID()
+	err := os.WriteFile(path, []byte(`{"key":"a","id":"`+a.DecimalString()+`"}`+"\n"+`{"key":"b","i`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := types.ID()
+	if err := store.Claim("b", b); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for key, expected := range map[string]types.Uint128{"a": a, "b": b} {
+		id, found, err := store.Lookup(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, found)
+		assert.Equal(t, expected, id)
+	}
+
+	err = os.WriteFile(path, []byte("not json\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(path); err == nil {
+		t.Fatal("Expected a corrupt line to be rejected")
+	}
+}
+
+// shortFile fails the next write after writing only part of it.
+type shortFile struct {
+	storeFile
+	fail bool
+}
+
+func (f *shortFile) Write(data []byte) (int, error) {
+	if !f.fail {
+		return f.storeFile.Write(data)
+	}
+	f.fail = false
+	written, err := f.storeFile.Write(data[:len(data)/2])
+	if err != nil {
+		return written, err
+	}
+	return written, io.ErrShortWrite
+}
+
+func Test_FileStore_ShortWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.jsonl")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := types.ID(), types.ID(), types.ID()
+	if err := store.Claim("a", a); err != nil {
+		t.Fatal(err)
+	}
+	store.file = &shortFile{storeFile: store.file, fail: true}
+	assert.Equal(t, io.ErrShortWrite, store.Claim("b", b))
+	_, found, err := store.Lookup("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, false, found)
+
+	// The partial line was removed, so the store remains usable.
+	if err := store.Claim("c", c); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for key, expected := range map[string]types.Uint128{"a": a, "c": c} {
+		id, found, err := store.Lookup(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, found)
+		assert.Equal(t, expected, id)
+	}
+	_, found, err = store.Lookup("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, false, found)
+}
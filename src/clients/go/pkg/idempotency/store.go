@@ -0,0 +1,230 @@
+package idempotency
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Store persists the mapping from idempotency keys to transfer IDs.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Lookup returns the ID claimed by a key, if any.
+	Lookup(key string) (id types.Uint128, found bool, err error)
+	// Claim durably records that the key maps to the ID. Claiming the same key and ID again
+	// succeeds. It returns ErrKeyClaimed if the key maps to another ID, and ErrIDCollision if the
+	// ID is claimed by another key.
+	Claim(key string, id types.Uint128) error
+}
+
+type ErrKeyClaimed struct {
+	Key string
+	ID  types.Uint128
+}
+
+func (e ErrKeyClaimed) Error() string {
+	return fmt.Sprintf("Idempotency key %q is already claimed by ID %s.", e.Key, e.ID.UUIDString())
+}
+
+type ErrIDCollision struct {
+	ID types.Uint128
+	// The key that claimed the ID first.
+	Key string
+}
+
+func (e ErrIDCollision) Error() string {
+	return fmt.Sprintf("ID %s is already claimed by idempotency key %q.", e.ID.UUIDString(), e.Key)
+}
+
+// claims is the in-memory mapping, in both directions.
+type claims struct {
+	ids  map[string]types.Uint128
+	keys map[types.Uint128]string
+}
+
+func newClaims() claims {
+	return claims{ids: map[string]types.Uint128{}, keys: map[types.Uint128]string{}}
+}
+
+// check returns whether the key already maps to the ID, or an error if the claim conflicts.
+func (c *claims) check(key string, id types.Uint128) (bool, error) {
+	if existing, found := c.ids[key]; found {
+		if existing != id {
+			return false, ErrKeyClaimed{Key: key, ID: existing}
+		}
+		return true, nil
+	}
+	if existing, found := c.keys[id]; found {
+		return false, ErrIDCollision{ID: id, Key: existing}
+	}
+	return false, nil
+}
+
+func (c *claims) add(key string, id types.Uint128) {
+	c.ids[key] = id
+	c.keys[id] = key
+}
+
+// MemoryStore is a Store that is lost with the process, for tests and short-lived keys.
+type MemoryStore struct {
+	mutex  sync.Mutex
+	claims claims
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{claims: newClaims()}
+}
+
+func (s *MemoryStore) Lookup(key string) (types.Uint128, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id, found := s.claims.ids[key]
+	return id, found, nil
+}
+
+func (s *MemoryStore) Claim(key string, id types.Uint128) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	claimed, err := s.claims.check(key, id)
+	if err != nil || claimed {
+		return err
+	}
+	s.claims.add(key, id)
+	return nil
+}
+
+// FileStore is a Store backed by an append-only file of JSON lines, one per claim.
+// Each claim is synced to disk before Claim returns. A claim that fails to be written is removed
+// from the file, and if that fails too, every later Claim fails.
+type FileStore struct {
+	mutex  sync.Mutex
+	claims claims
+	file   storeFile
+	// Set if a failed claim could not be removed from the file, which is then unusable.
+	err error
+}
+
+// storeFile is the subset of *os.File used by FileStore.
+type storeFile interface {
+	io.WriteSeeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+type fileRecord struct {
+	Key string        `json:"key"`
+	ID  types.Uint128 `json:"id"`
+}
+
+// OpenFileStore opens or creates the store at the given path, and loads its claims.
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	store, err := loadFileStore(path, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func loadFileStore(path string, file *os.File) (*FileStore, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// A torn final line, from a crash during Claim, is not a claim since Claim had not returned.
+	// Truncate it so that the next claim starts on a new line.
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	if complete < len(data) {
+		if err := file.Truncate(int64(complete)); err != nil {
+			return nil, err
+		}
+		data = data[:complete]
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return nil, err
+	}
+
+	store := &FileStore{claims: newClaims(), file: file}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record fileRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		claimed, err := store.claims.check(record.Key, record.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		if !claimed {
+			store.claims.add(record.Key, record.ID)
+		}
+	}
+	return store, nil
+}
+
+func (s *FileStore) Lookup(key string) (types.Uint128, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id, found := s.claims.ids[key]
+	return id, found, nil
+}
+
+func (s *FileStore) Claim(key string, id types.Uint128) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	claimed, err := s.claims.check(key, id)
+	if err != nil || claimed {
+		return err
+	}
+
+	line, err := json.Marshal(fileRecord{Key: key, ID: id})
+	if err != nil {
+		return err
+	}
+	offset, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if err := s.append(append(line, '\n')); err != nil {
+		// A failed write may leave part of the line, and a failed sync the whole line, which
+		// is not a claim since Claim did not succeed. Remove it, so that the next claim starts
+		// on a new line.
+		if _, seekErr := s.file.Seek(offset, io.SeekStart); seekErr != nil {
+			s.err = fmt.Errorf("removing a failed claim: %w", seekErr)
+		} else if truncateErr := s.file.Truncate(offset); truncateErr != nil {
+			s.err = fmt.Errorf("removing a failed claim: %w", truncateErr)
+		}
+		return err
+	}
+	s.claims.add(key, id)
+	return nil
+}
+
+func (s *FileStore) append(line []byte) error {
+	if _, err := s.file.Write(line); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
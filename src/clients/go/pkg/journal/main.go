@@ -0,0 +1,244 @@
+// Package journal posts compound journal entries, with any number of debit and credit legs.
+//
+// A transfer moves an amount from one debit account to one credit account, so an entry is
+// decomposed into a chain of linked transfers through a suspense account of its ledger: each debit
+// leg is a transfer from its account to the suspense account, and each credit leg a transfer from
+// the suspense account to its account. The chain is atomic, and as the entry balances, it leaves
+// the suspense account's balance unchanged.
+//
+// An entry is a transaction (see package transaction) whose ID is the entry's ID, which is also
+// each leg's `UserData128`. Posting an entry again is idempotent, and Lookup reassembles an entry
+// from its ID.
+package journal
+
+import (
+	"fmt"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/transaction"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to post and look up entries.
+type Client interface {
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+	LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error)
+}
+
+// batchMax is the maximum number of events per request, and so of legs per entry.
+const batchMax = 8190
+
+// Leg is the amount that an entry debits or credits to one account.
+type Leg struct {
+	AccountID types.Uint128
+	Amount    types.Uint128
+}
+
+// Entry is a compound journal entry of a single ledger. Its debits and credits must balance.
+type Entry struct {
+	// The ID that ties the legs together. If zero, Post sets it with types.ID.
+	ID         types.Uint128
+	Ledger     uint32
+	Code       uint16
+	UserData64 uint64
+	UserData32 uint32
+	Debits     []Leg
+	Credits    []Leg
+}
+
+// ErrUnbalanced is returned when the debits and credits of an entry have different totals.
+type ErrUnbalanced struct {
+	Debits  types.Uint128
+	Credits types.Uint128
+}
+
+func (e ErrUnbalanced) Error() string {
+	return fmt.Sprintf("Entry is unbalanced: debits %s, credits %s.",
+		e.Debits.DecimalString(), e.Credits.DecimalString())
+}
+
+// ErrInvalidEntry is returned when an entry has no ID, is missing debits or credits, has too many
+// legs, or has a leg with a zero amount or on the suspense account.
+type ErrInvalidEntry struct {
+	Reason string
+}
+
+func (e ErrInvalidEntry) Error() string {
+	return fmt.Sprintf("Invalid entry: %s.", e.Reason)
+}
+
+// ErrNoSuspenseAccount is returned when the Journal has no suspense account for the entry's ledger.
+type ErrNoSuspenseAccount struct {
+	Ledger uint32
+}
+
+func (e ErrNoSuspenseAccount) Error() string {
+	return fmt.Sprintf("No suspense account for ledger %d.", e.Ledger)
+}
+
+// ErrNotFound is returned by Lookup when no entry has the ID.
+type ErrNotFound struct {
+	ID types.Uint128
+}
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("Entry %s not found.", e.ID.DecimalString())
+}
+
+// ErrTransferFailed is returned when the cluster rejects a leg, and so the whole entry.
+type ErrTransferFailed struct {
+	ID types.Uint128
+	// The leg's index in the chain: debits first, then credits.
+	Index  uint32
+	Result types.CreateTransferResult
+}
+
+func (e ErrTransferFailed) Error() string {
+	return fmt.Sprintf("Entry %s failed at leg %d: %s.", e.ID.DecimalString(), e.Index, e.Result)
+}
+
+// ErrConflict is returned when posting an entry that was already posted with different legs.
+type ErrConflict struct {
+	ID types.Uint128
+	// The first leg that differs, as in ErrTransferFailed, and its differing fields.
+	Index  uint32
+	Fields []types.FieldDiff
+}
+
+func (e ErrConflict) Error() string {
+	return fmt.Sprintf("Entry %s was posted with a different leg %d.", e.ID.DecimalString(), e.Index)
+}
+
+// Journal posts entries through the suspense account of each ledger.
+type Journal struct {
+	client   Client
+	suspense map[uint32]types.Uint128
+}
+
+// New returns a Journal that posts entries of each ledger through its suspense account. The
+// suspense accounts must exist, and must not have the `DebitsMustNotExceedCredits` or
+// `CreditsMustNotExceedDebits` flags, as the debit legs are posted before the credit legs.
+func New(client Client, suspense map[uint32]types.Uint128) *Journal {
+	accounts := make(map[uint32]types.Uint128, len(suspense))
+	for ledger, id := range suspense {
+		accounts[ledger] = id
+	}
+	return &Journal{client: client, suspense: accounts}
+}
+
+// Transfers validates the entry and returns the chain of transfers that posts it.
+func (j *Journal) Transfers(entry Entry) ([]types.Transfer, error) {
+	suspense, ok := j.suspense[entry.Ledger]
+	if !ok {
+		return nil, ErrNoSuspenseAccount{Ledger: entry.Ledger}
+	}
+	if entry.ID.IsZero() {
+		return nil, ErrInvalidEntry{Reason: "ID must not be zero"}
+	}
+	if len(entry.Debits) == 0 || len(entry.Credits) == 0 {
+		return nil, ErrInvalidEntry{Reason: "there must be at least one debit and one credit"}
+	}
+	if len(entry.Debits)+len(entry.Credits) > batchMax {
+		return nil, ErrInvalidEntry{Reason: fmt.Sprintf("there must be at most %d legs", batchMax)}
+	}
+
+	total := func(legs []Leg) (types.Uint128, error) {
+		var sum types.Uint128
+		for _, leg := range legs {
+			if leg.Amount.IsZero() {
+				return sum, ErrInvalidEntry{Reason: "leg amounts must not be zero"}
+			}
+			if leg.AccountID == suspense {
+				return sum, ErrInvalidEntry{Reason: "legs must not use the suspense account"}
+			}
+			var err error
+			if sum, err = sum.AddChecked(leg.Amount); err != nil {
+				return sum, ErrInvalidEntry{Reason: "total amount overflows"}
+			}
+		}
+		return sum, nil
+	}
+	debits, err := total(entry.Debits)
+	if err != nil {
+		return nil, err
+	}
+	credits, err := total(entry.Credits)
+	if err != nil {
+		return nil, err
+	}
+	if debits != credits {
+		return nil, ErrUnbalanced{Debits: debits, Credits: credits}
+	}
+
+	transfers := make([]types.Transfer, 0, len(entry.Debits)+len(entry.Credits))
+	leg := func(debit types.Uint128, credit types.Uint128, amount types.Uint128) {
+		transfers = append(transfers, types.Transfer{
+			DebitAccountID:  debit,
+			CreditAccountID: credit,
+			Amount:          amount,
+			UserData128:     entry.ID,
+			UserData64:      entry.UserData64,
+			UserData32:      entry.UserData32,
+			Ledger:          entry.Ledger,
+			Code:            entry.Code,
+		})
+	}
+	for _, debit := range entry.Debits {
+		leg(debit.AccountID, suspense, debit.Amount)
+	}
+	for _, credit := range entry.Credits {
+		leg(suspense, credit.AccountID, credit.Amount)
+	}
+	return transaction.Legs(entry.ID, transfers)
+}
+
+// Post posts the entry atomically, and returns its ID. Posting an entry that was already posted
+// succeeds without effect, unless its legs differ, which returns ErrConflict.
+func (j *Journal) Post(entry Entry) (types.Uint128, error) {
+	if entry.ID.IsZero() {
+		entry.ID = types.ID()
+	}
+	transfers, err := j.Transfers(entry)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	err = transaction.New(j.client).CreateTransaction(entry.ID, transfers)
+	switch err := err.(type) {
+	case nil:
+		return entry.ID, nil
+	case transaction.ErrTransferFailed:
+		return types.Uint128{}, ErrTransferFailed{ID: entry.ID, Index: err.Index, Result: err.Result}
+	case transaction.ErrConflict:
+		return types.Uint128{}, ErrConflict{ID: entry.ID, Index: err.Index, Fields: err.Fields}
+	}
+	return types.Uint128{}, err
+}
+
+// Lookup reassembles a posted entry from its legs.
+func (j *Journal) Lookup(id types.Uint128) (Entry, error) {
+	legs, err := transaction.New(j.client).LookupTransaction(id)
+	if _, ok := err.(transaction.ErrNotFound); ok {
+		return Entry{}, ErrNotFound{ID: id}
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+
+	first := legs[0]
+	entry := Entry{
+		ID:         id,
+		Ledger:     first.Ledger,
+		Code:       first.Code,
+		UserData64: first.UserData64,
+		UserData32: first.UserData32,
+	}
+	// Debit legs credit the suspense account, which the first leg always does.
+	suspense := first.CreditAccountID
+	for _, transfer := range legs {
+		if transfer.CreditAccountID == suspense {
+			entry.Debits = append(entry.Debits, Leg{AccountID: transfer.DebitAccountID, Amount: transfer.Amount})
+		} else {
+			entry.Credits = append(entry.Credits, Leg{AccountID: transfer.CreditAccountID, Amount: transfer.Amount})
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,145 @@
+package journal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/transaction"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func Test_Post(t *testing.T) {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	suspense := types.ToUint128(100)
+	cash := types.ToUint128(1)
+	receivable := types.ToUint128(2)
+	revenue := types.ToUint128(3)
+	tax := types.ToUint128(4)
+	limited := types.ToUint128(5)
+	accounts := []types.Account{
+		{ID: suspense, Ledger: 1, Code: 1},
+		{ID: cash, Ledger: 1, Code: 1},
+		{ID: receivable, Ledger: 1, Code: 1},
+		{ID: revenue, Ledger: 1, Code: 1},
+		{ID: tax, Ledger: 1, Code: 1},
+		{ID: limited, Ledger: 1, Code: 1, Flags: types.AccountFlagDebitsMustNotExceedCredits.ToUint16()},
+	}
+	accountResults, err := cluster.CreateAccounts(accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, accountResults)
+
+	journal := New(cluster, map[uint32]types.Uint128{1: suspense})
+	entry := Entry{
+		Ledger:     1,
+		Code:       7,
+		UserData64: 42,
+		Debits: []Leg{
+			{AccountID: cash, Amount: types.ToUint128(60)},
+			{AccountID: receivable, Amount: types.ToUint128(50)},
+		},
+		Credits: []Leg{
+			{AccountID: revenue, Amount: types.ToUint128(100)},
+			{AccountID: tax, Amount: types.ToUint128(10)},
+		},
+	}
+	id, err := journal.Post(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, types.Uint128{}, id)
+
+	// Posting again is idempotent.
+	entry.ID = id
+	again, err := journal.Post(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, again)
+
+	// Posting again with different legs conflicts, although only the credits differ.
+	conflicting := entry
+	conflicting.Credits = []Leg{
+		{AccountID: revenue, Amount: types.ToUint128(101)},
+		{AccountID: tax, Amount: types.ToUint128(9)},
+	}
+	_, err = journal.Post(conflicting)
+	assert.Equal(t, ErrConflict{ID: id, Index: 2, Fields: []types.FieldDiff{
+		{Field: "amount", Submitted: "101", Stored: "100"},
+	}}, err)
+
+	found, err := journal.Lookup(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, entry, found)
+
+	balances, err := cluster.LookupAccounts([]types.Uint128{suspense, cash, tax})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(110), balances[0].DebitsPosted)
+	assert.Equal(t, types.ToUint128(110), balances[0].CreditsPosted)
+	assert.Equal(t, types.ToUint128(60), balances[1].DebitsPosted)
+	assert.Equal(t, types.ToUint128(10), balances[2].CreditsPosted)
+
+	_, err = journal.Lookup(types.ToUint128(999))
+	assert.Equal(t, ErrNotFound{ID: types.ToUint128(999)}, err)
+
+	// The chain is atomic.
+	failing := Entry{
+		ID:      types.ToUint128(1000),
+		Ledger:  1,
+		Code:    7,
+		Debits:  []Leg{{AccountID: cash, Amount: types.ToUint128(5)}, {AccountID: limited, Amount: types.ToUint128(5)}},
+		Credits: []Leg{{AccountID: revenue, Amount: types.ToUint128(10)}},
+	}
+	_, err = journal.Post(failing)
+	assert.Equal(t, ErrTransferFailed{ID: failing.ID, Index: 1, Result: types.TransferExceedsCredits}, err)
+	_, err = journal.Lookup(failing.ID)
+	assert.Equal(t, ErrNotFound{ID: failing.ID}, err)
+}
+
+func Test_Transfers(t *testing.T) {
+	journal := New(nil, map[uint32]types.Uint128{1: types.ToUint128(100)})
+	leg := func(account uint64, amount uint64) Leg {
+		return Leg{AccountID: types.ToUint128(account), Amount: types.ToUint128(amount)}
+	}
+	entry := Entry{ID: types.ToUint128(1), Ledger: 1, Code: 1, Debits: []Leg{leg(1, 10)}, Credits: []Leg{leg(2, 4), leg(3, 6)}}
+
+	transfers, err := journal.Transfers(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, transfers, 3)
+	assert.Equal(t, types.TransferFlagLinked.ToUint16(), transfers[0].Flags)
+	assert.Equal(t, types.TransferFlagLinked.ToUint16(), transfers[1].Flags)
+	assert.Equal(t, uint16(0), transfers[2].Flags)
+	assert.Equal(t, transaction.LegID(entry.ID, 2), transfers[2].ID)
+	assert.Equal(t, types.ToUint128(100), transfers[2].DebitAccountID)
+	assert.Equal(t, entry.ID, transfers[2].UserData128)
+
+	unbalanced := entry
+	unbalanced.Credits = []Leg{leg(2, 4)}
+	_, err = journal.Transfers(unbalanced)
+	assert.Equal(t, ErrUnbalanced{Debits: types.ToUint128(10), Credits: types.ToUint128(4)}, err)
+
+	unknown := entry
+	unknown.Ledger = 2
+	_, err = journal.Transfers(unknown)
+	assert.Equal(t, ErrNoSuspenseAccount{Ledger: 2}, err)
+
+	for _, invalid := range []Entry{
+		{ID: types.ToUint128(1), Ledger: 1, Debits: []Leg{leg(1, 10)}},
+		{ID: types.ToUint128(1), Ledger: 1, Debits: []Leg{leg(1, 0)}, Credits: []Leg{leg(2, 0)}},
+		{ID: types.ToUint128(1), Ledger: 1, Debits: []Leg{leg(100, 1)}, Credits: []Leg{leg(2, 1)}},
+		{Ledger: 1, Debits: []Leg{leg(1, 1)}, Credits: []Leg{leg(2, 1)}},
+	} {
+		_, err := journal.Transfers(invalid)
+		_, ok := err.(ErrInvalidEntry)
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,420 @@
+// Package netting settles the net positions of counterparties over a time window.
+//
+// The transfers between the participants' accounts within the window are netted into a position
+// per participant: the amount credited to its account by the other participants, less the
+// amount debited. As every such transfer credits one participant and debits another, the
+// positions of a ledger add up to zero, and settling them takes one transfer per participant
+// with a nonzero position, through the settlement account of its ledger: a participant with a
+// positive position is debited by it, and a participant with a negative position is credited by
+// it, so that afterwards every position is zero, as does the settlement account's balance.
+//
+// Settlement transfers have IDs derived from the window, the settlement account, the code, and
+// the participant (see SettlementID), so settling the same window again is idempotent, Reconcile
+// can find them, and nettings with other settlement accounts or codes do not collide.
+package netting
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to net and settle positions.
+type Client interface {
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+	LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error)
+	GetAccountTransfers(filter types.AccountFilter) ([]types.Transfer, error)
+}
+
+// batchMax is the maximum number of events per request, and of results per query.
+const batchMax = 8190
+
+// Window is the range of transfers netted, from From (inclusive) to To (exclusive).
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// WindowID returns an ID derived from the window, and the settlement account and code of its
+// settlement transfers, which is their `UserData128`.
+func WindowID(window Window, settlementAccountID types.Uint128, code uint16) types.Uint128 {
+	name := fmt.Sprintf("netting/%s/%d/%d/%d", settlementAccountID.DecimalString(), code,
+		window.From.UnixNano(), window.To.UnixNano())
+	return types.UUIDv8(types.UUIDNamespaceURL, []byte(name))
+}
+
+// SettlementID returns the ID of the transfer that settles an account's position for a window,
+// through the settlement account with the code.
+func SettlementID(
+	window Window,
+	settlementAccountID types.Uint128,
+	code uint16,
+	accountID types.Uint128,
+) types.Uint128 {
+	id := accountID.BigEndianBytes()
+	return types.UUIDv8(WindowID(window, settlementAccountID, code), id[:])
+}
+
+// Position is the net position of a participant over a window.
+type Position struct {
+	AccountID types.Uint128
+	// The ledger of the account, or zero if it has no transfers within the window.
+	Ledger uint32
+	// The amount credited to the account by the other participants, less the amount debited.
+	Net types.Net
+}
+
+type ErrInvalidWindow struct{}
+
+func (e ErrInvalidWindow) Error() string { return "Window must start before it ends." }
+
+type ErrInvalidParticipants struct {
+	AccountID types.Uint128
+	Reason    string
+}
+
+func (e ErrInvalidParticipants) Error() string {
+	return fmt.Sprintf("Invalid participant %s: %s.", e.AccountID.DecimalString(), e.Reason)
+}
+
+type ErrNoSettlementAccount struct {
+	Ledger uint32
+}
+
+func (e ErrNoSettlementAccount) Error() string {
+	return fmt.Sprintf("No settlement account for ledger %d.", e.Ledger)
+}
+
+// ErrTransferFailed is returned when the cluster rejects a settlement transfer.
+type ErrTransferFailed struct {
+	AccountID types.Uint128
+	Result    types.CreateTransferResult
+}
+
+func (e ErrTransferFailed) Error() string {
+	return fmt.Sprintf("Settlement of account %s failed: %s.", e.AccountID.DecimalString(), e.Result)
+}
+
+// ErrConflict is returned when settling a window again would settle a position differently from
+// its existing settlement transfer, as the window's transfers changed since it was settled.
+type ErrConflict struct {
+	AccountID types.Uint128
+	// The fields of the settlement transfer that differ, or none if it does not exist.
+	Fields []types.FieldDiff
+}
+
+func (e ErrConflict) Error() string {
+	return fmt.Sprintf("Settlement of account %s differs from its earlier settlement.",
+		e.AccountID.DecimalString())
+}
+
+// ErrUnreconciled is returned by Reconcile with the positions that are not zero after settlement.
+type ErrUnreconciled struct {
+	Positions []Position
+}
+
+func (e ErrUnreconciled) Error() string {
+	return fmt.Sprintf("%d positions are not settled.", len(e.Positions))
+}
+
+// Netting nets and settles the positions of participants through the settlement account of each
+// ledger.
+type Netting struct {
+	client     Client
+	settlement map[uint32]types.Uint128
+	code       uint16
+}
+
+// New returns a Netting that settles positions through the settlement accounts, keyed by ledger,
+// with transfers of the given code.
+func New(client Client, settlementAccounts map[uint32]types.Uint128, code uint16) *Netting {
+	accounts := make(map[uint32]types.Uint128, len(settlementAccounts))
+	for ledger, id := range settlementAccounts {
+		accounts[ledger] = id
+	}
+	return &Netting{client: client, settlement: accounts, code: code}
+}
+
+// flows accumulates the amounts debited from and credited to an account.
+type flows struct {
+	ledger  uint32
+	debits  types.Uint128
+	credits types.Uint128
+}
+
+func (f *flows) add(transfer types.Transfer, accountID types.Uint128) error {
+	f.ledger = transfer.Ledger
+	var err error
+	if transfer.DebitAccountID == accountID {
+		f.debits, err = f.debits.AddChecked(transfer.Amount)
+	} else {
+		f.credits, err = f.credits.AddChecked(transfer.Amount)
+	}
+	return err
+}
+
+func (f *flows) position(accountID types.Uint128) Position {
+	balance := types.AccountBalance{DebitsPosted: f.debits, CreditsPosted: f.credits}
+	return Position{AccountID: accountID, Ledger: f.ledger, Net: balance.NetPosted(0)}
+}
+
+// Positions returns the position of each participant over the window, in order. Only posted
+// transfers between two participants count: pending transfers are counted once posted, by the
+// transfer that posts them.
+func (n *Netting) Positions(participants []types.Uint128, window Window) ([]Position, error) {
+	all, err := n.flows(participants, window)
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]Position, len(participants))
+	for i, id := range participants {
+		positions[i] = all[id].position(id)
+	}
+	return positions, nil
+}
+
+func (n *Netting) flows(participants []types.Uint128, window Window) (map[types.Uint128]*flows, error) {
+	if window.From.UnixNano() <= 0 || !window.From.Before(window.To) {
+		return nil, ErrInvalidWindow{}
+	}
+	settlement := make(map[types.Uint128]struct{}, len(n.settlement))
+	for _, id := range n.settlement {
+		settlement[id] = struct{}{}
+	}
+	all := make(map[types.Uint128]*flows, len(participants))
+	for _, id := range participants {
+		if _, ok := all[id]; ok {
+			return nil, ErrInvalidParticipants{AccountID: id, Reason: "it is listed more than once"}
+		}
+		if _, ok := settlement[id]; ok {
+			return nil, ErrInvalidParticipants{AccountID: id, Reason: "it is a settlement account"}
+		}
+		all[id] = &flows{}
+	}
+
+	// Each transfer between participants is found once, from its debit account.
+	for _, id := range participants {
+		filter := types.AccountFilter{
+			AccountID:    id,
+			TimestampMin: uint64(window.From.UnixNano()),
+			TimestampMax: uint64(window.To.UnixNano()) - 1,
+			Limit:        batchMax,
+			Flags:        types.AccountFilterFlagDebits.ToUint32(),
+		}
+		for {
+			transfers, err := n.client.GetAccountTransfers(filter)
+			if err != nil {
+				return nil, err
+			}
+			for _, transfer := range transfers {
+				flags := types.TransferFlag(transfer.Flags)
+				if flags.Has(types.TransferFlagPending) || flags.Has(types.TransferFlagVoidPendingTransfer) {
+					continue
+				}
+				credit, ok := all[transfer.CreditAccountID]
+				if !ok {
+					continue
+				}
+				if err := all[id].add(transfer, id); err != nil {
+					return nil, err
+				}
+				if err := credit.add(transfer, transfer.CreditAccountID); err != nil {
+					return nil, err
+				}
+			}
+			if len(transfers) < batchMax {
+				break
+			}
+			filter.TimestampMin = transfers[len(transfers)-1].Timestamp + 1
+		}
+	}
+	return all, nil
+}
+
+// Plan returns the transfers that settle the positions, one per nonzero position, without
+// creating them.
+func (n *Netting) Plan(positions []Position, window Window) ([]types.Transfer, error) {
+	var transfers []types.Transfer
+	for _, position := range positions {
+		if position.Net.Sign() == 0 {
+			continue
+		}
+		settlement, ok := n.settlement[position.Ledger]
+		if !ok {
+			return nil, ErrNoSettlementAccount{Ledger: position.Ledger}
+		}
+		transfer := types.Transfer{
+			ID:              SettlementID(window, settlement, n.code, position.AccountID),
+			DebitAccountID:  position.AccountID,
+			CreditAccountID: settlement,
+			Amount:          position.Net.Magnitude,
+			UserData128:     WindowID(window, settlement, n.code),
+			Ledger:          position.Ledger,
+			Code:            n.code,
+		}
+		if position.Net.Negative {
+			transfer.DebitAccountID, transfer.CreditAccountID = settlement, position.AccountID
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+// Settlement is the outcome of Settle.
+type Settlement struct {
+	Positions []Position
+	Transfers []types.Transfer
+}
+
+// Settle nets the positions of the participants over the window, and creates the transfers that
+// settle them, unless `dryRun` is set. Each request of up to 8190 transfers is atomic. Settling a
+// window again is idempotent, but its positions must not have changed, so settle a window only
+// once it has ended. A settlement transfer that already exists is compared with the one planned,
+// and returns ErrConflict if they differ; this only checks the transfers planned, so use
+// Reconcile to check that every position is settled.
+func (n *Netting) Settle(participants []types.Uint128, window Window, dryRun bool) (Settlement, error) {
+	positions, err := n.Positions(participants, window)
+	if err != nil {
+		return Settlement{}, err
+	}
+	transfers, err := n.Plan(positions, window)
+	if err != nil {
+		return Settlement{}, err
+	}
+	settlement := Settlement{Positions: positions, Transfers: transfers}
+	if dryRun {
+		return settlement, nil
+	}
+
+	for start := 0; start < len(transfers); start += batchMax {
+		end := start + batchMax
+		if end > len(transfers) {
+			end = len(transfers)
+		}
+		batch := make([]types.Transfer, end-start)
+		copy(batch, transfers[start:end])
+		for i := range batch[:len(batch)-1] {
+			batch[i].Flags = types.TransferFlagLinked.ToUint16()
+		}
+		results, err := n.client.CreateTransfers(batch)
+		if err != nil {
+			return Settlement{}, err
+		}
+		if exists(results) {
+			if err := n.verify(batch); err != nil {
+				return Settlement{}, err
+			}
+			continue
+		}
+		for _, result := range results {
+			if result.Result == types.TransferLinkedEventFailed {
+				continue
+			}
+			return Settlement{}, ErrTransferFailed{
+				AccountID: n.participant(batch[result.Index]),
+				Result:    result.Result,
+			}
+		}
+	}
+	return settlement, nil
+}
+
+// exists returns whether a settlement transfer already exists. If the batch was settled already,
+// the transfers that exist fail the chain, and the others fail with `TransferLinkedEventFailed`,
+// whether or not they match.
+func exists(results []types.TransferEventResult) bool {
+	for _, result := range results {
+		if result.Result == types.TransferExists || result.Result.ExistsWithDifferent() {
+			return true
+		}
+	}
+	return false
+}
+
+// verify compares the settlement transfers with those that exist, returning ErrConflict for the
+// first one that differs or does not exist.
+func (n *Netting) verify(transfers []types.Transfer) error {
+	ids := make([]types.Uint128, len(transfers))
+	for i, transfer := range transfers {
+		ids[i] = transfer.ID
+	}
+	found, err := n.client.LookupTransfers(ids)
+	if err != nil {
+		return err
+	}
+	stored := make(map[types.Uint128]types.Transfer, len(found))
+	for _, transfer := range found {
+		stored[transfer.ID] = transfer
+	}
+	for _, transfer := range transfers {
+		existing, ok := stored[transfer.ID]
+		if !ok {
+			return ErrConflict{AccountID: n.participant(transfer)}
+		}
+		if fields := types.DiffTransfers(transfer, existing); len(fields) > 0 {
+			return ErrConflict{AccountID: n.participant(transfer), Fields: fields}
+		}
+	}
+	return nil
+}
+
+// participant returns the account whose position a settlement transfer settles.
+func (n *Netting) participant(transfer types.Transfer) types.Uint128 {
+	if n.settlement[transfer.Ledger] == transfer.DebitAccountID {
+		return transfer.CreditAccountID
+	}
+	return transfer.DebitAccountID
+}
+
+// Reconcile checks that the position of every participant over the window, together with its
+// settlement transfer, is zero. It returns ErrUnreconciled with the positions that are not.
+func (n *Netting) Reconcile(participants []types.Uint128, window Window) error {
+	all, err := n.flows(participants, window)
+	if err != nil {
+		return err
+	}
+	// Only participants with transfers within the window, and so a ledger, can have been settled.
+	settlementIDs := make(map[types.Uint128]types.Uint128, len(participants))
+	var settled []types.Uint128
+	for _, id := range participants {
+		if settlement, ok := n.settlement[all[id].ledger]; ok {
+			settlementIDs[id] = SettlementID(window, settlement, n.code, id)
+			settled = append(settled, id)
+		}
+	}
+	for start := 0; start < len(settled); start += batchMax {
+		end := start + batchMax
+		if end > len(settled) {
+			end = len(settled)
+		}
+		ids := make([]types.Uint128, 0, end-start)
+		for _, id := range settled[start:end] {
+			ids = append(ids, settlementIDs[id])
+		}
+		found, err := n.client.LookupTransfers(ids)
+		if err != nil {
+			return err
+		}
+		for _, transfer := range found {
+			for _, id := range []types.Uint128{transfer.DebitAccountID, transfer.CreditAccountID} {
+				if f, ok := all[id]; ok && transfer.ID == settlementIDs[id] {
+					if err := f.add(transfer, id); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	var unreconciled []Position
+	for _, id := range participants {
+		if position := all[id].position(id); position.Net.Sign() != 0 {
+			unreconciled = append(unreconciled, position)
+		}
+	}
+	if len(unreconciled) > 0 {
+		return ErrUnreconciled{Positions: unreconciled}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package netting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+var (
+	a          = types.ToUint128(1)
+	b          = types.ToUint128(2)
+	c          = types.ToUint128(3)
+	outsider   = types.ToUint128(4)
+	settlement = types.ToUint128(100)
+)
+
+func setup(t *testing.T) (*fake.Cluster, Window) {
+	start := time.Unix(1_700_000_000, 0)
+	cluster := fake.New(start)
+	accountResults, err := cluster.CreateAccounts([]types.Account{
+		{ID: a, Ledger: 1, Code: 1},
+		{ID: b, Ledger: 1, Code: 1},
+		{ID: c, Ledger: 1, Code: 1},
+		{ID: outsider, Ledger: 1, Code: 1},
+		{ID: settlement, Ledger: 1, Code: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, accountResults)
+
+	transfer := func(id uint64, debit types.Uint128, credit types.Uint128, amount uint64, flags types.TransferFlag) types.Transfer {
+		return types.Transfer{
+			ID:              types.ToUint128(id),
+			DebitAccountID:  debit,
+			CreditAccountID: credit,
+			Amount:          types.ToUint128(amount),
+			Ledger:          1,
+			Code:            1,
+			Flags:           flags.ToUint16(),
+		}
+	}
+	results, err := cluster.CreateTransfers([]types.Transfer{
+		transfer(10, a, b, 100, 0),
+		transfer(11, b, c, 30, 0),
+		transfer(12, c, a, 50, 0),
+		transfer(13, a, c, 20, 0),
+		// Transfers with other accounts, and pending transfers, do not count.
+		transfer(14, a, outsider, 1000, 0),
+		transfer(15, a, b, 7, types.TransferFlagPending),
+		transfer(16, b, a, 9, types.TransferFlagPending),
+		// Until they are posted.
+		{ID: types.ToUint128(17), PendingID: types.ToUint128(16), Flags: types.TransferFlagPostPendingTransfer.ToUint16()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+	cluster.Advance(time.Second)
+	return cluster, Window{From: start, To: cluster.Now()}
+}
+
+func Test_Settle(t *testing.T) {
+	cluster, window := setup(t)
+	netting := New(cluster, map[uint32]types.Uint128{1: settlement}, 9)
+	participants := []types.Uint128{a, b, c}
+
+	positions, err := netting.Positions(participants, window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Position{
+		{AccountID: a, Ledger: 1, Net: types.Net{Negative: true, Magnitude: types.ToUint128(61)}},
+		{AccountID: b, Ledger: 1, Net: types.Net{Magnitude: types.ToUint128(61)}},
+		{AccountID: c, Ledger: 1, Net: types.Net{}},
+	}, positions)
+
+	// A dry run creates nothing.
+	dry, err := netting.Settle(participants, window, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, dry.Transfers, 2)
+	assert.Equal(t, settlement, dry.Transfers[0].DebitAccountID)
+	assert.Equal(t, a, dry.Transfers[0].CreditAccountID)
+	assert.Equal(t, b, dry.Transfers[1].DebitAccountID)
+	assert.Equal(t, settlement, dry.Transfers[1].CreditAccountID)
+	assert.Equal(t, SettlementID(window, settlement, 9, b), dry.Transfers[1].ID)
+	assert.Equal(t, WindowID(window, settlement, 9), dry.Transfers[1].UserData128)
+	// Another netting of the window, with another code, has other IDs.
+	other, err := New(cluster, map[uint32]types.Uint128{1: settlement}, 10).Settle(participants, window, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, dry.Transfers[1].ID, other.Transfers[1].ID)
+	assert.NotEqual(t, dry.Transfers[1].UserData128, other.Transfers[1].UserData128)
+	err = netting.Reconcile(participants, window)
+	unreconciled, ok := err.(ErrUnreconciled)
+	assert.True(t, ok)
+	assert.Len(t, unreconciled.Positions, 2)
+
+	settled, err := netting.Settle(participants, window, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, dry, settled)
+	assert.Equal(t, nil, netting.Reconcile(participants, window))
+
+	// Settling again is idempotent.
+	_, err = netting.Settle(participants, window, false)
+	assert.Equal(t, nil, err)
+	accounts, err := cluster.LookupAccounts([]types.Uint128{settlement})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(61), accounts[0].DebitsPosted)
+	assert.Equal(t, types.ToUint128(61), accounts[0].CreditsPosted)
+
+	// Settling the window again with other positions conflicts: without c, a's position is -91.
+	_, err = netting.Settle([]types.Uint128{a, b}, window, false)
+	assert.Equal(t, ErrConflict{AccountID: a, Fields: []types.FieldDiff{
+		{Field: "amount", Submitted: "91", Stored: "61"},
+	}}, err)
+
+	// A later window has nothing to settle.
+	later := Window{From: window.To, To: window.To.Add(time.Hour)}
+	empty, err := netting.Settle(participants, later, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, empty.Transfers)
+}
+
+func Test_Settle_Invalid(t *testing.T) {
+	cluster, window := setup(t)
+	netting := New(cluster, map[uint32]types.Uint128{1: settlement}, 9)
+
+	_, err := netting.Positions([]types.Uint128{a, b}, Window{From: window.To, To: window.From})
+	assert.Equal(t, ErrInvalidWindow{}, err)
+	_, err = netting.Positions([]types.Uint128{a, a}, window)
+	assert.Equal(t, ErrInvalidParticipants{AccountID: a, Reason: "it is listed more than once"}, err)
+	_, err = netting.Positions([]types.Uint128{a, settlement}, window)
+	assert.Equal(t, ErrInvalidParticipants{AccountID: settlement, Reason: "it is a settlement account"}, err)
+
+	_, err = New(cluster, nil, 9).Settle([]types.Uint128{a, b}, window, true)
+	assert.Equal(t, ErrNoSettlementAccount{Ledger: 1}, err)
+}
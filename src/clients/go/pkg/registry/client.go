@@ -0,0 +1,129 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client wrapped by a ValidatingClient.
+type Client interface {
+	CreateAccounts(accounts []types.Account) ([]types.AccountEventResult, error)
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+	LookupAccounts(accountIDs []types.Uint128) ([]types.Account, error)
+}
+
+// batchMax is the maximum number of events per request.
+const batchMax = 8190
+
+// EventError is the validation error of one event of a batch.
+type EventError struct {
+	Index uint32
+	ID    types.Uint128
+	Err   error
+}
+
+// ErrInvalidEvents is returned, without submitting anything, when any event of a batch is
+// invalid. Rejecting the whole batch keeps linked chains intact.
+type ErrInvalidEvents struct {
+	Events []EventError
+}
+
+func (e ErrInvalidEvents) Error() string {
+	first := e.Events[0]
+	return fmt.Sprintf("%d invalid events, the first at index %d (ID %s): %v", len(e.Events),
+		first.Index, first.ID, first.Err)
+}
+
+// ValidatingClient validates accounts and transfers against a Registry before submitting them.
+type ValidatingClient struct {
+	client   Client
+	registry *Registry
+}
+
+func NewValidatingClient(client Client, registry *Registry) *ValidatingClient {
+	return &ValidatingClient{client: client, registry: registry}
+}
+
+// CreateAccounts submits the accounts if their ledgers and codes are all registered, or else
+// returns ErrInvalidEvents.
+func (c *ValidatingClient) CreateAccounts(
+	accounts []types.Account,
+) ([]types.AccountEventResult, error) {
+	var invalid []EventError
+	for i, account := range accounts {
+		if err := c.registry.ValidateAccount(account); err != nil {
+			invalid = append(invalid, EventError{Index: uint32(i), ID: account.ID, Err: err})
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, ErrInvalidEvents{Events: invalid}
+	}
+	return c.client.CreateAccounts(accounts)
+}
+
+// CreateTransfers submits the transfers if they are valid (see Registry.ValidateTransfer), or
+// else returns ErrInvalidEvents. The debit and credit accounts are looked up for their codes.
+// Transfers whose accounts are not found are left to the cluster to reject.
+func (c *ValidatingClient) CreateTransfers(
+	transfers []types.Transfer,
+) ([]types.TransferEventResult, error) {
+	codes, err := c.accountCodes(transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	var invalid []EventError
+	for i, transfer := range transfers {
+		debit, debitFound := codes[transfer.DebitAccountID]
+		credit, creditFound := codes[transfer.CreditAccountID]
+		var err error
+		if debitFound && creditFound {
+			err = c.registry.ValidateTransfer(transfer, Pair{Debit: debit, Credit: credit})
+		} else {
+			// Still check the ledger and code, which do not depend on the accounts.
+			err = c.registry.ValidateTransfer(transfer, Pair{})
+			if _, ok := err.(ErrPairNotAllowed); ok {
+				err = nil
+			}
+		}
+		if err != nil {
+			invalid = append(invalid, EventError{Index: uint32(i), ID: transfer.ID, Err: err})
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, ErrInvalidEvents{Events: invalid}
+	}
+	return c.client.CreateTransfers(transfers)
+}
+
+// accountCodes looks up the codes of the transfers' debit and credit accounts.
+func (c *ValidatingClient) accountCodes(transfers []types.Transfer) (map[types.Uint128]uint16, error) {
+	var ids []types.Uint128
+	seen := map[types.Uint128]struct{}{}
+	for _, transfer := range transfers {
+		for _, id := range []types.Uint128{transfer.DebitAccountID, transfer.CreditAccountID} {
+			if _, ok := seen[id]; ok || id.IsZero() {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+
+	codes := make(map[types.Uint128]uint16, len(ids))
+	for start := 0; start < len(ids); start += batchMax {
+		end := start + batchMax
+		if end > len(ids) {
+			end = len(ids)
+		}
+		accounts, err := c.client.LookupAccounts(ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range accounts {
+			codes[account.ID] = account.Code
+		}
+	}
+	return codes, nil
+}
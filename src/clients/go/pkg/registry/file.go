@@ -0,0 +1,233 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/amount"
+)
+
+// file is the declarative format read by Load.
+type file struct {
+	Ledgers []struct {
+		ID          uint32 `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Asset       string `json:"asset"`
+		Scale       uint8  `json:"scale"`
+	} `json:"ledgers"`
+	AccountCodes []struct {
+		Code        uint16 `json:"code"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"account_codes"`
+	TransferCodes []struct {
+		Code        uint16 `json:"code"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Pairs       []struct {
+			Debit  string `json:"debit"`
+			Credit string `json:"credit"`
+		} `json:"pairs"`
+	} `json:"transfer_codes"`
+}
+
+// ErrInvalidFile is returned by Load when the file is not valid JSON, or has unknown fields.
+type ErrInvalidFile struct {
+	Err error
+}
+
+func (e ErrInvalidFile) Error() string {
+	return fmt.Sprintf("Invalid registry file: %v.", e.Err)
+}
+
+func (e ErrInvalidFile) Unwrap() error { return e.Err }
+
+// ErrUnknownAccountCodeName is returned by Load when a pair refers to an undeclared account code.
+type ErrUnknownAccountCodeName struct {
+	TransferCode string
+	AccountCode  string
+}
+
+func (e ErrUnknownAccountCodeName) Error() string {
+	return fmt.Sprintf("Transfer code %q refers to the unknown account code %q.",
+		e.TransferCode, e.AccountCode)
+}
+
+// ErrIdentifierCollision is returned by GenerateConstants when two names of the same kind
+// generate the same Go identifier, such as "bank-fees" and "bank_fees".
+type ErrIdentifierCollision struct {
+	Kind       string
+	Names      [2]string
+	Identifier string
+}
+
+func (e ErrIdentifierCollision) Error() string {
+	return fmt.Sprintf("The %ss %q and %q both generate %s.", e.Kind, e.Names[0], e.Names[1], e.Identifier)
+}
+
+// Load reads a registry from a declarative JSON file of ledgers, with an optional asset code and
+// scale, account codes, and transfer codes whose pairs refer to account codes by name:
+//
+//	{
+//	  "ledgers": [
+//	    {"id": 1, "name": "usd", "description": "US dollars", "asset": "USD", "scale": 2}
+//	  ],
+//	  "account_codes": [
+//	    {"code": 1, "name": "bank"},
+//	    {"code": 2, "name": "customer"}
+//	  ],
+//	  "transfer_codes": [
+//	    {"code": 1, "name": "deposit", "pairs": [{"debit": "bank", "credit": "customer"}]}
+//	  ]
+//	}
+//
+// Unknown fields are rejected.
+func Load(r io.Reader) (*Registry, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var declared file
+	if err := decoder.Decode(&declared); err != nil {
+		return nil, ErrInvalidFile{Err: err}
+	}
+
+	registry := New()
+	for _, ledger := range declared.Ledgers {
+		if err := registry.AddLedger(Ledger{
+			ID:          ledger.ID,
+			Name:        ledger.Name,
+			Description: ledger.Description,
+			Asset:       amount.Asset{Code: ledger.Asset, Scale: ledger.Scale},
+		}); err != nil {
+			return nil, err
+		}
+	}
+	accountCodes := map[string]uint16{}
+	for _, code := range declared.AccountCodes {
+		if err := registry.AddAccountCode(AccountCode{
+			Code:        code.Code,
+			Name:        code.Name,
+			Description: code.Description,
+		}); err != nil {
+			return nil, err
+		}
+		accountCodes[code.Name] = code.Code
+	}
+	for _, code := range declared.TransferCodes {
+		transferCode := TransferCode{
+			Code:        code.Code,
+			Name:        code.Name,
+			Description: code.Description,
+		}
+		for _, pair := range code.Pairs {
+			debit, ok := accountCodes[pair.Debit]
+			if !ok {
+				return nil, ErrUnknownAccountCodeName{TransferCode: code.Name, AccountCode: pair.Debit}
+			}
+			credit, ok := accountCodes[pair.Credit]
+			if !ok {
+				return nil, ErrUnknownAccountCodeName{TransferCode: code.Name, AccountCode: pair.Credit}
+			}
+			transferCode.Pairs = append(transferCode.Pairs, Pair{Debit: debit, Credit: credit})
+		}
+		if err := registry.AddTransferCode(transferCode); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// GenerateConstants writes Go source for package `packageName`, with a typed constant for each
+// ledger and code of the registry, for example:
+//
+//	const LedgerUsd uint32 = 1
+//	const AccountCodeBank uint16 = 1
+//	const TransferCodeDeposit uint16 = 1
+//
+// Call it from a small program run by `go generate`, together with Load.
+func GenerateConstants(w io.Writer, packageName string, registry *Registry) error {
+	type constant struct {
+		name        string
+		value       uint32
+		description string
+	}
+	var body bytes.Buffer
+	declared := map[string]string{}
+	group := func(kind string, prefix string, typeName string, constants []constant) error {
+		if len(constants) == 0 {
+			return nil
+		}
+		fmt.Fprintf(&body, "\n// %s.\nconst (\n", strings.ToUpper(kind[:1])+kind[1:]+"s")
+		for _, c := range constants {
+			identifier := prefix + goName(c.name)
+			if previous, ok := declared[identifier]; ok {
+				return ErrIdentifierCollision{
+					Kind:       kind,
+					Names:      [2]string{previous, c.name},
+					Identifier: identifier,
+				}
+			}
+			declared[identifier] = c.name
+
+			fmt.Fprintf(&body, "\t// %s is the %s %q.", identifier, kind, c.name)
+			if c.description != "" {
+				fmt.Fprintf(&body, " %s", commentText(c.description))
+			}
+			fmt.Fprintf(&body, "\n\t%s %s = %d\n", identifier, typeName, c.value)
+		}
+		fmt.Fprintf(&body, ")\n")
+		return nil
+	}
+
+	var ledgers, accountCodes, transferCodes []constant
+	for _, ledger := range registry.Ledgers() {
+		ledgers = append(ledgers, constant{ledger.Name, ledger.ID, ledger.Description})
+	}
+	for _, code := range registry.AccountCodes() {
+		accountCodes = append(accountCodes, constant{code.Name, uint32(code.Code), code.Description})
+	}
+	for _, code := range registry.TransferCodes() {
+		transferCodes = append(transferCodes, constant{code.Name, uint32(code.Code), code.Description})
+	}
+	if err := group("ledger", "Ledger", "uint32", ledgers); err != nil {
+		return err
+	}
+	if err := group("account code", "AccountCode", "uint16", accountCodes); err != nil {
+		return err
+	}
+	if err := group("transfer code", "TransferCode", "uint16", transferCodes); err != nil {
+		return err
+	}
+
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "// Code generated by registry.GenerateConstants. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n", packageName)
+	source.Write(body.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// commentText returns the description on a single line, so that it cannot end the comment that
+// it is written in, and inject source into the generated file.
+func commentText(description string) string {
+	text := strings.Join(strings.Fields(description), " ")
+	return strings.ReplaceAll(text, "*/", "* /")
+}
+
+// goName converts a name such as "bank_fees" or "bank-fees" to "BankFees".
+func goName(name string) string {
+	var result strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }) {
+		result.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return result.String()
+}
@@ -0,0 +1,328 @@
+// Package registry declares the ledgers, account codes, and transfer codes of an application,
+// so that they are named in code and validated before reaching the cluster.
+//
+// Each transfer code declares the pairs of debit and credit account codes that it may move
+// amounts between, for example that a "deposit" debits a "bank" account and credits a "customer"
+// account. A ValidatingClient rejects accounts and transfers that use an undeclared ledger or
+// code, and transfers between accounts whose codes are not an allowed pair.
+//
+// Registries can be loaded from a declarative JSON file (see Load), from which GenerateConstants
+// writes Go constants for each name.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/amount"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+type Ledger struct {
+	ID          uint32
+	Name        string
+	Description string
+	// Asset is optional, see Registry.Assets.
+	Asset amount.Asset
+}
+
+type AccountCode struct {
+	Code        uint16
+	Name        string
+	Description string
+}
+
+type TransferCode struct {
+	Code        uint16
+	Name        string
+	Description string
+	// Pairs are the allowed debit and credit account codes. A transfer code without pairs may
+	// move amounts between accounts of any registered codes.
+	Pairs []Pair
+}
+
+// Pair is an allowed combination of the debit account's code and the credit account's code.
+type Pair struct {
+	Debit  uint16
+	Credit uint16
+}
+
+// ErrZero is returned when registering a ledger or code of zero, which the cluster rejects.
+type ErrZero struct {
+	Kind string
+}
+
+func (e ErrZero) Error() string {
+	return fmt.Sprintf("The %s must not be zero.", e.Kind)
+}
+
+// ErrInvalidName is returned for names that cannot be turned into Go identifiers.
+type ErrInvalidName struct {
+	Kind string
+	Name string
+}
+
+func (e ErrInvalidName) Error() string {
+	return fmt.Sprintf("Invalid %s name %q.", e.Kind, e.Name)
+}
+
+type ErrAlreadyRegistered struct {
+	Kind string
+	// The conflicting value, either the number or the name.
+	Value string
+}
+
+func (e ErrAlreadyRegistered) Error() string {
+	return fmt.Sprintf("The %s %s is already registered.", e.Kind, e.Value)
+}
+
+type ErrUnknownAccountCode struct {
+	Code uint16
+}
+
+func (e ErrUnknownAccountCode) Error() string {
+	return fmt.Sprintf("Account code %d is not registered.", e.Code)
+}
+
+type ErrUnknownTransferCode struct {
+	Code uint16
+}
+
+func (e ErrUnknownTransferCode) Error() string {
+	return fmt.Sprintf("Transfer code %d is not registered.", e.Code)
+}
+
+type ErrPairNotAllowed struct {
+	TransferCode uint16
+	Pair         Pair
+}
+
+func (e ErrPairNotAllowed) Error() string {
+	return fmt.Sprintf("Transfer code %d does not allow debiting account code %d and crediting "+
+		"account code %d.", e.TransferCode, e.Pair.Debit, e.Pair.Credit)
+}
+
+// Registry holds the declared ledgers and codes. It is safe for concurrent use.
+type Registry struct {
+	mutex         sync.RWMutex
+	ledgers       map[uint32]Ledger
+	accountCodes  map[uint16]AccountCode
+	transferCodes map[uint16]TransferCode
+	// Names must be unique within each kind, as they become identifiers.
+	names map[string]struct{}
+}
+
+func New() *Registry {
+	return &Registry{
+		ledgers:       map[uint32]Ledger{},
+		accountCodes:  map[uint16]AccountCode{},
+		transferCodes: map[uint16]TransferCode{},
+		names:         map[string]struct{}{},
+	}
+}
+
+// claimName must be called with the mutex held.
+func (r *Registry) claimName(kind string, name string) error {
+	if !validName(name) {
+		return ErrInvalidName{Kind: kind, Name: name}
+	}
+	key := kind + "/" + name
+	if _, ok := r.names[key]; ok {
+		return ErrAlreadyRegistered{Kind: kind, Value: fmt.Sprintf("%q", name)}
+	}
+	r.names[key] = struct{}{}
+	return nil
+}
+
+// validName accepts names that can be turned into Go identifiers, like "usd" or "bank_fees".
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && (i == 0 || c != '_' && c != '-' && (c < '0' || c > '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Registry) AddLedger(ledger Ledger) error {
+	if ledger.ID == 0 {
+		return ErrZero{Kind: "ledger"}
+	}
+	if ledger.Asset.Scale > amount.ScaleMax {
+		return amount.ErrInvalidScale{Scale: int(ledger.Asset.Scale)}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.ledgers[ledger.ID]; ok {
+		return ErrAlreadyRegistered{Kind: "ledger", Value: fmt.Sprint(ledger.ID)}
+	}
+	if err := r.claimName("ledger", ledger.Name); err != nil {
+		return err
+	}
+	r.ledgers[ledger.ID] = ledger
+	return nil
+}
+
+func (r *Registry) AddAccountCode(code AccountCode) error {
+	if code.Code == 0 {
+		return ErrZero{Kind: "account code"}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.accountCodes[code.Code]; ok {
+		return ErrAlreadyRegistered{Kind: "account code", Value: fmt.Sprint(code.Code)}
+	}
+	if err := r.claimName("account code", code.Name); err != nil {
+		return err
+	}
+	r.accountCodes[code.Code] = code
+	return nil
+}
+
+// AddTransferCode registers a transfer code. The account codes of its pairs must already be
+// registered.
+func (r *Registry) AddTransferCode(code TransferCode) error {
+	if code.Code == 0 {
+		return ErrZero{Kind: "transfer code"}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.transferCodes[code.Code]; ok {
+		return ErrAlreadyRegistered{Kind: "transfer code", Value: fmt.Sprint(code.Code)}
+	}
+	for _, pair := range code.Pairs {
+		for _, accountCode := range []uint16{pair.Debit, pair.Credit} {
+			if _, ok := r.accountCodes[accountCode]; !ok {
+				return ErrUnknownAccountCode{Code: accountCode}
+			}
+		}
+	}
+	if err := r.claimName("transfer code", code.Name); err != nil {
+		return err
+	}
+	code.Pairs = append([]Pair(nil), code.Pairs...)
+	r.transferCodes[code.Code] = code
+	return nil
+}
+
+func (r *Registry) Ledger(id uint32) (Ledger, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	ledger, ok := r.ledgers[id]
+	return ledger, ok
+}
+
+func (r *Registry) AccountCode(code uint16) (AccountCode, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	accountCode, ok := r.accountCodes[code]
+	return accountCode, ok
+}
+
+func (r *Registry) TransferCode(code uint16) (TransferCode, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	transferCode, ok := r.transferCodes[code]
+	return transferCode, ok
+}
+
+// Ledgers returns the registered ledgers, ordered by ID.
+func (r *Registry) Ledgers() []Ledger {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	ledgers := make([]Ledger, 0, len(r.ledgers))
+	for _, ledger := range r.ledgers {
+		ledgers = append(ledgers, ledger)
+	}
+	sort.Slice(ledgers, func(i, j int) bool { return ledgers[i].ID < ledgers[j].ID })
+	return ledgers
+}
+
+// AccountCodes returns the registered account codes, ordered by code.
+func (r *Registry) AccountCodes() []AccountCode {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	codes := make([]AccountCode, 0, len(r.accountCodes))
+	for _, code := range r.accountCodes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+// TransferCodes returns the registered transfer codes, ordered by code.
+func (r *Registry) TransferCodes() []TransferCode {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	codes := make([]TransferCode, 0, len(r.transferCodes))
+	for _, code := range r.transferCodes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+// Assets returns an amount.LedgerRegistry of the ledgers that declare an asset.
+func (r *Registry) Assets() (*amount.LedgerRegistry, error) {
+	assets := amount.NewLedgerRegistry()
+	for _, ledger := range r.Ledgers() {
+		if ledger.Asset.Code == "" {
+			continue
+		}
+		if err := assets.Register(ledger.ID, ledger.Asset); err != nil {
+			return nil, err
+		}
+	}
+	return assets, nil
+}
+
+// ValidateAccount checks that the account's ledger and code are registered.
+func (r *Registry) ValidateAccount(account types.Account) error {
+	if _, ok := r.Ledger(account.Ledger); !ok {
+		return amount.ErrUnknownLedger{Ledger: account.Ledger}
+	}
+	if _, ok := r.AccountCode(account.Code); !ok {
+		return ErrUnknownAccountCode{Code: account.Code}
+	}
+	return nil
+}
+
+// ValidateTransfer checks that the transfer's ledger and code are registered, and that the code
+// allows the pair of the debit and credit accounts' codes.
+//
+// Transfers that post or void a pending transfer inherit their ledger, code, and accounts from
+// it, so only the fields they set are checked, and their accounts are not.
+func (r *Registry) ValidateTransfer(transfer types.Transfer, pair Pair) error {
+	flags := transfer.TransferFlags()
+	inherits := flags.PostPendingTransfer || flags.VoidPendingTransfer
+	if transfer.Ledger != 0 || !inherits {
+		if _, ok := r.Ledger(transfer.Ledger); !ok {
+			return amount.ErrUnknownLedger{Ledger: transfer.Ledger}
+		}
+	}
+	if transfer.Code == 0 && inherits {
+		return nil
+	}
+	code, ok := r.TransferCode(transfer.Code)
+	if !ok {
+		return ErrUnknownTransferCode{Code: transfer.Code}
+	}
+	if inherits || len(code.Pairs) == 0 {
+		return nil
+	}
+	for _, allowed := range code.Pairs {
+		if allowed == pair {
+			return nil
+		}
+	}
+	return ErrPairNotAllowed{TransferCode: transfer.Code, Pair: pair}
+}
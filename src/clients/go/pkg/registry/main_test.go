@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/amount"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+const declaration = `{
+  "ledgers": [
+    {"id": 1, "name": "usd", "description": "US dollars.", "asset": "USD", "scale": 2},
+    {"id": 2, "name": "points"}
+  ],
+  "account_codes": [
+    {"code": 1, "name": "bank"},
+    {"code": 2, "name": "customer"},
+    {"code": 3, "name": "bank_fees"}
+  ],
+  "transfer_codes": [
+    {"code": 1, "name": "deposit", "pairs": [{"debit": "bank", "credit": "customer"}]},
+    {"code": 2, "name": "fee", "pairs": [
+      {"debit": "customer", "credit": "bank_fees"},
+      {"debit": "bank", "credit": "bank_fees"}
+    ]},
+    {"code": 3, "name": "adjustment"}
+  ]
+}`
+
+func load(t *testing.T) *Registry {
+	registry, err := Load(strings.NewReader(declaration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func Test_Registry(t *testing.T) {
+	registry := load(t)
+
+	ledger, ok := registry.Ledger(1)
+	assert.True(t, ok)
+	assert.Equal(t, "usd", ledger.Name)
+	assert.Equal(t, amount.Asset{Code: "USD", Scale: 2}, ledger.Asset)
+	code, ok := registry.TransferCode(2)
+	assert.True(t, ok)
+	assert.Equal(t, []Pair{{Debit: 2, Credit: 3}, {Debit: 1, Credit: 3}}, code.Pairs)
+	assert.Len(t, registry.AccountCodes(), 3)
+
+	assets, err := registry.Assets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := assets.Format(1, types.ToUint128(1234))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "12.34 USD", formatted)
+	_, err = assets.Asset(2)
+	assert.Equal(t, amount.ErrUnknownLedger{Ledger: 2}, err)
+
+	assert.Equal(t, ErrAlreadyRegistered{Kind: "ledger", Value: "1"},
+		registry.AddLedger(Ledger{ID: 1, Name: "eur"}))
+	assert.Equal(t, ErrAlreadyRegistered{Kind: "account code", Value: `"bank"`},
+		registry.AddAccountCode(AccountCode{Code: 9, Name: "bank"}))
+	assert.Equal(t, ErrUnknownAccountCode{Code: 9},
+		registry.AddTransferCode(TransferCode{Code: 9, Name: "x", Pairs: []Pair{{1, 9}}}))
+	assert.Equal(t, ErrInvalidName{Kind: "account code", Name: "1st"},
+		registry.AddAccountCode(AccountCode{Code: 9, Name: "1st"}))
+	assert.Equal(t, ErrZero{Kind: "account code"},
+		registry.AddAccountCode(AccountCode{Code: 0, Name: "zero"}))
+
+	_, err = Load(strings.NewReader(`{"ledgers": [{"id": 1, "name": "usd", "currency": "USD"}]}`))
+	var invalidFile ErrInvalidFile
+	if !errors.As(err, &invalidFile) {
+		t.Fatalf("Expected ErrInvalidFile, got %v", err)
+	}
+	_, err = Load(strings.NewReader(
+		`{"transfer_codes": [{"code": 1, "name": "t", "pairs": [{"debit": "a", "credit": "b"}]}]}`))
+	assert.Equal(t, ErrUnknownAccountCodeName{TransferCode: "t", AccountCode: "a"}, err)
+	_, err = Load(strings.NewReader(`{"ledgers": [{"id": 1, "name": "usd", "scale": 39}]}`))
+	assert.Equal(t, amount.ErrInvalidScale{Scale: 39}, err)
+}
+
+func Test_ValidateTransfer(t *testing.T) {
+	registry := load(t)
+
+	assert.Equal(t, nil, registry.ValidateTransfer(
+		types.Transfer{Ledger: 1, Code: 1}, Pair{Debit: 1, Credit: 2}))
+	assert.Equal(t, ErrPairNotAllowed{TransferCode: 1, Pair: Pair{Debit: 2, Credit: 1}},
+		registry.ValidateTransfer(types.Transfer{Ledger: 1, Code: 1}, Pair{Debit: 2, Credit: 1}))
+	assert.Equal(t, nil, registry.ValidateTransfer(
+		types.Transfer{Ledger: 1, Code: 3}, Pair{Debit: 2, Credit: 1}))
+	assert.Equal(t, amount.ErrUnknownLedger{Ledger: 7},
+		registry.ValidateTransfer(types.Transfer{Ledger: 7, Code: 1}, Pair{Debit: 1, Credit: 2}))
+	assert.Equal(t, ErrUnknownTransferCode{Code: 7},
+		registry.ValidateTransfer(types.Transfer{Ledger: 1, Code: 7}, Pair{Debit: 1, Credit: 2}))
+
+	// Posting and voiding inherit the fields they leave zero.
+	post := types.TransferFlags{PostPendingTransfer: true}.ToUint16()
+	assert.Equal(t, nil, registry.ValidateTransfer(types.Transfer{Flags: post}, Pair{}))
+	assert.Equal(t, ErrUnknownTransferCode{Code: 7},
+		registry.ValidateTransfer(types.Transfer{Code: 7, Flags: post}, Pair{}))
+
+	assert.Equal(t, nil, registry.ValidateAccount(types.Account{Ledger: 2, Code: 3}))
+	assert.Equal(t, ErrUnknownAccountCode{Code: 4},
+		registry.ValidateAccount(types.Account{Ledger: 2, Code: 4}))
+}
+
+func Test_ValidatingClient(t *testing.T) {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	client := NewValidatingClient(cluster, load(t))
+
+	_, err := client.CreateAccounts([]types.Account{
+		{ID: types.ToUint128(1), Ledger: 1, Code: 1},
+		{ID: types.ToUint128(2), Ledger: 1, Code: 5},
+		{ID: types.ToUint128(3), Ledger: 3, Code: 1},
+	})
+	var invalid ErrInvalidEvents
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected ErrInvalidEvents, got %v", err)
+	}
+	assert.Equal(t, []EventError{
+		{Index: 1, ID: types.ToUint128(2), Err: ErrUnknownAccountCode{Code: 5}},
+		{Index: 2, ID: types.ToUint128(3), Err: amount.ErrUnknownLedger{Ledger: 3}},
+	}, invalid.Events)
+	accounts, err := cluster.LookupAccounts([]types.Uint128{types.ToUint128(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, accounts, 0)
+
+	results, err := client.CreateAccounts([]types.Account{
+		{ID: types.ToUint128(1), Ledger: 1, Code: 1},
+		{ID: types.ToUint128(2), Ledger: 1, Code: 2},
+		{ID: types.ToUint128(3), Ledger: 1, Code: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	deposit := types.Transfer{
+		ID:              types.ToUint128(10),
+		DebitAccountID:  types.ToUint128(1),
+		CreditAccountID: types.ToUint128(2),
+		Amount:          types.ToUint128(100),
+		Ledger:          1,
+		Code:            1,
+	}
+	reversed := deposit
+	reversed.ID = types.ToUint128(11)
+	reversed.DebitAccountID, reversed.CreditAccountID = deposit.CreditAccountID, deposit.DebitAccountID
+	_, err = client.CreateTransfers([]types.Transfer{deposit, reversed})
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected ErrInvalidEvents, got %v", err)
+	}
+	assert.Len(t, invalid.Events, 1)
+	assert.Equal(t, uint32(1), invalid.Events[0].Index)
+	assert.Equal(t, ErrPairNotAllowed{TransferCode: 1, Pair: Pair{Debit: 2, Credit: 1}},
+		invalid.Events[0].Err)
+
+	// Unknown accounts are left to the cluster.
+	missing := deposit
+	missing.ID = types.ToUint128(12)
+	missing.CreditAccountID = types.ToUint128(99)
+	fee := deposit
+	fee.ID = types.ToUint128(13)
+	fee.DebitAccountID, fee.CreditAccountID, fee.Code = types.ToUint128(2), types.ToUint128(3), 2
+	transferResults, err := client.CreateTransfers([]types.Transfer{deposit, missing, fee})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, transferResults, 1)
+	assert.Equal(t, uint32(1), transferResults[0].Index)
+	assert.Equal(t, types.TransferCreditAccountNotFound, transferResults[0].Result)
+}
+
+func Test_GenerateConstants(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := GenerateConstants(&buffer, "accounting", load(t)); err != nil {
+		t.Fatal(err)
+	}
+	source := buffer.String()
+	for _, expected := range []string{
+		"// Code generated by registry.GenerateConstants. DO NOT EDIT.\n\npackage accounting\n",
+		"// Ledgers.\nconst (\n\t// LedgerUsd is the ledger \"usd\". US dollars.\n\tLedgerUsd uint32 = 1\n",
+		"\tAccountCodeBankFees uint16 = 3\n",
+		"// Transfer codes.\nconst (\n",
+		"\tTransferCodeAdjustment uint16 = 3\n",
+	} {
+		if !strings.Contains(source, expected) {
+			t.Fatalf("Expected generated source to contain %q, got:\n%s", expected, source)
+		}
+	}
+
+	// A description cannot inject source.
+	injected := New()
+	description := "Fees.\nconst Injected = 1 /* */"
+	if err := injected.AddAccountCode(AccountCode{Code: 1, Name: "fees", Description: description}); err != nil {
+		t.Fatal(err)
+	}
+	buffer.Reset()
+	if err := GenerateConstants(&buffer, "accounting", injected); err != nil {
+		t.Fatal(err)
+	}
+	expected := "\t// AccountCodeFees is the account code \"fees\". Fees. const Injected = 1 /* * /\n"
+	if !strings.Contains(buffer.String(), expected) {
+		t.Fatalf("Expected generated source to contain %q, got:\n%s", expected, buffer.String())
+	}
+
+	registry := New()
+	if err := registry.AddAccountCode(AccountCode{Code: 1, Name: "bank_fees"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.AddAccountCode(AccountCode{Code: 2, Name: "bank-fees"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrIdentifierCollision{
+		Kind:       "account code",
+		Names:      [2]string{"bank_fees", "bank-fees"},
+		Identifier: "AccountCodeBankFees",
+	}, GenerateConstants(&buffer, "accounting", registry))
+}
@@ -0,0 +1,236 @@
+// Package reversal undoes posted transfers with compensating transfers.
+//
+// Transfers are immutable, so a posted transfer is undone by a reversal: a transfer of the same
+// ledger that moves (part of) the amount back, from the original credit account to the original
+// debit account. By convention, a reversal's `UserData128` is the ID of the transfer it reverses.
+//
+// The IDs of the reversals of a transfer are derived from its ID (see ID), so the reversals that
+// already exist can be looked up, and a transfer can never be reversed by more than its amount,
+// even by concurrent or retried calls: two reversals racing for the same ID are deduplicated by
+// the cluster.
+//
+// Pending transfers cannot be reversed, as nothing has been posted yet: void them instead, or
+// reverse the transfer that posted them.
+//
+// Reverse is a function of this package, taking the client, rather than a method of the client:
+//
+//	reversal, err := reversal.Reverse(client, transferID, reversal.Options{})
+//
+// It only needs the narrow Client interface below, so it works with any implementation of it,
+// and it takes no context, as none of the client's requests do.
+package reversal
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to reverse transfers.
+type Client interface {
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+	LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error)
+}
+
+// lookupMax is the number of reversal IDs looked up per request.
+const lookupMax = 32
+
+// Options adjust the reversal created by Reverse. The zero value reverses the whole remaining
+// amount, with the original code and user data.
+type Options struct {
+	// The amount to reverse, at most the amount not reversed yet. Zero reverses all of it.
+	Amount types.Uint128
+	// The code of the reversal, e.g. a dedicated "refund" code. Zero keeps the original code.
+	Code uint16
+	// The reversal's `UserData64` and `UserData32`. Zero keeps the original's.
+	UserData64 uint64
+	UserData32 uint32
+}
+
+// ErrNotFound is returned when the transfer to reverse does not exist.
+type ErrNotFound struct {
+	TransferID types.Uint128
+}
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("Transfer %s not found.", e.TransferID.DecimalString())
+}
+
+// ErrNotPosted is returned for pending and voiding transfers, which did not post an amount.
+type ErrNotPosted struct {
+	TransferID types.Uint128
+	Flags      types.TransferFlag
+}
+
+func (e ErrNotPosted) Error() string {
+	return fmt.Sprintf("Transfer %s (%s) did not post an amount, and cannot be reversed.",
+		e.TransferID.DecimalString(), e.Flags)
+}
+
+// ErrAlreadyReversed is returned when the whole amount of a transfer has been reversed.
+type ErrAlreadyReversed struct {
+	TransferID types.Uint128
+	// The existing reversals, in order.
+	Reversals []types.Transfer
+}
+
+func (e ErrAlreadyReversed) Error() string {
+	return fmt.Sprintf("Transfer %s is already reversed.", e.TransferID.DecimalString())
+}
+
+// ErrExceedsRemaining is returned when a partial reversal is larger than the amount that the
+// earlier reversals left.
+type ErrExceedsRemaining struct {
+	TransferID types.Uint128
+	Amount     types.Uint128
+	Remaining  types.Uint128
+}
+
+func (e ErrExceedsRemaining) Error() string {
+	return fmt.Sprintf("Reversing %s of transfer %s exceeds the %s not reversed yet.",
+		e.Amount.DecimalString(), e.TransferID.DecimalString(), e.Remaining.DecimalString())
+}
+
+// ErrReversalsExceedAmount is returned when the existing reversals of a transfer add up to more
+// than its amount, which means that transfers not created by Reverse use the reversal IDs.
+type ErrReversalsExceedAmount struct {
+	TransferID types.Uint128
+}
+
+func (e ErrReversalsExceedAmount) Error() string {
+	return fmt.Sprintf("The reversals of transfer %s exceed its amount.", e.TransferID.DecimalString())
+}
+
+// ErrConflict is returned when another reversal of the same transfer was created concurrently.
+// Reverse may be retried, with the remaining amount.
+type ErrConflict struct {
+	TransferID types.Uint128
+	ReversalID types.Uint128
+}
+
+func (e ErrConflict) Error() string {
+	return fmt.Sprintf("Reversal %s of transfer %s was created concurrently.",
+		e.ReversalID.DecimalString(), e.TransferID.DecimalString())
+}
+
+// ErrTransferFailed is returned when the cluster rejects the reversal, for example because it
+// would exceed a limit of one of the accounts.
+type ErrTransferFailed struct {
+	TransferID types.Uint128
+	Result     types.CreateTransferResult
+}
+
+func (e ErrTransferFailed) Error() string {
+	return fmt.Sprintf("Reversal of transfer %s failed: %s.", e.TransferID.DecimalString(), e.Result)
+}
+
+// ID returns the ID of the n-th reversal (from zero) of a transfer.
+func ID(transferID types.Uint128, n uint32) types.Uint128 {
+	return types.UUIDv8(transferID, []byte("reversal/"+strconv.FormatUint(uint64(n), 10)))
+}
+
+// Reversals returns the existing reversals of a transfer, in order.
+func Reversals(client Client, transferID types.Uint128) ([]types.Transfer, error) {
+	var reversals []types.Transfer
+	for {
+		ids := make([]types.Uint128, lookupMax)
+		for i := range ids {
+			ids[i] = ID(transferID, uint32(len(reversals)+i))
+		}
+		found, err := client.LookupTransfers(ids)
+		if err != nil {
+			return nil, err
+		}
+		// Reversals are created in order, so the first missing ID ends the sequence.
+		for i, transfer := range found {
+			if transfer.ID != ids[i] {
+				return reversals, nil
+			}
+			reversals = append(reversals, transfer)
+		}
+		if len(found) < lookupMax {
+			return reversals, nil
+		}
+	}
+}
+
+// Reverse creates a reversal of a posted transfer, and returns it (without its timestamp).
+//
+// Retrying a full reversal is safe: once the whole amount is reversed, Reverse returns
+// ErrAlreadyReversed. A retried partial reversal reverses a further part, up to the original
+// amount, so check Reversals before retrying a partial reversal whose outcome is unknown.
+func Reverse(client Client, transferID types.Uint128, options Options) (types.Transfer, error) {
+	found, err := client.LookupTransfers([]types.Uint128{transferID})
+	if err != nil {
+		return types.Transfer{}, err
+	}
+	if len(found) == 0 {
+		return types.Transfer{}, ErrNotFound{TransferID: transferID}
+	}
+	original := found[0]
+	flags := types.TransferFlag(original.Flags)
+	if flags.Has(types.TransferFlagPending) || flags.Has(types.TransferFlagVoidPendingTransfer) {
+		return types.Transfer{}, ErrNotPosted{TransferID: transferID, Flags: flags}
+	}
+
+	reversals, err := Reversals(client, transferID)
+	if err != nil {
+		return types.Transfer{}, err
+	}
+	remaining := original.Amount
+	for _, reversal := range reversals {
+		remaining, err = remaining.SubChecked(reversal.Amount)
+		if err != nil {
+			return types.Transfer{}, ErrReversalsExceedAmount{TransferID: transferID}
+		}
+	}
+	if remaining.IsZero() {
+		return types.Transfer{}, ErrAlreadyReversed{TransferID: transferID, Reversals: reversals}
+	}
+	amount := options.Amount
+	if amount.IsZero() {
+		amount = remaining
+	}
+	if amount.Cmp(remaining) > 0 {
+		return types.Transfer{}, ErrExceedsRemaining{
+			TransferID: transferID,
+			Amount:     amount,
+			Remaining:  remaining,
+		}
+	}
+
+	reversal := types.Transfer{
+		ID:              ID(transferID, uint32(len(reversals))),
+		DebitAccountID:  original.CreditAccountID,
+		CreditAccountID: original.DebitAccountID,
+		Amount:          amount,
+		UserData128:     transferID,
+		UserData64:      original.UserData64,
+		UserData32:      original.UserData32,
+		Ledger:          original.Ledger,
+		Code:            original.Code,
+	}
+	if options.Code != 0 {
+		reversal.Code = options.Code
+	}
+	if options.UserData64 != 0 {
+		reversal.UserData64 = options.UserData64
+	}
+	if options.UserData32 != 0 {
+		reversal.UserData32 = options.UserData32
+	}
+
+	results, err := client.CreateTransfers([]types.Transfer{reversal})
+	if err != nil {
+		return types.Transfer{}, err
+	}
+	if len(results) > 0 {
+		result := results[0].Result
+		if result == types.TransferExists || result.ExistsWithDifferent() {
+			return types.Transfer{}, ErrConflict{TransferID: transferID, ReversalID: reversal.ID}
+		}
+		return types.Transfer{}, ErrTransferFailed{TransferID: transferID, Result: result}
+	}
+	return reversal, nil
+}
@@ -0,0 +1,216 @@
+package reversal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func Test_Reverse(t *testing.T) {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	customer := types.Account{ID: types.ToUint128(1), Ledger: 1, Code: 1}
+	merchant := types.Account{ID: types.ToUint128(2), Ledger: 1, Code: 1}
+	accountResults, err := cluster.CreateAccounts([]types.Account{customer, merchant})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, accountResults)
+
+	payment := types.Transfer{
+		ID:              types.ToUint128(10),
+		DebitAccountID:  customer.ID,
+		CreditAccountID: merchant.ID,
+		Amount:          types.ToUint128(100),
+		UserData64:      7,
+		Ledger:          1,
+		Code:            1,
+	}
+	hold := types.Transfer{
+		ID:              types.ToUint128(11),
+		DebitAccountID:  customer.ID,
+		CreditAccountID: merchant.ID,
+		Amount:          types.ToUint128(5),
+		Ledger:          1,
+		Code:            1,
+		Flags:           types.TransferFlagPending.ToUint16(),
+	}
+	results, err := cluster.CreateTransfers([]types.Transfer{payment, hold})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	_, err = Reverse(cluster, types.ToUint128(99), Options{})
+	assert.Equal(t, ErrNotFound{TransferID: types.ToUint128(99)}, err)
+	_, err = Reverse(cluster, hold.ID, Options{})
+	assert.Equal(t, ErrNotPosted{TransferID: hold.ID, Flags: types.TransferFlagPending}, err)
+
+	// A partial reversal, with a dedicated code.
+	partial, err := Reverse(cluster, payment.ID, Options{Amount: types.ToUint128(30), Code: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ID(payment.ID, 0), partial.ID)
+	assert.Equal(t, merchant.ID, partial.DebitAccountID)
+	assert.Equal(t, customer.ID, partial.CreditAccountID)
+	assert.Equal(t, payment.ID, partial.UserData128)
+	assert.Equal(t, uint64(7), partial.UserData64)
+	assert.Equal(t, uint16(2), partial.Code)
+
+	_, err = Reverse(cluster, payment.ID, Options{Amount: types.ToUint128(71)})
+	assert.Equal(t, ErrExceedsRemaining{
+		TransferID: payment.ID,
+		Amount:     types.ToUint128(71),
+		Remaining:  types.ToUint128(70),
+	}, err)
+
+	// The rest.
+	rest, err := Reverse(cluster, payment.ID, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ID(payment.ID, 1), rest.ID)
+	assert.Equal(t, types.ToUint128(70), rest.Amount)
+
+	reversals, err := Reversals(cluster, payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, reversals, 2)
+
+	_, err = Reverse(cluster, payment.ID, Options{})
+	already, ok := err.(ErrAlreadyReversed)
+	assert.True(t, ok)
+	assert.Len(t, already.Reversals, 2)
+
+	accounts, err := cluster.LookupAccounts([]types.Uint128{customer.ID, merchant.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(100), accounts[0].CreditsPosted)
+	assert.Equal(t, types.ToUint128(100), accounts[1].DebitsPosted)
+
+	// A reversal that the cluster rejects.
+	limited := types.Account{
+		ID:     types.ToUint128(3),
+		Ledger: 1,
+		Code:   1,
+		Flags:  types.AccountFlagDebitsMustNotExceedCredits.ToUint16(),
+	}
+	accountResults, err = cluster.CreateAccounts([]types.Account{limited})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, accountResults)
+	toLimited := types.Transfer{
+		ID:              types.ToUint128(12),
+		DebitAccountID:  customer.ID,
+		CreditAccountID: limited.ID,
+		Amount:          types.ToUint128(10),
+		Ledger:          1,
+		Code:            1,
+	}
+	spent := types.Transfer{
+		ID:              types.ToUint128(13),
+		DebitAccountID:  limited.ID,
+		CreditAccountID: merchant.ID,
+		Amount:          types.ToUint128(10),
+		Ledger:          1,
+		Code:            1,
+	}
+	results, err = cluster.CreateTransfers([]types.Transfer{toLimited, spent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+	_, err = Reverse(cluster, toLimited.ID, Options{})
+	assert.Equal(t, ErrTransferFailed{TransferID: toLimited.ID, Result: types.TransferExceedsCredits}, err)
+}
+
+// conflicting creates a reversal between the lookup and the creation of another.
+type conflicting struct {
+	*fake.Cluster
+	transferID types.Uint128
+}
+
+func (c conflicting) CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error) {
+	if _, err := Reverse(c.Cluster, c.transferID, Options{Amount: types.ToUint128(1)}); err != nil {
+		return nil, err
+	}
+	return c.Cluster.CreateTransfers(transfers)
+}
+
+func Test_Reverse_Conflict(t *testing.T) {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	accountResults, err := cluster.CreateAccounts([]types.Account{
+		{ID: types.ToUint128(1), Ledger: 1, Code: 1},
+		{ID: types.ToUint128(2), Ledger: 1, Code: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, accountResults)
+	payment := types.Transfer{
+		ID:              types.ToUint128(10),
+		DebitAccountID:  types.ToUint128(1),
+		CreditAccountID: types.ToUint128(2),
+		Amount:          types.ToUint128(100),
+		Ledger:          1,
+		Code:            1,
+	}
+	results, err := cluster.CreateTransfers([]types.Transfer{payment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	_, err = Reverse(conflicting{cluster, payment.ID}, payment.ID, Options{})
+	assert.Equal(t, ErrConflict{TransferID: payment.ID, ReversalID: ID(payment.ID, 0)}, err)
+
+	// A retry reverses the remaining amount only.
+	rest, err := Reverse(cluster, payment.ID, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(99), rest.Amount)
+}
+
+func Test_Reverse_ReversalsExceedAmount(t *testing.T) {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	accountResults, err := cluster.CreateAccounts([]types.Account{
+		{ID: types.ToUint128(1), Ledger: 1, Code: 1},
+		{ID: types.ToUint128(2), Ledger: 1, Code: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, accountResults)
+	payment := types.Transfer{
+		ID:              types.ToUint128(10),
+		DebitAccountID:  types.ToUint128(1),
+		CreditAccountID: types.ToUint128(2),
+		Amount:          types.ToUint128(100),
+		Ledger:          1,
+		Code:            1,
+	}
+	// A transfer not created by Reverse takes the first reversal ID, with a larger amount.
+	impostor := types.Transfer{
+		ID:              ID(payment.ID, 0),
+		DebitAccountID:  types.ToUint128(2),
+		CreditAccountID: types.ToUint128(1),
+		Amount:          types.ToUint128(101),
+		Ledger:          1,
+		Code:            1,
+	}
+	results, err := cluster.CreateTransfers([]types.Transfer{payment, impostor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	_, err = Reverse(cluster, payment.ID, Options{})
+	assert.Equal(t, ErrReversalsExceedAmount{TransferID: payment.ID}, err)
+}
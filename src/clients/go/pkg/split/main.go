@@ -0,0 +1,344 @@
+// Package split pays a total from one account to many recipients, such as a seller, a platform
+// fee, and tax.
+//
+// Recipients receive either an explicit amount, or a share of what remains by weight. Weighted
+// shares are allocated exactly with the largest remainder method: each share is rounded down,
+// and the units left over go to the shares with the largest remainders, so the amounts always
+// add up to the total.
+//
+// A split of up to 8190 legs is created atomically as one chain of linked transfers. Larger
+// splits do not fit in one request, so they are first created as pending transfers, in chunks,
+// and only posted once every chunk is created; if a chunk fails, the pending transfers already
+// created are voided. The IDs of the legs, and of the transfers that post or void them, are
+// derived from the split's ID as for a transaction (see package transaction).
+//
+// If Pay is interrupted, for example by a crash, pay the split again with the same ID: the chunks
+// that already exist are compared with the split's legs, and the others are created, then posted
+// or voided. Set Timeout so that the cluster voids the pending legs of a split that is never paid
+// again.
+package split
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/transaction"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to pay splits.
+type Client interface {
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+	LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error)
+}
+
+// batchMax is the maximum number of events per request.
+const batchMax = 8190
+
+// Recipient receives either an explicit Amount, or a share by Weight of the total that remains
+// after the explicit amounts.
+type Recipient struct {
+	AccountID types.Uint128
+	Amount    types.Uint128
+	Weight    uint64
+}
+
+type Split struct {
+	// The ID of the split. If zero, Pay sets it with types.ID.
+	ID         types.Uint128
+	Source     types.Uint128
+	Total      types.Uint128
+	Recipients []Recipient
+	Ledger     uint32
+	Code       uint16
+	// The timeout in seconds of the pending legs of a split too large for one chain, after which
+	// the cluster voids them unless they are posted. Zero never times out. Pay fails to post legs
+	// that time out before every chunk is created.
+	Timeout uint32
+	// The `UserData128`, `UserData64`, and `UserData32` of every leg.
+	UserData128 types.Uint128
+	UserData64  uint64
+	UserData32  uint32
+}
+
+type ErrInvalidSplit struct {
+	Reason string
+}
+
+func (e ErrInvalidSplit) Error() string {
+	return fmt.Sprintf("Invalid split: %s.", e.Reason)
+}
+
+// ErrTransferFailed is returned when the cluster rejects a leg. Pay only returns it once the
+// pending legs already created are voided, if any, so no recipient is paid; unless posting them
+// failed, which the cluster only allows if they are voided or posted concurrently.
+type ErrTransferFailed struct {
+	ID types.Uint128
+	// The index of the recipient.
+	Index  int
+	Result types.CreateTransferResult
+}
+
+func (e ErrTransferFailed) Error() string {
+	return fmt.Sprintf("Split %s failed at recipient %d: %s.", e.ID.DecimalString(), e.Index, e.Result)
+}
+
+// ErrConflict is returned when a split, or the posting or voiding of its pending legs, already
+// exists with different legs, such as a different split paid with the same ID. Pay does not void
+// the pending legs then, as they may belong to the existing split.
+type ErrConflict struct {
+	ID types.Uint128
+	// The index of the recipient whose leg differs, and its differing fields. A leg that does not
+	// exist has no fields.
+	Index  int
+	Fields []types.FieldDiff
+}
+
+func (e ErrConflict) Error() string {
+	return fmt.Sprintf("Split %s exists with a different leg for recipient %d.", e.ID.DecimalString(), e.Index)
+}
+
+// ErrVoidFailed is returned when the pending legs of a failed split could not be voided, and so
+// remain pending.
+type ErrVoidFailed struct {
+	ID     types.Uint128
+	Cause  ErrTransferFailed
+	Result types.CreateTransferResult
+}
+
+func (e ErrVoidFailed) Error() string {
+	return fmt.Sprintf("%s Voiding its pending transfers failed: %s.", e.Cause.Error(), e.Result)
+}
+
+// Allocate returns the amount of each recipient, in order, which add up to the total.
+func Allocate(total types.Uint128, recipients []Recipient) ([]types.Uint128, error) {
+	if total.IsZero() {
+		return nil, ErrInvalidSplit{Reason: "total must not be zero"}
+	}
+	if len(recipients) == 0 {
+		return nil, ErrInvalidSplit{Reason: "there must be at least one recipient"}
+	}
+
+	amounts := make([]types.Uint128, len(recipients))
+	rest := total
+	var weights big.Int
+	for i, recipient := range recipients {
+		switch {
+		case recipient.Weight == 0 && recipient.Amount.IsZero():
+			return nil, ErrInvalidSplit{Reason: fmt.Sprintf("recipient %d has neither an amount nor a weight", i)}
+		case recipient.Weight != 0 && !recipient.Amount.IsZero():
+			return nil, ErrInvalidSplit{Reason: fmt.Sprintf("recipient %d has both an amount and a weight", i)}
+		case recipient.Weight != 0:
+			weights.Add(&weights, new(big.Int).SetUint64(recipient.Weight))
+			continue
+		}
+		var err error
+		if rest, err = rest.SubChecked(recipient.Amount); err != nil {
+			return nil, ErrInvalidSplit{Reason: "the amounts exceed the total"}
+		}
+		amounts[i] = recipient.Amount
+	}
+	if weights.Sign() == 0 {
+		if !rest.IsZero() {
+			return nil, ErrInvalidSplit{Reason: "the amounts do not add up to the total"}
+		}
+		return amounts, nil
+	}
+
+	type share struct {
+		index     int
+		remainder big.Int
+	}
+	var shares []share
+	restBig := rest.BigInt()
+	allocated := new(big.Int)
+	for i, recipient := range recipients {
+		if recipient.Weight == 0 {
+			continue
+		}
+		product := new(big.Int).Mul(&restBig, new(big.Int).SetUint64(recipient.Weight))
+		s := share{index: i}
+		quotient, _ := product.QuoRem(product, &weights, &s.remainder)
+		// The quotient is at most the rest, which fits in a Uint128.
+		amounts[i] = types.BigIntToUint128(*quotient)
+		allocated.Add(allocated, quotient)
+		shares = append(shares, s)
+	}
+	sort.SliceStable(shares, func(a, b int) bool {
+		return shares[a].remainder.Cmp(&shares[b].remainder) > 0
+	})
+	// Fewer units are left over than there are weighted recipients.
+	leftover := new(big.Int).Sub(&restBig, allocated).Int64()
+	for _, s := range shares[:leftover] {
+		amounts[s.index] = amounts[s.index].Add(types.ToUint128(1))
+	}
+	return amounts, nil
+}
+
+// Result is the outcome of a split.
+type Result struct {
+	ID types.Uint128
+	// The legs, by recipient. Recipients allocated a zero amount have no leg, and a zero ID.
+	Legs []types.Transfer
+	// Whether the split was too large for one chain, and was paid with pending transfers that
+	// were then posted.
+	Pending bool
+}
+
+// Pay allocates the total and pays every recipient, atomically. Paying a split again with the
+// same ID is idempotent, unless it failed: a failed split with pending transfers must be paid
+// again with a new ID, as its legs are voided. Paying a different split with the same ID returns
+// ErrConflict.
+func Pay(client Client, split Split) (Result, error) {
+	if split.Source.IsZero() {
+		return Result{}, ErrInvalidSplit{Reason: "source must be set"}
+	}
+	amounts, err := Allocate(split.Total, split.Recipients)
+	if err != nil {
+		return Result{}, err
+	}
+	if split.ID.IsZero() {
+		split.ID = types.ID()
+	}
+
+	result := Result{ID: split.ID, Legs: make([]types.Transfer, len(amounts))}
+	var transfers []types.Transfer
+	// The recipient of each leg.
+	var recipients []int
+	for i, amount := range amounts {
+		if amount.IsZero() {
+			continue
+		}
+		recipients = append(recipients, i)
+		transfers = append(transfers, types.Transfer{
+			DebitAccountID:  split.Source,
+			CreditAccountID: split.Recipients[i].AccountID,
+			Amount:          amount,
+			UserData128:     split.UserData128,
+			UserData64:      split.UserData64,
+			UserData32:      split.UserData32,
+			Ledger:          split.Ledger,
+			Code:            split.Code,
+		})
+	}
+	result.Pending = len(transfers) > batchMax
+	if result.Pending {
+		for i := range transfers {
+			transfers[i].Flags = types.TransferFlagPending.ToUint16()
+			transfers[i].Timeout = split.Timeout
+		}
+	}
+
+	legs := make([]types.Transfer, 0, len(transfers))
+	for start := 0; start < len(transfers); start += batchMax {
+		end := start + batchMax
+		if end > len(transfers) {
+			end = len(transfers)
+		}
+		chunk, err := create(client, split.ID, split.ID, start, transfers[start:end], recipients)
+		if cause, ok := err.(ErrTransferFailed); ok {
+			if start > 0 {
+				err := resolve(client, split.ID, legs, recipients, types.TransferFlagVoidPendingTransfer)
+				if void, ok := err.(ErrTransferFailed); ok {
+					return Result{}, ErrVoidFailed{ID: split.ID, Cause: cause, Result: void.Result}
+				}
+				if err != nil {
+					return Result{}, err
+				}
+			}
+			return Result{}, cause
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		legs = append(legs, chunk...)
+	}
+	if result.Pending {
+		if err := resolve(client, split.ID, legs, recipients, types.TransferFlagPostPendingTransfer); err != nil {
+			return Result{}, err
+		}
+	}
+
+	for i, leg := range legs {
+		result.Legs[recipients[i]] = leg
+	}
+	return result, nil
+}
+
+// create creates a chunk of the transaction `id` (the split, or the posting or voiding of its
+// pending legs), numbered from the leg `start`, and returns it. A chunk that already exists fails
+// at its first leg with `TransferExists`, and at the others with `TransferLinkedEventFailed`, so
+// its legs are compared with the existing ones. `recipients` is the recipient of each leg.
+func create(
+	client Client,
+	splitID types.Uint128,
+	id types.Uint128,
+	start int,
+	legs []types.Transfer,
+	recipients []int,
+) ([]types.Transfer, error) {
+	chunk, err := transaction.Chunk(id, uint32(start), legs)
+	if err != nil {
+		return nil, err
+	}
+	results, err := client.CreateTransfers(chunk)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range results {
+		switch {
+		case event.Result == types.TransferLinkedEventFailed:
+			continue
+		case event.Index == 0 && (event.Result == types.TransferExists ||
+			event.Result.ExistsWithDifferent()):
+			err := transaction.New(client).VerifyChunk(id, uint32(start), chunk)
+			if conflict, ok := err.(transaction.ErrConflict); ok {
+				return nil, ErrConflict{
+					ID:     splitID,
+					Index:  recipients[conflict.Index],
+					Fields: conflict.Fields,
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			return chunk, nil
+		}
+		return nil, ErrTransferFailed{
+			ID:     splitID,
+			Index:  recipients[start+int(event.Index)],
+			Result: event.Result,
+		}
+	}
+	return chunk, nil
+}
+
+// resolve posts or voids the pending legs in chunks, as the transactions PostID or VoidID of the
+// split (see transaction.Chunk). `recipients` is the recipient of each pending leg.
+func resolve(
+	client Client,
+	id types.Uint128,
+	pending []types.Transfer,
+	recipients []int,
+	flag types.TransferFlag,
+) error {
+	resolutionID := transaction.PostID(id)
+	if flag == types.TransferFlagVoidPendingTransfer {
+		resolutionID = transaction.VoidID(id)
+	}
+	resolutions, err := transaction.Resolutions(id, pending, flag)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(resolutions); start += batchMax {
+		end := start + batchMax
+		if end > len(resolutions) {
+			end = len(resolutions)
+		}
+		if _, err := create(client, id, resolutionID, start, resolutions[start:end], recipients); err != nil {
+			return err
+		}
+	}
+	return nil
+}
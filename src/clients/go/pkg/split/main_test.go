@@ -0,0 +1,317 @@
+package split
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/transaction"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func Test_Allocate(t *testing.T) {
+	amounts, err := Allocate(types.ToUint128(100), []Recipient{
+		{Amount: types.ToUint128(10)},
+		{Weight: 1},
+		{Weight: 1},
+		{Weight: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 90 / 3 is exact.
+	assert.Equal(t, []types.Uint128{types.ToUint128(10), types.ToUint128(30), types.ToUint128(30), types.ToUint128(30)}, amounts)
+
+	// 100 / 3 leaves one unit over, for the first of the equal remainders.
+	amounts, err = Allocate(types.ToUint128(100), []Recipient{{Weight: 1}, {Weight: 1}, {Weight: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []types.Uint128{types.ToUint128(34), types.ToUint128(33), types.ToUint128(33)}, amounts)
+
+	// 10 by 1:2:4 is 1.43, 2.86, and 5.71, so the two units left over go to the largest
+	// remainders.
+	amounts, err = Allocate(types.ToUint128(10), []Recipient{{Weight: 1}, {Weight: 2}, {Weight: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []types.Uint128{types.ToUint128(1), types.ToUint128(3), types.ToUint128(6)}, amounts)
+
+	// Shares never overflow, even of the largest total.
+	max := types.Uint128{}.Sub(types.ToUint128(1))
+	amounts, err = Allocate(max, []Recipient{{Weight: 1 << 63}, {Weight: 1 << 63}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, max, amounts[0].Add(amounts[1]))
+
+	for _, recipients := range [][]Recipient{
+		nil,
+		{{}},
+		{{Amount: types.ToUint128(1), Weight: 1}},
+		{{Amount: types.ToUint128(99)}},
+		{{Amount: types.ToUint128(101)}, {Weight: 1}},
+	} {
+		_, err := Allocate(types.ToUint128(100), recipients)
+		_, ok := err.(ErrInvalidSplit)
+		assert.True(t, ok)
+	}
+	_, err = Allocate(types.Uint128{}, []Recipient{{Weight: 1}})
+	_, ok := err.(ErrInvalidSplit)
+	assert.True(t, ok)
+}
+
+func setup(t *testing.T, recipients int) *fake.Cluster {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	accounts := []types.Account{{ID: types.ToUint128(1), Ledger: 1, Code: 1}}
+	for i := 0; i < recipients; i++ {
+		accounts = append(accounts, types.Account{ID: types.ToUint128(uint64(100 + i)), Ledger: 1, Code: 1})
+	}
+	for start := 0; start < len(accounts); start += batchMax {
+		end := start + batchMax
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+		results, err := cluster.CreateAccounts(accounts[start:end])
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, results)
+	}
+	return cluster
+}
+
+func Test_Pay(t *testing.T) {
+	cluster := setup(t, 3)
+	split := Split{
+		ID:     types.ToUint128(1000),
+		Source: types.ToUint128(1),
+		Total:  types.ToUint128(1000),
+		Recipients: []Recipient{
+			{AccountID: types.ToUint128(100), Weight: 85},
+			{AccountID: types.ToUint128(101), Weight: 15},
+			{AccountID: types.ToUint128(102), Amount: types.ToUint128(80)},
+		},
+		Ledger: 1,
+		Code:   1,
+	}
+	result, err := Pay(cluster, split)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, false, result.Pending)
+	assert.Len(t, result.Legs, 3)
+	assert.Equal(t, types.ToUint128(782), result.Legs[0].Amount)
+	assert.Equal(t, types.ToUint128(138), result.Legs[1].Amount)
+	assert.Equal(t, types.ToUint128(80), result.Legs[2].Amount)
+	assert.Equal(t, transaction.LegID(split.ID, 2), result.Legs[2].ID)
+
+	// Paying again is idempotent, but paying a different split with the same ID conflicts.
+	_, err = Pay(cluster, split)
+	assert.Equal(t, nil, err)
+	different := split
+	different.Recipients = []Recipient{split.Recipients[1], split.Recipients[0], split.Recipients[2]}
+	_, err = Pay(cluster, different)
+	conflict, ok := err.(ErrConflict)
+	assert.True(t, ok)
+	assert.Equal(t, 0, conflict.Index)
+	assert.Equal(t, "credit_account_id", conflict.Fields[0].Field)
+
+	found, err := transaction.New(cluster).LookupTransaction(split.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, found, 3)
+	accounts, err := cluster.LookupAccounts([]types.Uint128{types.ToUint128(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(1000), accounts[0].DebitsPosted)
+
+	// A failing leg fails the split.
+	split.ID = types.ToUint128(1001)
+	split.Recipients[1].AccountID = types.ToUint128(99)
+	_, err = Pay(cluster, split)
+	assert.Equal(t, ErrTransferFailed{ID: split.ID, Index: 1, Result: types.TransferCreditAccountNotFound}, err)
+}
+
+func Test_Pay_Pending(t *testing.T) {
+	recipients := batchMax + 10
+	cluster := setup(t, recipients)
+	split := Split{
+		ID:     types.ToUint128(1000),
+		Source: types.ToUint128(1),
+		Total:  types.ToUint128(1_000_000),
+		Ledger: 1,
+		Code:   1,
+	}
+	for i := 0; i < recipients; i++ {
+		split.Recipients = append(split.Recipients, Recipient{AccountID: types.ToUint128(uint64(100 + i)), Weight: 1})
+	}
+
+	// The last recipient does not exist, so the first chunk is voided.
+	failing := split
+	failing.ID = types.ToUint128(999)
+	failing.Recipients = append([]Recipient{}, split.Recipients...)
+	failing.Recipients[recipients-1].AccountID = types.ToUint128(99)
+	_, err := Pay(cluster, failing)
+	assert.Equal(t, ErrTransferFailed{ID: failing.ID, Index: recipients - 1, Result: types.TransferCreditAccountNotFound}, err)
+	accounts, err := cluster.LookupAccounts([]types.Uint128{types.ToUint128(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.Uint128{}, accounts[0].DebitsPending)
+	assert.Equal(t, types.Uint128{}, accounts[0].DebitsPosted)
+
+	result, err := Pay(cluster, split)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, result.Pending)
+	assert.Len(t, result.Legs, recipients)
+	accounts, err = cluster.LookupAccounts([]types.Uint128{types.ToUint128(1), types.ToUint128(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.Uint128{}, accounts[0].DebitsPending)
+	assert.Equal(t, types.ToUint128(1_000_000), accounts[0].DebitsPosted)
+	assert.Equal(t, result.Legs[0].Amount, accounts[1].CreditsPosted)
+
+	// Paying again is idempotent, but a different leg in the second chunk conflicts.
+	_, err = Pay(cluster, split)
+	assert.Equal(t, nil, err)
+	different := split
+	different.Recipients = append([]Recipient{}, split.Recipients...)
+	different.Recipients[recipients-1].AccountID = types.ToUint128(100)
+	_, err = Pay(cluster, different)
+	conflict, ok := err.(ErrConflict)
+	assert.True(t, ok)
+	assert.Equal(t, recipients-1, conflict.Index)
+	assert.Equal(t, "credit_account_id", conflict.Fields[0].Field)
+}
+
+func Test_Pay_Retry(t *testing.T) {
+	recipients := batchMax + 10
+	cluster := setup(t, recipients)
+	split := Split{
+		ID:      types.ToUint128(1000),
+		Source:  types.ToUint128(1),
+		Total:   types.ToUint128(1_000_000),
+		Ledger:  1,
+		Code:    1,
+		Timeout: 3600,
+	}
+	for i := 0; i < recipients; i++ {
+		split.Recipients = append(split.Recipients, Recipient{AccountID: types.ToUint128(uint64(100 + i)), Weight: 1})
+	}
+
+	// Posting fails, as if Pay was interrupted, so the legs remain pending until they time out.
+	client := failing{Cluster: cluster, faults: map[types.Uint128]fault{
+		transaction.LegID(transaction.PostID(split.ID), 0): {index: 0, result: types.TransferPendingTransferExpired},
+	}}
+	_, err := Pay(client, split)
+	assert.Equal(t, ErrTransferFailed{ID: split.ID, Index: 0, Result: types.TransferPendingTransferExpired}, err)
+	pending, err := transaction.New(cluster).LookupTransaction(split.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, pending, recipients)
+	assert.Equal(t, uint32(3600), pending[0].Timeout)
+
+	// Paying again verifies the existing chunks, and posts them.
+	result, err := Pay(cluster, split)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, result.Pending)
+	accounts, err := cluster.LookupAccounts([]types.Uint128{types.ToUint128(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.Uint128{}, accounts[0].DebitsPending)
+	assert.Equal(t, types.ToUint128(1_000_000), accounts[0].DebitsPosted)
+}
+
+// failing fails the chains whose first transfer has a faulty ID, without creating any of their
+// transfers.
+type failing struct {
+	*fake.Cluster
+	faults map[types.Uint128]fault
+}
+
+// fault is the leg that fails a chain, and its result.
+type fault struct {
+	index  uint32
+	result types.CreateTransferResult
+}
+
+func (c failing) CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error) {
+	if len(transfers) == 0 {
+		return c.Cluster.CreateTransfers(transfers)
+	}
+	fault, ok := c.faults[transfers[0].ID]
+	if !ok {
+		return c.Cluster.CreateTransfers(transfers)
+	}
+	results := make([]types.TransferEventResult, len(transfers))
+	for i := range transfers {
+		results[i] = types.TransferEventResult{Index: uint32(i), Result: types.TransferLinkedEventFailed}
+	}
+	results[fault.index].Result = fault.result
+	return results, nil
+}
+
+func Test_Pay_Void(t *testing.T) {
+	recipients := batchMax + 10
+	cluster := setup(t, recipients)
+	split := Split{
+		ID:     types.ToUint128(1000),
+		Source: types.ToUint128(1),
+		Total:  types.ToUint128(1_000_000),
+		Ledger: 1,
+		Code:   1,
+	}
+	for i := 0; i < recipients; i++ {
+		split.Recipients = append(split.Recipients, Recipient{AccountID: types.ToUint128(uint64(100 + i)), Weight: 1})
+	}
+
+	// The second chunk fails, so the first chunk is voided.
+	client := failing{Cluster: cluster, faults: map[types.Uint128]fault{
+		transaction.LegID(split.ID, batchMax): {index: 3, result: types.TransferExceedsCredits},
+	}}
+	_, err := Pay(client, split)
+	assert.Equal(t, ErrTransferFailed{ID: split.ID, Index: batchMax + 3, Result: types.TransferExceedsCredits}, err)
+	voided, err := transaction.New(cluster).LookupTransaction(transaction.VoidID(split.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, voided, batchMax)
+	assert.Equal(t, transaction.LegID(split.ID, batchMax-1), voided[batchMax-1].PendingID)
+	accounts, err := cluster.LookupAccounts([]types.Uint128{types.ToUint128(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.Uint128{}, accounts[0].DebitsPending)
+	assert.Equal(t, types.Uint128{}, accounts[0].DebitsPosted)
+
+	// If voiding fails too, the first chunk remains pending.
+	split.ID = types.ToUint128(1001)
+	client = failing{Cluster: cluster, faults: map[types.Uint128]fault{
+		transaction.LegID(split.ID, batchMax):              {index: 3, result: types.TransferExceedsCredits},
+		transaction.LegID(transaction.VoidID(split.ID), 0): {index: 0, result: types.TransferPendingTransferExpired},
+	}}
+	_, err = Pay(client, split)
+	assert.Equal(t, ErrVoidFailed{
+		ID:     split.ID,
+		Cause:  ErrTransferFailed{ID: split.ID, Index: batchMax + 3, Result: types.TransferExceedsCredits},
+		Result: types.TransferPendingTransferExpired,
+	}, err)
+	_, err = transaction.New(cluster).LookupTransaction(transaction.VoidID(split.ID))
+	assert.Equal(t, transaction.ErrNotFound{ID: transaction.VoidID(split.ID)}, err)
+	pending, err := transaction.New(cluster).LookupTransaction(split.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, pending, batchMax)
+}
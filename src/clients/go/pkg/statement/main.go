@@ -0,0 +1,482 @@
+// Package statement generates account statements with running balances.
+//
+// A statement covers the transfers of one account within a time range, in commit order. Each
+// line carries the account's balances after the transfer was applied, together with the
+// opening and closing balances of the range.
+//
+// For accounts with the `History` flag the balances are read from `GetAccountBalances`.
+// Otherwise they are recomputed by replaying every transfer of the account from its creation,
+// including the expiry of pending transfers, which is not visible through `GetAccountTransfers`.
+package statement
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to generate statements.
+type Client interface {
+	LookupAccounts(accountIDs []types.Uint128) ([]types.Account, error)
+	LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error)
+	GetAccountTransfers(filter types.AccountFilter) ([]types.Transfer, error)
+	GetAccountBalances(filter types.AccountFilter) ([]types.AccountBalance, error)
+}
+
+// pageMax is the maximum number of results returned by a single query.
+const pageMax = 8190
+
+type Direction uint8
+
+const (
+	Debit Direction = iota
+	Credit
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Debit:
+		return "Debit"
+	case Credit:
+		return "Credit"
+	}
+	return fmt.Sprintf("Direction(%d)", uint8(d))
+}
+
+type State uint8
+
+const (
+	// A single-phase transfer, posted immediately.
+	Posted State = iota
+	// A pending transfer, reserving its amount.
+	Pending
+	// Posts a pending transfer, releasing the reserved amount and posting `Amount`.
+	PostPending
+	// Voids a pending transfer, releasing the reserved amount.
+	VoidPending
+)
+
+func (s State) String() string {
+	switch s {
+	case Posted:
+		return "Posted"
+	case Pending:
+		return "Pending"
+	case PostPending:
+		return "PostPending"
+	case VoidPending:
+		return "VoidPending"
+	}
+	return fmt.Sprintf("State(%d)", uint8(s))
+}
+
+// Balance is a snapshot of an account's balances.
+type Balance struct {
+	DebitsPending  types.Uint128
+	DebitsPosted   types.Uint128
+	CreditsPending types.Uint128
+	CreditsPosted  types.Uint128
+}
+
+// NetPosted returns `CreditsPosted - DebitsPosted`.
+func (b Balance) NetPosted() types.Net {
+	return b.accountBalance().NetPosted(0)
+}
+
+// NetPending returns `CreditsPending - DebitsPending`.
+func (b Balance) NetPending() types.Net {
+	return b.accountBalance().NetPending(0)
+}
+
+func (b Balance) accountBalance() types.AccountBalance {
+	return types.AccountBalance{
+		DebitsPending:  b.DebitsPending,
+		DebitsPosted:   b.DebitsPosted,
+		CreditsPending: b.CreditsPending,
+		CreditsPosted:  b.CreditsPosted,
+	}
+}
+
+type Line struct {
+	Transfer types.Transfer
+	// The other account of the transfer.
+	Counterparty types.Uint128
+	Direction    Direction
+	State        State
+	// The amount moved by this transfer. Voids report the released amount.
+	Amount types.Uint128
+	// The account's balances after this transfer.
+	Balance Balance
+}
+
+type Statement struct {
+	Account types.Account
+	// The range covered by the statement, `From` inclusive and `To` exclusive.
+	From time.Time
+	To   time.Time
+	// The balances after the last transfer before the range, and after the last transfer within
+	// the range. Pending transfers that expire without a subsequent transfer are reflected only
+	// by the next line, except for replayed accounts (without `History`), whose opening and
+	// closing balances also release the pending transfers expired by the start and the end of
+	// the range.
+	Opening Balance
+	Closing Balance
+	Lines   []Line
+}
+
+type ErrInvalidRange struct{}
+
+func (e ErrInvalidRange) Error() string {
+	return "Statement range must end after it starts, at a positive timestamp."
+}
+
+// Generate builds the statement of `accountID` for transfers committed within [from, to).
+func Generate(client Client, accountID types.Uint128, from time.Time, to time.Time) (Statement, error) {
+	if from.UnixNano() <= 0 || !from.Before(to) {
+		return Statement{}, ErrInvalidRange{}
+	}
+
+	accounts, err := client.LookupAccounts([]types.Uint128{accountID})
+	if err != nil {
+		return Statement{}, err
+	}
+	if len(accounts) == 0 {
+		return Statement{}, errors.ErrAccountNotFound{}
+	}
+
+	statement := Statement{
+		Account: accounts[0],
+		From:    from,
+		To:      to,
+		Lines:   []Line{},
+	}
+	timestampMin := uint64(from.UnixNano())
+	timestampMax := uint64(to.UnixNano()) - 1
+
+	if statement.Account.AccountFlags().History {
+		err = generateFromHistory(client, &statement, timestampMin, timestampMax)
+	} else {
+		err = generateFromReplay(client, &statement, timestampMin, timestampMax)
+	}
+	if err != nil {
+		return Statement{}, err
+	}
+	return statement, nil
+}
+
+func filter(accountID types.Uint128, timestampMin uint64, timestampMax uint64) types.AccountFilter {
+	return types.AccountFilter{
+		AccountID:    accountID,
+		TimestampMin: timestampMin,
+		TimestampMax: timestampMax,
+		Limit:        pageMax,
+		Flags: types.AccountFilterFlags{
+			Debits:  true,
+			Credits: true,
+		}.ToUint32(),
+	}
+}
+
+// scan calls `visit` for every transfer of the account within the range, in commit order.
+func scan(
+	client Client,
+	accountID types.Uint128,
+	timestampMin uint64,
+	timestampMax uint64,
+	visit func(transfers []types.Transfer) error,
+) error {
+	for {
+		transfers, err := client.GetAccountTransfers(filter(accountID, timestampMin, timestampMax))
+		if err != nil {
+			return err
+		}
+		if len(transfers) > 0 {
+			if err := visit(transfers); err != nil {
+				return err
+			}
+		}
+		if len(transfers) < pageMax {
+			return nil
+		}
+		timestampMin = transfers[len(transfers)-1].Timestamp + 1
+	}
+}
+
+func newLine(accountID types.Uint128, transfer types.Transfer, pending *types.Transfer) Line {
+	line := Line{
+		Transfer: transfer,
+		Amount:   transfer.Amount,
+	}
+	if transfer.DebitAccountID == accountID {
+		line.Direction = Debit
+		line.Counterparty = transfer.CreditAccountID
+	} else {
+		line.Direction = Credit
+		line.Counterparty = transfer.DebitAccountID
+	}
+
+	flags := transfer.TransferFlags()
+	switch {
+	case flags.Pending:
+		line.State = Pending
+	case flags.PostPendingTransfer:
+		line.State = PostPending
+	case flags.VoidPendingTransfer:
+		line.State = VoidPending
+		if pending != nil {
+			line.Amount = pending.Amount
+		}
+	default:
+		line.State = Posted
+	}
+	return line
+}
+
+func generateFromHistory(
+	client Client,
+	statement *Statement,
+	timestampMin uint64,
+	timestampMax uint64,
+) error {
+	accountID := statement.Account.ID
+
+	// The opening balance is the balance after the last transfer before the range.
+	if timestampMin > 1 {
+		opening := filter(accountID, 0, timestampMin-1)
+		opening.Limit = 1
+		opening.Flags = types.AccountFilterFlags{
+			Debits:   true,
+			Credits:  true,
+			Reversed: true,
+		}.ToUint32()
+		balances, err := client.GetAccountBalances(opening)
+		if err != nil {
+			return err
+		}
+		if len(balances) > 0 {
+			statement.Opening = fromAccountBalance(balances[0])
+		}
+	}
+	statement.Closing = statement.Opening
+
+	pendings := map[types.Uint128]types.Transfer{}
+	return scan(client, accountID, timestampMin, timestampMax, func(transfers []types.Transfer) error {
+		balances, err := client.GetAccountBalances(filter(
+			accountID,
+			transfers[0].Timestamp,
+			transfers[len(transfers)-1].Timestamp,
+		))
+		if err != nil {
+			return err
+		}
+		if len(balances) != len(transfers) {
+			return fmt.Errorf("expected %d balances, got %d", len(transfers), len(balances))
+		}
+
+		if err := lookupPendings(client, transfers, pendings); err != nil {
+			return err
+		}
+
+		for i, transfer := range transfers {
+			if balances[i].Timestamp != transfer.Timestamp {
+				return fmt.Errorf("balance at %d does not match transfer at %d",
+					balances[i].Timestamp, transfer.Timestamp)
+			}
+			var pending *types.Transfer
+			if p, ok := pendings[transfer.PendingID]; ok {
+				pending = &p
+			}
+
+			line := newLine(accountID, transfer, pending)
+			line.Balance = fromAccountBalance(balances[i])
+			statement.Lines = append(statement.Lines, line)
+			statement.Closing = line.Balance
+		}
+		return nil
+	})
+}
+
+func fromAccountBalance(balance types.AccountBalance) Balance {
+	return Balance{
+		DebitsPending:  balance.DebitsPending,
+		DebitsPosted:   balance.DebitsPosted,
+		CreditsPending: balance.CreditsPending,
+		CreditsPosted:  balance.CreditsPosted,
+	}
+}
+
+// lookupPendings fetches the pending transfers referenced by posting and voiding transfers that
+// are not already known.
+func lookupPendings(client Client, transfers []types.Transfer, pendings map[types.Uint128]types.Transfer) error {
+	missing := []types.Uint128{}
+	for _, transfer := range transfers {
+		if transfer.PendingID == (types.Uint128{}) {
+			continue
+		}
+		if _, ok := pendings[transfer.PendingID]; !ok {
+			missing = append(missing, transfer.PendingID)
+		}
+	}
+	for start := 0; start < len(missing); start += pageMax {
+		end := start + pageMax
+		if end > len(missing) {
+			end = len(missing)
+		}
+		found, err := client.LookupTransfers(missing[start:end])
+		if err != nil {
+			return err
+		}
+		for _, pending := range found {
+			pendings[pending.ID] = pending
+		}
+	}
+	return nil
+}
+
+// replay tracks an account's balances by applying its transfers in commit order.
+type replay struct {
+	accountID types.Uint128
+	balance   [4]big.Int // Indexed like Balance's fields.
+	// Pending transfers with a timeout that were neither posted nor voided yet.
+	open map[types.Uint128]types.Transfer
+}
+
+const (
+	debitsPending = iota
+	debitsPosted
+	creditsPending
+	creditsPosted
+)
+
+func (r *replay) adjust(field int, amount types.Uint128, sign int) {
+	value := amount.BigInt()
+	if sign < 0 {
+		r.balance[field].Sub(&r.balance[field], &value)
+	} else {
+		r.balance[field].Add(&r.balance[field], &value)
+	}
+}
+
+// expire releases the pending transfers that the cluster expired at or before `timestamp`.
+func (r *replay) expire(timestamp uint64) {
+	for id, pending := range r.open {
+		expiresAt := pending.Timestamp + uint64(pending.Timeout)*uint64(time.Second)
+		if expiresAt <= timestamp {
+			r.releasePending(pending)
+			delete(r.open, id)
+		}
+	}
+}
+
+func (r *replay) releasePending(pending types.Transfer) {
+	if pending.DebitAccountID == r.accountID {
+		r.adjust(debitsPending, pending.Amount, -1)
+	} else {
+		r.adjust(creditsPending, pending.Amount, -1)
+	}
+}
+
+func (r *replay) apply(transfer types.Transfer, pending *types.Transfer) error {
+	r.expire(transfer.Timestamp)
+
+	debit := transfer.DebitAccountID == r.accountID
+	flags := transfer.TransferFlags()
+	switch {
+	case flags.Pending:
+		if debit {
+			r.adjust(debitsPending, transfer.Amount, 1)
+		} else {
+			r.adjust(creditsPending, transfer.Amount, 1)
+		}
+		if transfer.Timeout > 0 {
+			r.open[transfer.ID] = transfer
+		}
+	case flags.PostPendingTransfer, flags.VoidPendingTransfer:
+		if pending == nil {
+			return fmt.Errorf("pending transfer %s was not found", transfer.PendingID)
+		}
+		r.releasePending(*pending)
+		delete(r.open, pending.ID)
+		if flags.PostPendingTransfer {
+			if debit {
+				r.adjust(debitsPosted, transfer.Amount, 1)
+			} else {
+				r.adjust(creditsPosted, transfer.Amount, 1)
+			}
+		}
+	default:
+		if debit {
+			r.adjust(debitsPosted, transfer.Amount, 1)
+		} else {
+			r.adjust(creditsPosted, transfer.Amount, 1)
+		}
+	}
+	return nil
+}
+
+func (r *replay) snapshot() Balance {
+	return Balance{
+		DebitsPending:  types.BigIntToUint128(r.balance[debitsPending]),
+		DebitsPosted:   types.BigIntToUint128(r.balance[debitsPosted]),
+		CreditsPending: types.BigIntToUint128(r.balance[creditsPending]),
+		CreditsPosted:  types.BigIntToUint128(r.balance[creditsPosted]),
+	}
+}
+
+func generateFromReplay(
+	client Client,
+	statement *Statement,
+	timestampMin uint64,
+	timestampMax uint64,
+) error {
+	accountID := statement.Account.ID
+	r := replay{
+		accountID: accountID,
+		open:      map[types.Uint128]types.Transfer{},
+	}
+	pendings := map[types.Uint128]types.Transfer{}
+	openingDone := false
+
+	err := scan(client, accountID, 0, timestampMax, func(transfers []types.Transfer) error {
+		if err := lookupPendings(client, transfers, pendings); err != nil {
+			return err
+		}
+
+		for _, transfer := range transfers {
+			if !openingDone && transfer.Timestamp >= timestampMin {
+				r.expire(timestampMin - 1)
+				statement.Opening = r.snapshot()
+				openingDone = true
+			}
+
+			var pending *types.Transfer
+			if p, ok := pendings[transfer.PendingID]; ok {
+				pending = &p
+			}
+			if err := r.apply(transfer, pending); err != nil {
+				return err
+			}
+
+			if transfer.Timestamp >= timestampMin {
+				line := newLine(accountID, transfer, pending)
+				line.Balance = r.snapshot()
+				statement.Lines = append(statement.Lines, line)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !openingDone {
+		r.expire(timestampMin - 1)
+		statement.Opening = r.snapshot()
+	}
+	r.expire(timestampMax)
+	statement.Closing = r.snapshot()
+	return nil
+}
@@ -0,0 +1,203 @@
+package statement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func createTransfer(t *testing.T, cluster *fake.Cluster, transfer types.Transfer) {
+	transfer.Ledger = 1
+	transfer.Code = 1
+	results, err := cluster.CreateTransfers([]types.Transfer{transfer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+}
+
+func lookupBalance(t *testing.T, cluster *fake.Cluster, id types.Uint128) Balance {
+	accounts, err := cluster.LookupAccounts([]types.Uint128{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Balance{
+		DebitsPending:  accounts[0].DebitsPending,
+		DebitsPosted:   accounts[0].DebitsPosted,
+		CreditsPending: accounts[0].CreditsPending,
+		CreditsPosted:  accounts[0].CreditsPosted,
+	}
+}
+
+func Test_Generate(t *testing.T) {
+	for _, history := range []bool{false, true} {
+		cluster := fake.New(epoch)
+		account := types.Account{
+			ID:     types.ID(),
+			Ledger: 1,
+			Code:   1,
+			Flags:  types.AccountFlags{History: history}.ToUint16(),
+		}
+		counterparty := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+		results, err := cluster.CreateAccounts([]types.Account{account, counterparty})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, results)
+
+		// Before the statement range: a credit, and a pending debit that expires within it.
+		cluster.Advance(time.Hour)
+		createTransfer(t, cluster, types.Transfer{
+			ID:              types.ID(),
+			DebitAccountID:  counterparty.ID,
+			CreditAccountID: account.ID,
+			Amount:          types.ToUint128(1000),
+		})
+		createTransfer(t, cluster, types.Transfer{
+			ID:              types.ID(),
+			DebitAccountID:  account.ID,
+			CreditAccountID: counterparty.ID,
+			Amount:          types.ToUint128(50),
+			Timeout:         uint32((90 * time.Minute).Seconds()),
+			Flags:           types.TransferFlags{Pending: true}.ToUint16(),
+		})
+		opening := lookupBalance(t, cluster, account.ID)
+
+		// Within the statement range:
+		cluster.Advance(time.Hour)
+		from := cluster.Now()
+		createTransfer(t, cluster, types.Transfer{
+			ID:              types.ID(),
+			DebitAccountID:  account.ID,
+			CreditAccountID: counterparty.ID,
+			Amount:          types.ToUint128(300),
+		})
+		pendingID := types.ID()
+		createTransfer(t, cluster, types.Transfer{
+			ID:              pendingID,
+			DebitAccountID:  account.ID,
+			CreditAccountID: counterparty.ID,
+			Amount:          types.ToUint128(200),
+			Flags:           types.TransferFlags{Pending: true}.ToUint16(),
+		})
+		cluster.Advance(time.Hour) // Expires the first pending transfer.
+		createTransfer(t, cluster, types.Transfer{
+			ID:        types.ID(),
+			PendingID: pendingID,
+			Amount:    types.ToUint128(150),
+			Flags:     types.TransferFlags{PostPendingTransfer: true}.ToUint16(),
+		})
+		closing := lookupBalance(t, cluster, account.ID)
+
+		// After the statement range:
+		cluster.Advance(time.Hour)
+		to := cluster.Now()
+		createTransfer(t, cluster, types.Transfer{
+			ID:              types.ID(),
+			DebitAccountID:  counterparty.ID,
+			CreditAccountID: account.ID,
+			Amount:          types.ToUint128(1),
+		})
+
+		statement, err := Generate(cluster, account.ID, from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, opening, statement.Opening)
+		assert.Equal(t, closing, statement.Closing)
+		assert.Len(t, statement.Lines, 3)
+
+		line := statement.Lines[0]
+		assert.Equal(t, Debit, line.Direction)
+		assert.Equal(t, Posted, line.State)
+		assert.Equal(t, counterparty.ID, line.Counterparty)
+		assert.Equal(t, types.ToUint128(300), line.Amount)
+		assert.Equal(t, types.ToUint128(300), line.Balance.DebitsPosted)
+		assert.Equal(t, types.ToUint128(50), line.Balance.DebitsPending)
+
+		line = statement.Lines[1]
+		assert.Equal(t, Pending, line.State)
+		assert.Equal(t, types.ToUint128(250), line.Balance.DebitsPending)
+
+		line = statement.Lines[2]
+		assert.Equal(t, PostPending, line.State)
+		assert.Equal(t, types.ToUint128(150), line.Amount)
+		assert.Equal(t, types.ToUint128(0), line.Balance.DebitsPending)
+		assert.Equal(t, types.ToUint128(450), line.Balance.DebitsPosted)
+		assert.Equal(t, types.ToUint128(1000), line.Balance.CreditsPosted)
+
+		assert.Equal(t, types.Net{Magnitude: types.ToUint128(550)}, statement.Closing.NetPosted())
+	}
+}
+
+func Test_Generate_Empty(t *testing.T) {
+	cluster := fake.New(epoch)
+	account := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+	results, err := cluster.CreateAccounts([]types.Account{account})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	statement, err := Generate(cluster, account.ID, epoch, epoch.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, statement.Lines, 0)
+	assert.Equal(t, Balance{}, statement.Opening)
+	assert.Equal(t, Balance{}, statement.Closing)
+
+	_, err = Generate(cluster, account.ID, epoch, epoch)
+	assert.Equal(t, ErrInvalidRange{}, err)
+
+	_, err = Generate(cluster, types.ID(), epoch, epoch.Add(time.Hour))
+	assert.Equal(t, errors.ErrAccountNotFound{}, err)
+}
+
+func Test_Generate_Expiry(t *testing.T) {
+	cluster := fake.New(epoch)
+	account := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+	counterparty := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+	results, err := cluster.CreateAccounts([]types.Account{account, counterparty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	pending := func(amount uint64) {
+		createTransfer(t, cluster, types.Transfer{
+			ID:              types.ID(),
+			DebitAccountID:  account.ID,
+			CreditAccountID: counterparty.ID,
+			Amount:          types.ToUint128(amount),
+			Timeout:         uint32(time.Hour.Seconds()),
+			Flags:           types.TransferFlags{Pending: true}.ToUint16(),
+		})
+	}
+
+	// Expires before the range, without a transfer in between.
+	cluster.Advance(time.Hour)
+	pending(50)
+	cluster.Advance(2 * time.Hour)
+	from := cluster.Now()
+
+	// Expires within the range, without a subsequent transfer.
+	pending(200)
+	cluster.Advance(2 * time.Hour)
+	to := cluster.Now()
+
+	statement, err := Generate(cluster, account.ID, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Balance{}, statement.Opening)
+	assert.Equal(t, Balance{}, statement.Closing)
+	assert.Len(t, statement.Lines, 1)
+	assert.Equal(t, types.ToUint128(200), statement.Lines[0].Balance.DebitsPending)
+}
@@ -0,0 +1,244 @@
+// Package timeseries resamples an account's historical balances into fixed-width buckets.
+//
+// The balances are read with `GetAccountBalances`, so the account must have been created with
+// `AccountFlags.History`. A balance only changes when a transfer is committed, so buckets
+// without any transfer carry the last observed balance forward.
+package timeseries
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used to read balances.
+type Client interface {
+	GetAccountBalances(filter types.AccountFilter) ([]types.AccountBalance, error)
+}
+
+// pageMax is the maximum number of results returned by a single query.
+const pageMax = 8190
+
+// bucketsMax bounds the number of buckets of a single series.
+const bucketsMax = 1 << 20
+
+// Range summarizes the values a balance took within a bucket.
+type Range struct {
+	Open  types.Net
+	Close types.Net
+	Min   types.Net
+	Max   types.Net
+}
+
+func newRange(value types.Net) Range {
+	return Range{Open: value, Close: value, Min: value, Max: value}
+}
+
+func (r *Range) observe(value types.Net) {
+	r.Close = value
+	if value.Cmp(r.Min) < 0 {
+		r.Min = value
+	}
+	if value.Cmp(r.Max) > 0 {
+		r.Max = value
+	}
+}
+
+type Bucket struct {
+	// The bucket covers [Start, End).
+	Start time.Time
+	End   time.Time
+	// The net posted and pending balances, see Options.Flags.
+	Posted  Range
+	Pending Range
+	// The number of balance changes within the bucket.
+	Observations int
+}
+
+type Options struct {
+	// The account's flags, which set the direction of its balances (see
+	// types.AccountBalance.NetPosted): `debits - credits` with `CreditsMustNotExceedDebits`, as
+	// for asset and expense accounts, and `credits - debits` otherwise.
+	Flags types.AccountFlag
+}
+
+type ErrInvalidRange struct{}
+
+func (e ErrInvalidRange) Error() string {
+	return "Range must end after it starts, at a positive timestamp, with a positive bucket width."
+}
+
+type ErrTooManyBuckets struct{}
+
+func (e ErrTooManyBuckets) Error() string {
+	return fmt.Sprintf("Range would produce more than %d buckets.", bucketsMax)
+}
+
+// Resample reads the balances of an account within [from, to) and resamples them into
+// buckets of the given width, starting at `from`.
+func Resample(
+	client Client,
+	accountID types.Uint128,
+	from time.Time,
+	to time.Time,
+	width time.Duration,
+	options Options,
+) ([]Bucket, error) {
+	if err := validate(from, to, width); err != nil {
+		return nil, err
+	}
+	timestampMin := uint64(from.UnixNano())
+	timestampMax := uint64(to.UnixNano()) - 1
+	flags := types.AccountFilterFlags{Debits: true, Credits: true}
+
+	// The balance carried into the first bucket.
+	var initial *types.AccountBalance
+	if timestampMin > 1 {
+		balances, err := client.GetAccountBalances(types.AccountFilter{
+			AccountID:    accountID,
+			TimestampMax: timestampMin - 1,
+			Limit:        1,
+			Flags: types.AccountFilterFlags{
+				Debits:   true,
+				Credits:  true,
+				Reversed: true,
+			}.ToUint32(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(balances) > 0 {
+			initial = &balances[0]
+		}
+	}
+
+	balances := []types.AccountBalance{}
+	for {
+		page, err := client.GetAccountBalances(types.AccountFilter{
+			AccountID:    accountID,
+			TimestampMin: timestampMin,
+			TimestampMax: timestampMax,
+			Limit:        pageMax,
+			Flags:        flags.ToUint32(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, page...)
+		if len(page) < pageMax {
+			break
+		}
+		timestampMin = page[len(page)-1].Timestamp + 1
+	}
+
+	return ResampleBalances(initial, balances, from, to, width, options)
+}
+
+func validate(from time.Time, to time.Time, width time.Duration) error {
+	if from.UnixNano() <= 0 || !from.Before(to) || width <= 0 {
+		return ErrInvalidRange{}
+	}
+	if to.Sub(from)/width >= bucketsMax {
+		return ErrTooManyBuckets{}
+	}
+	return nil
+}
+
+// ResampleBalances resamples balances that were already read, in ascending timestamp order.
+// `initial` is the last balance before `from`, or nil if there was none.
+func ResampleBalances(
+	initial *types.AccountBalance,
+	balances []types.AccountBalance,
+	from time.Time,
+	to time.Time,
+	width time.Duration,
+	options Options,
+) ([]Bucket, error) {
+	if err := validate(from, to, width); err != nil {
+		return nil, err
+	}
+
+	var posted, pending types.Net
+	if initial != nil {
+		posted, pending = initial.NetPosted(options.Flags), initial.NetPending(options.Flags)
+	}
+
+	buckets := []Bucket{}
+	next := 0
+	for start := from; start.Before(to); start = start.Add(width) {
+		end := start.Add(width)
+		if end.After(to) {
+			end = to
+		}
+		bucket := Bucket{
+			Start:   start,
+			End:     end,
+			Posted:  newRange(posted),
+			Pending: newRange(pending),
+		}
+
+		for ; next < len(balances); next++ {
+			timestamp := balances[next].Timestamp
+			if timestamp < uint64(start.UnixNano()) {
+				return nil, fmt.Errorf("balance at %d is out of order", timestamp)
+			}
+			if timestamp >= uint64(end.UnixNano()) {
+				break
+			}
+			posted = balances[next].NetPosted(options.Flags)
+			pending = balances[next].NetPending(options.Flags)
+			bucket.Posted.observe(posted)
+			bucket.Pending.observe(pending)
+			bucket.Observations++
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+var csvHeader = []string{
+	"start",
+	"end",
+	"observations",
+	"posted_open",
+	"posted_close",
+	"posted_min",
+	"posted_max",
+	"pending_open",
+	"pending_close",
+	"pending_min",
+	"pending_max",
+}
+
+// WriteCSV writes the buckets as CSV with a header row.
+// Times are formatted as RFC 3339 in UTC, and balances as decimal integers.
+func WriteCSV(w io.Writer, buckets []Bucket) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		record := []string{
+			bucket.Start.UTC().Format(time.RFC3339Nano),
+			bucket.End.UTC().Format(time.RFC3339Nano),
+			strconv.Itoa(bucket.Observations),
+			bucket.Posted.Open.String(),
+			bucket.Posted.Close.String(),
+			bucket.Posted.Min.String(),
+			bucket.Posted.Max.String(),
+			bucket.Pending.Open.String(),
+			bucket.Pending.Close.String(),
+			bucket.Pending.Min.String(),
+			bucket.Pending.Max.String(),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,133 @@
+package timeseries
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func Test_Resample(t *testing.T) {
+	cluster := fake.New(epoch)
+	account := types.Account{
+		ID:     types.ID(),
+		Ledger: 1,
+		Code:   1,
+		Flags:  types.AccountFlags{History: true}.ToUint16(),
+	}
+	counterparty := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+	results, err := cluster.CreateAccounts([]types.Account{account, counterparty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+
+	transfer := func(debit, credit types.Uint128, amount uint64, pending bool) {
+		results, err := cluster.CreateTransfers([]types.Transfer{{
+			ID:              types.ID(),
+			DebitAccountID:  debit,
+			CreditAccountID: credit,
+			Amount:          types.ToUint128(amount),
+			Ledger:          1,
+			Code:            1,
+			Flags:           types.TransferFlags{Pending: pending}.ToUint16(),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, results)
+	}
+
+	// Before the range, carried into the first bucket:
+	cluster.Advance(30 * time.Minute)
+	transfer(counterparty.ID, account.ID, 100, false)
+
+	// Hour 1: two credits and a debit.
+	cluster.Advance(time.Hour)
+	transfer(counterparty.ID, account.ID, 50, false)
+	cluster.Advance(time.Minute)
+	transfer(account.ID, counterparty.ID, 120, false)
+	cluster.Advance(time.Minute)
+	transfer(counterparty.ID, account.ID, 10, true)
+
+	// Hour 2: nothing.
+	// Hour 3: one debit.
+	cluster.Advance(2 * time.Hour)
+	transfer(account.ID, counterparty.ID, 5, false)
+
+	from := epoch.Add(time.Hour)
+	to := epoch.Add(4 * time.Hour)
+	buckets, err := Resample(cluster, account.ID, from, to, time.Hour, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, buckets, 3)
+
+	check := func(r Range, open, close, min, max string) {
+		t.Helper()
+		assert.Equal(t, open, r.Open.String())
+		assert.Equal(t, close, r.Close.String())
+		assert.Equal(t, min, r.Min.String())
+		assert.Equal(t, max, r.Max.String())
+	}
+
+	assert.Equal(t, from, buckets[0].Start)
+	assert.Equal(t, 3, buckets[0].Observations)
+	check(buckets[0].Posted, "100", "30", "30", "150")
+	check(buckets[0].Pending, "0", "10", "0", "10")
+
+	assert.Equal(t, 0, buckets[1].Observations)
+	check(buckets[1].Posted, "30", "30", "30", "30")
+	check(buckets[1].Pending, "10", "10", "10", "10")
+
+	assert.Equal(t, 1, buckets[2].Observations)
+	check(buckets[2].Posted, "30", "25", "25", "30")
+	assert.Equal(t, to, buckets[2].End)
+
+	// Debit-normal accounts flip the sign.
+	buckets, err = Resample(cluster, account.ID, from, to, time.Hour, Options{Flags: types.AccountFlagCreditsMustNotExceedDebits})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(buckets[2].Posted, "-30", "-25", "-30", "-25")
+
+	var buffer bytes.Buffer
+	if err := WriteCSV(&buffer, buckets[:1]); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, strings.Join(csvHeader, ","), lines[0])
+	assert.Equal(t, "2024-01-01T01:00:00Z,2024-01-01T02:00:00Z,3,-100,-30,-150,-30,0,-10,-10,0", lines[1])
+}
+
+func Test_ResampleBalances_Partial(t *testing.T) {
+	balances := []types.AccountBalance{
+		{CreditsPosted: types.ToUint128(7), Timestamp: uint64(epoch.Add(90 * time.Minute).UnixNano())},
+	}
+	buckets, err := ResampleBalances(nil, balances, epoch, epoch.Add(100*time.Minute), time.Hour, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, buckets, 2)
+	assert.Equal(t, types.Net{}, buckets[0].Posted.Close)
+	assert.Equal(t, epoch.Add(100*time.Minute), buckets[1].End)
+	assert.Equal(t, types.Net{Magnitude: types.ToUint128(7)}, buckets[1].Posted.Close)
+}
+
+func Test_ResampleBalances_Invalid(t *testing.T) {
+	_, err := ResampleBalances(nil, nil, epoch, epoch, time.Hour, Options{})
+	assert.Equal(t, ErrInvalidRange{}, err)
+
+	_, err = ResampleBalances(nil, nil, epoch, epoch.Add(time.Hour), 0, Options{})
+	assert.Equal(t, ErrInvalidRange{}, err)
+
+	_, err = ResampleBalances(nil, nil, epoch, epoch.Add(time.Hour), time.Nanosecond, Options{})
+	assert.Equal(t, ErrTooManyBuckets{}, err)
+}
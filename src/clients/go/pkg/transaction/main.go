@@ -0,0 +1,300 @@
+// Package transaction groups the legs of a business transaction under one ID.
+//
+// A transaction is a chain of linked transfers, created atomically. The ID of each leg is derived
+// from the transaction's ID and the leg's index (see LegID), so the legs of a transaction can be
+// looked up from its ID alone, without scanning the accounts involved, and creating the same
+// transaction again is idempotent.
+//
+// The legs of a pending transaction are posted or voided together, by another transaction whose
+// ID is derived from the first (see PostID and VoidID), so that retries are idempotent too.
+package transaction
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Client is the subset of the TigerBeetle client used by transactions.
+type Client interface {
+	CreateTransfers(transfers []types.Transfer) ([]types.TransferEventResult, error)
+	LookupTransfers(transferIDs []types.Uint128) ([]types.Transfer, error)
+}
+
+// batchMax is the maximum number of events per request, and so of legs per transaction.
+const batchMax = 8190
+
+// lookupMin is the number of leg IDs looked up by the first request of LookupTransaction. Each
+// further request looks up twice as many, up to batchMax.
+const lookupMin = 64
+
+type ErrInvalidTransaction struct {
+	ID     types.Uint128
+	Reason string
+}
+
+func (e ErrInvalidTransaction) Error() string {
+	return fmt.Sprintf("Invalid transaction %s: %s.", e.ID.DecimalString(), e.Reason)
+}
+
+type ErrNotFound struct {
+	ID types.Uint128
+}
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("Transaction %s not found.", e.ID.DecimalString())
+}
+
+// ErrNotPending is returned when posting or voiding a transaction with a leg that is not pending.
+type ErrNotPending struct {
+	ID    types.Uint128
+	Index uint32
+}
+
+func (e ErrNotPending) Error() string {
+	return fmt.Sprintf("Leg %d of transaction %s is not pending.", e.Index, e.ID.DecimalString())
+}
+
+// ErrTransferFailed is returned when the cluster rejects a leg, and so the whole transaction.
+type ErrTransferFailed struct {
+	ID     types.Uint128
+	Index  uint32
+	Result types.CreateTransferResult
+}
+
+func (e ErrTransferFailed) Error() string {
+	return fmt.Sprintf("Transaction %s failed at leg %d: %s.", e.ID.DecimalString(), e.Index, e.Result)
+}
+
+// ErrConflict is returned when creating a transaction that already exists with different legs.
+// The cluster only reports that the first leg exists, so the legs are looked up and compared.
+type ErrConflict struct {
+	ID types.Uint128
+	// The first leg that differs, and its differing fields. A leg that only exists on one side
+	// has no fields.
+	Index  uint32
+	Fields []types.FieldDiff
+}
+
+func (e ErrConflict) Error() string {
+	return fmt.Sprintf("Transaction %s exists with a different leg %d.", e.ID.DecimalString(), e.Index)
+}
+
+// LegID returns the transfer ID of the n-th leg (from zero) of a transaction.
+func LegID(transactionID types.Uint128, n uint32) types.Uint128 {
+	return types.UUIDv8(transactionID, []byte("leg/"+strconv.FormatUint(uint64(n), 10)))
+}
+
+// PostID returns the ID of the transaction that posts a pending transaction.
+func PostID(transactionID types.Uint128) types.Uint128 {
+	return types.UUIDv8(transactionID, []byte("post"))
+}
+
+// VoidID returns the ID of the transaction that voids a pending transaction.
+func VoidID(transactionID types.Uint128) types.Uint128 {
+	return types.UUIDv8(transactionID, []byte("void"))
+}
+
+// Transactions creates, looks up, posts, and voids transactions.
+type Transactions struct {
+	client Client
+}
+
+func New(client Client) *Transactions {
+	return &Transactions{client: client}
+}
+
+// Legs returns the legs as they are created by CreateTransaction: with their IDs derived from the
+// transaction's ID, and linked in order.
+func Legs(id types.Uint128, legs []types.Transfer) ([]types.Transfer, error) {
+	return Chunk(id, 0, legs)
+}
+
+// Chunk returns the legs as Legs does, but numbered from the leg `start`. Transfers too many for
+// one transaction can be created in chunks of up to 8190 legs, each atomic on its own, with the
+// IDs that one transaction would give them.
+func Chunk(id types.Uint128, start uint32, legs []types.Transfer) ([]types.Transfer, error) {
+	if id.IsZero() {
+		return nil, ErrInvalidTransaction{ID: id, Reason: "ID must not be zero"}
+	}
+	if len(legs) == 0 || len(legs) > batchMax {
+		return nil, ErrInvalidTransaction{
+			ID:     id,
+			Reason: fmt.Sprintf("there must be between 1 and %d legs", batchMax),
+		}
+	}
+	linked := make([]types.Transfer, len(legs))
+	for i, leg := range legs {
+		leg.ID = LegID(id, start+uint32(i))
+		leg.Flags = types.TransferFlag(leg.Flags).With(types.TransferFlagLinked).ToUint16()
+		linked[i] = leg
+	}
+	last := &linked[len(linked)-1]
+	last.Flags = types.TransferFlag(last.Flags).Without(types.TransferFlagLinked).ToUint16()
+	return linked, nil
+}
+
+// CreateTransaction creates the legs atomically, replacing their IDs (see Legs). Creating a
+// transaction that already exists succeeds without effect, unless its legs differ, which
+// returns ErrConflict.
+func (t *Transactions) CreateTransaction(id types.Uint128, legs []types.Transfer) error {
+	linked, err := Legs(id, legs)
+	if err != nil {
+		return err
+	}
+	return t.create(id, linked)
+}
+
+func (t *Transactions) create(id types.Uint128, legs []types.Transfer) error {
+	results, err := t.client.CreateTransfers(legs)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		switch {
+		case result.Result == types.TransferLinkedEventFailed:
+			continue
+		case result.Index == 0 && (result.Result == types.TransferExists ||
+			result.Result.ExistsWithDifferent()):
+			// The other legs fail with `TransferLinkedEventFailed`, whether or not they match.
+			return t.Verify(id, legs)
+		}
+		return ErrTransferFailed{ID: id, Index: result.Index, Result: result.Result}
+	}
+	return nil
+}
+
+// Verify compares the legs, as returned by Legs, with those of the existing transaction. It returns
+// ErrConflict if they differ, and ErrNotFound if the transaction does not exist.
+func (t *Transactions) Verify(id types.Uint128, legs []types.Transfer) error {
+	stored, err := t.LookupTransaction(id)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(legs) || i < len(stored); i++ {
+		if i >= len(legs) || i >= len(stored) {
+			return ErrConflict{ID: id, Index: uint32(i)}
+		}
+		if fields := types.DiffTransfers(legs[i], stored[i]); len(fields) > 0 {
+			return ErrConflict{ID: id, Index: uint32(i), Fields: fields}
+		}
+	}
+	return nil
+}
+
+// VerifyChunk compares the legs of a chunk, as returned by Chunk, with the legs of the existing
+// transaction that have the same IDs, ignoring its other legs. It returns ErrConflict if they
+// differ, or if one of them does not exist.
+func (t *Transactions) VerifyChunk(id types.Uint128, start uint32, legs []types.Transfer) error {
+	ids := make([]types.Uint128, len(legs))
+	for i := range legs {
+		ids[i] = LegID(id, start+uint32(i))
+	}
+	found, err := t.client.LookupTransfers(ids)
+	if err != nil {
+		return err
+	}
+	stored := make(map[types.Uint128]types.Transfer, len(found))
+	for _, transfer := range found {
+		stored[transfer.ID] = transfer
+	}
+	for i, leg := range legs {
+		transfer, ok := stored[ids[i]]
+		if !ok {
+			return ErrConflict{ID: id, Index: start + uint32(i)}
+		}
+		if fields := types.DiffTransfers(leg, transfer); len(fields) > 0 {
+			return ErrConflict{ID: id, Index: start + uint32(i), Fields: fields}
+		}
+	}
+	return nil
+}
+
+// LookupTransaction returns the legs of a transaction, in order.
+func (t *Transactions) LookupTransaction(id types.Uint128) ([]types.Transfer, error) {
+	var legs []types.Transfer
+	for count := lookupMin; ; count *= 2 {
+		if count > batchMax {
+			count = batchMax
+		}
+		ids := make([]types.Uint128, count)
+		for i := range ids {
+			ids[i] = LegID(id, uint32(len(legs)+i))
+		}
+		found, err := t.client.LookupTransfers(ids)
+		if err != nil {
+			return nil, err
+		}
+		// Legs are created together, so the first missing ID ends the transaction.
+		for i, transfer := range found {
+			if transfer.ID != ids[i] {
+				return legs, notFound(id, legs)
+			}
+			legs = append(legs, transfer)
+		}
+		if len(found) < count {
+			return legs, notFound(id, legs)
+		}
+	}
+}
+
+// notFound returns ErrNotFound if the transaction has no legs.
+func notFound(id types.Uint128, legs []types.Transfer) error {
+	if len(legs) == 0 {
+		return ErrNotFound{ID: id}
+	}
+	return nil
+}
+
+// PostTransaction posts the full amount of every leg of a pending transaction atomically, and
+// returns the ID of the posting transaction (see PostID).
+func (t *Transactions) PostTransaction(id types.Uint128) (types.Uint128, error) {
+	return t.resolve(id, PostID(id), types.TransferFlagPostPendingTransfer)
+}
+
+// VoidTransaction voids every leg of a pending transaction atomically, and returns the ID of the
+// voiding transaction (see VoidID).
+func (t *Transactions) VoidTransaction(id types.Uint128) (types.Uint128, error) {
+	return t.resolve(id, VoidID(id), types.TransferFlagVoidPendingTransfer)
+}
+
+func (t *Transactions) resolve(
+	id types.Uint128,
+	resolutionID types.Uint128,
+	flag types.TransferFlag,
+) (types.Uint128, error) {
+	pending, err := t.LookupTransaction(id)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	legs, err := Resolutions(id, pending, flag)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	linked, err := Legs(resolutionID, legs)
+	if err != nil {
+		return types.Uint128{}, err
+	}
+	if err := t.create(resolutionID, linked); err != nil {
+		return types.Uint128{}, err
+	}
+	return resolutionID, nil
+}
+
+// Resolutions returns the transfers that post or void (per `flag`) the full amount of each pending
+// leg of the transaction `id`, without their IDs (see Legs and Chunk).
+func Resolutions(id types.Uint128, pending []types.Transfer, flag types.TransferFlag) ([]types.Transfer, error) {
+	legs := make([]types.Transfer, len(pending))
+	for i, leg := range pending {
+		if !types.TransferFlag(leg.Flags).Has(types.TransferFlagPending) {
+			return nil, ErrNotPending{ID: id, Index: uint32(i)}
+		}
+		legs[i] = types.Transfer{
+			PendingID: leg.ID,
+			Amount:    leg.Amount,
+			Flags:     flag.ToUint16(),
+		}
+	}
+	return legs, nil
+}
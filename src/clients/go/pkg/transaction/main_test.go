@@ -0,0 +1,217 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/internal/fake"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func setup(t *testing.T) *fake.Cluster {
+	cluster := fake.New(time.Unix(1_700_000_000, 0))
+	results, err := cluster.CreateAccounts([]types.Account{
+		{ID: types.ToUint128(1), Ledger: 1, Code: 1},
+		{ID: types.ToUint128(2), Ledger: 1, Code: 1},
+		{ID: types.ToUint128(3), Ledger: 1, Code: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, results)
+	return cluster
+}
+
+func legs(flags types.TransferFlag) []types.Transfer {
+	leg := func(debit uint64, credit uint64, amount uint64) types.Transfer {
+		return types.Transfer{
+			DebitAccountID:  types.ToUint128(debit),
+			CreditAccountID: types.ToUint128(credit),
+			Amount:          types.ToUint128(amount),
+			Ledger:          1,
+			Code:            1,
+			Flags:           flags.ToUint16(),
+		}
+	}
+	return []types.Transfer{leg(1, 2, 100), leg(2, 3, 98)}
+}
+
+func Test_LookupTransaction(t *testing.T) {
+	cluster := setup(t)
+	transactions := New(cluster)
+	id := types.ToUint128(1000)
+
+	err := transactions.CreateTransaction(id, legs(0))
+	assert.Equal(t, nil, err)
+	// Creating the transaction again is idempotent.
+	err = transactions.CreateTransaction(id, legs(0))
+	assert.Equal(t, nil, err)
+
+	// Creating it again with different legs conflicts, even if only a later leg differs.
+	different := legs(0)
+	different[1].Amount = types.ToUint128(99)
+	err = transactions.CreateTransaction(id, different)
+	conflict, ok := err.(ErrConflict)
+	assert.True(t, ok)
+	assert.Equal(t, id, conflict.ID)
+	assert.Equal(t, uint32(1), conflict.Index)
+	assert.Equal(t, []types.FieldDiff{{Field: "amount", Submitted: "99", Stored: "98"}}, conflict.Fields)
+	// An extra leg links the last one, which then differs by its flags.
+	err = transactions.CreateTransaction(id, append(legs(0), legs(0)[0]))
+	conflict, ok = err.(ErrConflict)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), conflict.Index)
+	assert.Equal(t, "flags", conflict.Fields[0].Field)
+
+	found, err := transactions.LookupTransaction(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, found, 2)
+	assert.Equal(t, LegID(id, 0), found[0].ID)
+	assert.Equal(t, LegID(id, 1), found[1].ID)
+	assert.Equal(t, types.TransferFlagLinked.ToUint16(), found[0].Flags)
+	assert.Equal(t, uint16(0), found[1].Flags)
+	assert.Equal(t, types.ToUint128(98), found[1].Amount)
+
+	// A chunk is compared with the legs of the same indexes only.
+	chunk, err := Chunk(id, 1, legs(0)[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, nil, transactions.VerifyChunk(id, 1, chunk))
+	chunk[0].Amount = types.ToUint128(99)
+	err = transactions.VerifyChunk(id, 1, chunk)
+	assert.Equal(t, ErrConflict{
+		ID:     id,
+		Index:  1,
+		Fields: []types.FieldDiff{{Field: "amount", Submitted: "99", Stored: "98"}},
+	}, err)
+	err = transactions.VerifyChunk(id, 2, chunk)
+	assert.Equal(t, ErrConflict{ID: id, Index: 2}, err)
+
+	_, err = transactions.LookupTransaction(types.ToUint128(1001))
+	assert.Equal(t, ErrNotFound{ID: types.ToUint128(1001)}, err)
+
+	// A failing leg fails the transaction.
+	failing := legs(0)
+	failing[1].Ledger = 2
+	err = transactions.CreateTransaction(types.ToUint128(1002), failing)
+	assert.Equal(t, ErrTransferFailed{
+		ID:     types.ToUint128(1002),
+		Index:  1,
+		Result: types.TransferTransferMustHaveTheSameLedgerAsAccounts,
+	}, err)
+	_, err = transactions.LookupTransaction(types.ToUint128(1002))
+	assert.Equal(t, ErrNotFound{ID: types.ToUint128(1002)}, err)
+
+	// Only pending transactions can be posted or voided.
+	_, err = transactions.PostTransaction(id)
+	assert.Equal(t, ErrNotPending{ID: id, Index: 0}, err)
+
+	_, err = Legs(types.Uint128{}, legs(0))
+	_, ok = err.(ErrInvalidTransaction)
+	assert.True(t, ok)
+	_, err = Legs(id, nil)
+	_, ok = err.(ErrInvalidTransaction)
+	assert.True(t, ok)
+}
+
+// counting counts the lookups of a cluster.
+type counting struct {
+	*fake.Cluster
+	lookups int
+}
+
+func (c *counting) LookupTransfers(ids []types.Uint128) ([]types.Transfer, error) {
+	c.lookups++
+	return c.Cluster.LookupTransfers(ids)
+}
+
+func Test_LookupTransaction_Large(t *testing.T) {
+	cluster := &counting{Cluster: setup(t)}
+	transactions := New(cluster)
+	id := types.ToUint128(1000)
+
+	large := make([]types.Transfer, 1000)
+	for i := range large {
+		large[i] = legs(0)[0]
+	}
+	err := transactions.CreateTransaction(id, large)
+	assert.Equal(t, nil, err)
+
+	cluster.lookups = 0
+	found, err := transactions.LookupTransaction(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, found, 1000)
+	// 64 + 128 + 256 + 512 legs, then the last 40 of 1024.
+	assert.Equal(t, 5, cluster.lookups)
+}
+
+func Test_PostTransaction(t *testing.T) {
+	cluster := setup(t)
+	transactions := New(cluster)
+	id := types.ToUint128(1000)
+	err := transactions.CreateTransaction(id, legs(types.TransferFlagPending))
+	assert.Equal(t, nil, err)
+
+	postID, err := transactions.PostTransaction(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, PostID(id), postID)
+	// Posting again is idempotent.
+	_, err = transactions.PostTransaction(id)
+	assert.Equal(t, nil, err)
+
+	posted, err := transactions.LookupTransaction(postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, posted, 2)
+	assert.Equal(t, LegID(id, 0), posted[0].PendingID)
+	assert.Equal(t, LegID(id, 1), posted[1].PendingID)
+
+	accounts, err := cluster.LookupAccounts([]types.Uint128{types.ToUint128(1), types.ToUint128(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ToUint128(100), accounts[0].DebitsPosted)
+	assert.Equal(t, types.Uint128{}, accounts[0].DebitsPending)
+	assert.Equal(t, types.ToUint128(98), accounts[1].CreditsPosted)
+
+	// A posted transaction can no longer be voided.
+	_, err = transactions.VoidTransaction(id)
+	assert.Equal(t, ErrTransferFailed{
+		ID:     VoidID(id),
+		Index:  0,
+		Result: types.TransferPendingTransferAlreadyPosted,
+	}, err)
+}
+
+func Test_VoidTransaction(t *testing.T) {
+	cluster := setup(t)
+	transactions := New(cluster)
+	id := types.ToUint128(1000)
+	err := transactions.CreateTransaction(id, legs(types.TransferFlagPending))
+	assert.Equal(t, nil, err)
+
+	voidID, err := transactions.VoidTransaction(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, VoidID(id), voidID)
+
+	accounts, err := cluster.LookupAccounts([]types.Uint128{types.ToUint128(1), types.ToUint128(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, account := range accounts {
+		assert.Equal(t, types.Uint128{}, account.DebitsPending)
+		assert.Equal(t, types.Uint128{}, account.CreditsPending)
+		assert.Equal(t, types.Uint128{}, account.DebitsPosted)
+	}
+}
@@ -0,0 +1,119 @@
+package types
+
+import (
+	"math"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+)
+
+// Validate checks the filter the same way the cluster does. The cluster does not report invalid
+// filters, it returns no results instead.
+func (o AccountFilter) Validate() error {
+	flags := o.AccountFilterFlags()
+	switch {
+	case o.AccountID == Uint128{}:
+		return errors.ErrInvalidAccountFilter{Reason: "account id must not be zero"}
+	case o.AccountID == maxUint128():
+		return errors.ErrInvalidAccountFilter{Reason: "account id must not be int max"}
+	case o.TimestampMin == math.MaxUint64:
+		return errors.ErrInvalidAccountFilter{Reason: "timestamp min must not be int max"}
+	case o.TimestampMax == math.MaxUint64:
+		return errors.ErrInvalidAccountFilter{Reason: "timestamp max must not be int max"}
+	case o.TimestampMax != 0 && o.TimestampMin > o.TimestampMax:
+		return errors.ErrInvalidAccountFilter{Reason: "timestamp min must not exceed timestamp max"}
+	case o.Limit == 0:
+		return errors.ErrInvalidAccountFilter{Reason: "limit must not be zero"}
+	case !flags.Debits && !flags.Credits:
+		return errors.ErrInvalidAccountFilter{Reason: "debits or credits must be set"}
+	case o.Flags&^AccountFilterFlags{Debits: true, Credits: true, Reversed: true}.ToUint32() != 0:
+		return errors.ErrInvalidAccountFilter{Reason: "reserved flags must be zero"}
+	case o.Reserved != [24]uint8{}:
+		return errors.ErrInvalidAccountFilter{Reason: "reserved must be zero"}
+	}
+	return nil
+}
+
+// AccountFilterBuilder builds a validated AccountFilter:
+//
+//	filter, err := types.NewAccountFilter(accountID).
+//		Debits().
+//		Credits().
+//		TimeMin(from).
+//		Limit(100).
+//		Build()
+type AccountFilterBuilder struct {
+	filter AccountFilter
+	flags  AccountFilterFlags
+	err    error
+}
+
+func NewAccountFilter(accountID Uint128) *AccountFilterBuilder {
+	return &AccountFilterBuilder{
+		filter: AccountFilter{AccountID: accountID},
+	}
+}
+
+// Debits includes transfers where the account is the debit account.
+func (b *AccountFilterBuilder) Debits() *AccountFilterBuilder {
+	b.flags.Debits = true
+	return b
+}
+
+// Credits includes transfers where the account is the credit account.
+func (b *AccountFilterBuilder) Credits() *AccountFilterBuilder {
+	b.flags.Credits = true
+	return b
+}
+
+// Reversed returns results in descending timestamp order.
+func (b *AccountFilterBuilder) Reversed() *AccountFilterBuilder {
+	b.flags.Reversed = true
+	return b
+}
+
+// TimeMin sets the inclusive lower bound.
+func (b *AccountFilterBuilder) TimeMin(t time.Time) *AccountFilterBuilder {
+	if err := b.filter.SetTimestampMin(t); err != nil && b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// TimeMax sets the inclusive upper bound.
+func (b *AccountFilterBuilder) TimeMax(t time.Time) *AccountFilterBuilder {
+	if err := b.filter.SetTimestampMax(t); err != nil && b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// TimestampMin sets the inclusive lower bound as a raw timestamp.
+func (b *AccountFilterBuilder) TimestampMin(timestamp uint64) *AccountFilterBuilder {
+	b.filter.TimestampMin = timestamp
+	return b
+}
+
+// TimestampMax sets the inclusive upper bound as a raw timestamp.
+func (b *AccountFilterBuilder) TimestampMax(timestamp uint64) *AccountFilterBuilder {
+	b.filter.TimestampMax = timestamp
+	return b
+}
+
+func (b *AccountFilterBuilder) Limit(limit uint32) *AccountFilterBuilder {
+	b.filter.Limit = limit
+	return b
+}
+
+// Build returns the filter, or the first error encountered while building or validating it.
+func (b *AccountFilterBuilder) Build() (AccountFilter, error) {
+	if b.err != nil {
+		return AccountFilter{}, b.err
+	}
+	filter := b.filter
+	filter.Flags = b.flags.ToUint32()
+	if err := filter.Validate(); err != nil {
+		return AccountFilter{}, err
+	}
+	return filter, nil
+}
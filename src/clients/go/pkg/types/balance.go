@@ -0,0 +1,230 @@
+package types
+
+import (
+	"math"
+	"math/big"
+)
+
+// Net is a signed difference of two Uint128 amounts, as a sign and a magnitude, since it may not
+// fit in a signed 128-bit integer.
+type Net struct {
+	Negative  bool
+	Magnitude Uint128
+}
+
+func netOf(plus Uint128, minus Uint128) Net {
+	if difference, err := plus.SubChecked(minus); err == nil {
+		return Net{Magnitude: difference}
+	}
+	return Net{Negative: true, Magnitude: minus.Sub(plus)}
+}
+
+// sub returns `n - value`, saturating the magnitude on overflow.
+func (n Net) sub(value Uint128) Net {
+	if n.Negative {
+		return Net{Negative: true, Magnitude: saturatingAdd(n.Magnitude, value)}
+	}
+	return netOf(n.Magnitude, value)
+}
+
+// Sign returns -1, 0, or +1, like [math/big.Int.Sign].
+func (n Net) Sign() int {
+	switch {
+	case n.Magnitude.IsZero():
+		return 0
+	case n.Negative:
+		return -1
+	}
+	return 1
+}
+
+// Cmp returns -1, 0, or +1 as n is less than, equal to, or greater than other, like
+// [math/big.Int.Cmp].
+func (n Net) Cmp(other Net) int {
+	sign, otherSign := n.Sign(), other.Sign()
+	switch {
+	case sign < otherSign:
+		return -1
+	case sign > otherSign:
+		return 1
+	case sign < 0:
+		return other.Magnitude.Cmp(n.Magnitude)
+	}
+	return n.Magnitude.Cmp(other.Magnitude)
+}
+
+func (n Net) BigInt() big.Int {
+	value := n.Magnitude.BigInt()
+	if n.Negative {
+		value.Neg(&value)
+	}
+	return value
+}
+
+// String returns the value in decimal, e.g. "-42".
+func (n Net) String() string {
+	if n.Sign() < 0 {
+		return "-" + n.Magnitude.DecimalString()
+	}
+	return n.Magnitude.DecimalString()
+}
+
+func saturatingAdd(a Uint128, b Uint128) Uint128 {
+	sum, err := a.AddChecked(b)
+	if err != nil {
+		return maxUint128()
+	}
+	return sum
+}
+
+func saturatingSub(a Uint128, b Uint128) Uint128 {
+	difference, err := a.SubChecked(b)
+	if err != nil {
+		return Uint128{}
+	}
+	return difference
+}
+
+func minUint128(a Uint128, b Uint128) Uint128 {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+// The balance helpers below take the account's flags, since an AccountBalance does not include
+// them. Balances are signed in the account's normal direction: debits minus credits for
+// accounts with `CreditsMustNotExceedDebits` (such as assets), and credits minus debits for
+// all other accounts (such as liabilities).
+
+func debitNormal(flags AccountFlag) bool {
+	return flags.Has(AccountFlagCreditsMustNotExceedDebits)
+}
+
+// NetPosted returns the posted balance in the account's normal direction.
+func (b AccountBalance) NetPosted(flags AccountFlag) Net {
+	if debitNormal(flags) {
+		return netOf(b.DebitsPosted, b.CreditsPosted)
+	}
+	return netOf(b.CreditsPosted, b.DebitsPosted)
+}
+
+// NetPending returns the pending balance in the account's normal direction.
+func (b AccountBalance) NetPending(flags AccountFlag) Net {
+	if debitNormal(flags) {
+		return netOf(b.DebitsPending, b.CreditsPending)
+	}
+	return netOf(b.CreditsPending, b.DebitsPending)
+}
+
+// Available returns the posted balance in the account's normal direction, less the pending
+// amounts held against it. Pending amounts in its favor are not available until posted.
+func (b AccountBalance) Available(flags AccountFlag) Net {
+	if debitNormal(flags) {
+		return b.NetPosted(flags).sub(b.CreditsPending)
+	}
+	return b.NetPosted(flags).sub(b.DebitsPending)
+}
+
+// HeadroomForDebit returns the largest amount that a transfer may debit from the account,
+// whether limited by `DebitsMustNotExceedCredits` or by overflowing the account's debits.
+func (b AccountBalance) HeadroomForDebit(flags AccountFlag) Uint128 {
+	debits := saturatingAdd(b.DebitsPosted, b.DebitsPending)
+	headroom := maxUint128().Sub(debits)
+	if flags.Has(AccountFlagDebitsMustNotExceedCredits) {
+		headroom = minUint128(headroom, saturatingSub(b.CreditsPosted, debits))
+	}
+	return headroom
+}
+
+// HeadroomForCredit returns the largest amount that a transfer may credit to the account,
+// whether limited by `CreditsMustNotExceedDebits` or by overflowing the account's credits.
+func (b AccountBalance) HeadroomForCredit(flags AccountFlag) Uint128 {
+	credits := saturatingAdd(b.CreditsPosted, b.CreditsPending)
+	headroom := maxUint128().Sub(credits)
+	if flags.Has(AccountFlagCreditsMustNotExceedDebits) {
+		headroom = minUint128(headroom, saturatingSub(b.DebitsPosted, credits))
+	}
+	return headroom
+}
+
+func (o Account) balance() AccountBalance {
+	return AccountBalance{
+		DebitsPending:  o.DebitsPending,
+		DebitsPosted:   o.DebitsPosted,
+		CreditsPending: o.CreditsPending,
+		CreditsPosted:  o.CreditsPosted,
+		Timestamp:      o.Timestamp,
+	}
+}
+
+// NetPosted returns the posted balance in the account's normal direction, see
+// AccountBalance.NetPosted.
+func (o Account) NetPosted() Net {
+	return o.balance().NetPosted(AccountFlag(o.Flags))
+}
+
+// NetPending returns the pending balance in the account's normal direction.
+func (o Account) NetPending() Net {
+	return o.balance().NetPending(AccountFlag(o.Flags))
+}
+
+// Available returns the posted balance less pending holds, see AccountBalance.Available.
+func (o Account) Available() Net {
+	return o.balance().Available(AccountFlag(o.Flags))
+}
+
+// HeadroomForDebit returns the largest amount that a transfer may debit from the account.
+func (o Account) HeadroomForDebit() Uint128 {
+	return o.balance().HeadroomForDebit(AccountFlag(o.Flags))
+}
+
+// HeadroomForCredit returns the largest amount that a transfer may credit to the account.
+func (o Account) HeadroomForCredit() Uint128 {
+	return o.balance().HeadroomForCredit(AccountFlag(o.Flags))
+}
+
+// WouldExceed returns whether the transfer would fail on this account with
+// `ExceedsCredits`, `ExceedsDebits`, or an overflow, given the account's current balances.
+//
+// Posting or voiding a pending transfer only releases amounts that are already held, so neither
+// can exceed. A balancing transfer clamps its amount (or 2^64-1 if zero) to the account's
+// balance, whether or not the account has a limit, and exceeds if nothing is left; the clamped
+// amount is then checked against the limits of both accounts. When it is the other account that
+// clamps, the amount is only an upper bound, so the transfer may fit although WouldExceed
+// reports that it does not.
+func (o Account) WouldExceed(transfer Transfer) bool {
+	flags := TransferFlag(transfer.Flags)
+	if flags&(TransferFlagPostPendingTransfer|TransferFlagVoidPendingTransfer) != 0 {
+		return false
+	}
+	amount := transfer.Amount
+	if flags&(TransferFlagBalancingDebit|TransferFlagBalancingCredit) != 0 && amount.IsZero() {
+		amount = ToUint128(math.MaxUint64)
+	}
+	if transfer.DebitAccountID == o.ID {
+		if flags.Has(TransferFlagBalancingDebit) {
+			debits := saturatingAdd(o.DebitsPosted, o.DebitsPending)
+			amount = minUint128(amount, saturatingSub(o.CreditsPosted, debits))
+			if amount.IsZero() {
+				return true
+			}
+		}
+		if amount.Cmp(o.HeadroomForDebit()) > 0 {
+			return true
+		}
+	}
+	if transfer.CreditAccountID == o.ID {
+		if flags.Has(TransferFlagBalancingCredit) {
+			credits := saturatingAdd(o.CreditsPosted, o.CreditsPending)
+			amount = minUint128(amount, saturatingSub(o.DebitsPosted, credits))
+			if amount.IsZero() {
+				return true
+			}
+		}
+		if amount.Cmp(o.HeadroomForCredit()) > 0 {
+			return true
+		}
+	}
+	return false
+}
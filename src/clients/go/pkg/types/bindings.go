@@ -9,7 +9,11 @@ package types
 #include "../native/tb_client.h"
 */
 import "C"
-import "strconv"
+
+import (
+	"encoding/json"
+	"strconv"
+)
 
 type AccountFlags struct {
 	Linked                     bool
@@ -40,6 +44,100 @@ func (f AccountFlags) ToUint16() uint16 {
 	return ret
 }
 
+var accountFlagsNames = []string{
+	"linked",
+	"debits_must_not_exceed_credits",
+	"credits_must_not_exceed_debits",
+	"history",
+}
+
+func (f AccountFlags) Names() []string {
+	return flagNames(uint64(f.ToUint16()), accountFlagsNames)
+}
+
+func (f AccountFlags) MarshalText() ([]byte, error) {
+	return []byte(formatFlagNames(uint64(f.ToUint16()), accountFlagsNames)), nil
+}
+
+func (f *AccountFlags) UnmarshalText(text []byte) error {
+	flags, err := parseFlagNames("AccountFlags", string(text), accountFlagsNames)
+	if err != nil {
+		return err
+	}
+	*f = Account{Flags: uint16(flags)}.AccountFlags()
+	return nil
+}
+
+func (f AccountFlags) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Names())
+}
+
+func (f *AccountFlags) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	flags, err := flagsFromNames("AccountFlags", names, accountFlagsNames)
+	if err != nil {
+		return err
+	}
+	*f = Account{Flags: uint16(flags)}.AccountFlags()
+	return nil
+}
+
+func (f AccountFlags) MarshalBinary() ([]byte, error) {
+	return marshalBinary(f.ToUint16())
+}
+
+func (f *AccountFlags) UnmarshalBinary(data []byte) error {
+	var flags uint16
+	if err := unmarshalBinary(data, &flags); err != nil {
+		return err
+	}
+	*f = Account{Flags: flags}.AccountFlags()
+	return nil
+}
+
+type AccountFlag uint16
+
+const (
+	AccountFlagLinked                     AccountFlag = 1 << 0
+	AccountFlagDebitsMustNotExceedCredits AccountFlag = 1 << 1
+	AccountFlagCreditsMustNotExceedDebits AccountFlag = 1 << 2
+	AccountFlagHistory                    AccountFlag = 1 << 3
+)
+
+func ParseAccountFlags(s string) (AccountFlag, error) {
+	flags, err := parseFlags("AccountFlags", s, accountFlagsNames)
+	return AccountFlag(flags), err
+}
+
+func (f AccountFlag) Has(flags AccountFlag) bool {
+	return f&flags == flags
+}
+
+func (f AccountFlag) With(flags AccountFlag) AccountFlag {
+	return f | flags
+}
+
+func (f AccountFlag) Without(flags AccountFlag) AccountFlag {
+	return f &^ flags
+}
+
+func (f AccountFlag) ToUint16() uint16 {
+	return uint16(f)
+}
+
+func (f AccountFlag) String() string {
+	return formatFlags(uint64(f), accountFlagsNames)
+}
+
+func (f AccountFlag) Validate() error {
+	return validateFlags("AccountFlags", uint64(f), accountFlagsNames, []uint64{
+		uint64(AccountFlagDebitsMustNotExceedCredits | AccountFlagCreditsMustNotExceedDebits),
+	})
+}
+
 type TransferFlags struct {
 	Linked              bool
 	Pending             bool
@@ -79,6 +177,106 @@ func (f TransferFlags) ToUint16() uint16 {
 	return ret
 }
 
+var transferFlagsNames = []string{
+	"linked",
+	"pending",
+	"post_pending_transfer",
+	"void_pending_transfer",
+	"balancing_debit",
+	"balancing_credit",
+}
+
+func (f TransferFlags) Names() []string {
+	return flagNames(uint64(f.ToUint16()), transferFlagsNames)
+}
+
+func (f TransferFlags) MarshalText() ([]byte, error) {
+	return []byte(formatFlagNames(uint64(f.ToUint16()), transferFlagsNames)), nil
+}
+
+func (f *TransferFlags) UnmarshalText(text []byte) error {
+	flags, err := parseFlagNames("TransferFlags", string(text), transferFlagsNames)
+	if err != nil {
+		return err
+	}
+	*f = Transfer{Flags: uint16(flags)}.TransferFlags()
+	return nil
+}
+
+func (f TransferFlags) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Names())
+}
+
+func (f *TransferFlags) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	flags, err := flagsFromNames("TransferFlags", names, transferFlagsNames)
+	if err != nil {
+		return err
+	}
+	*f = Transfer{Flags: uint16(flags)}.TransferFlags()
+	return nil
+}
+
+func (f TransferFlags) MarshalBinary() ([]byte, error) {
+	return marshalBinary(f.ToUint16())
+}
+
+func (f *TransferFlags) UnmarshalBinary(data []byte) error {
+	var flags uint16
+	if err := unmarshalBinary(data, &flags); err != nil {
+		return err
+	}
+	*f = Transfer{Flags: flags}.TransferFlags()
+	return nil
+}
+
+type TransferFlag uint16
+
+const (
+	TransferFlagLinked              TransferFlag = 1 << 0
+	TransferFlagPending             TransferFlag = 1 << 1
+	TransferFlagPostPendingTransfer TransferFlag = 1 << 2
+	TransferFlagVoidPendingTransfer TransferFlag = 1 << 3
+	TransferFlagBalancingDebit      TransferFlag = 1 << 4
+	TransferFlagBalancingCredit     TransferFlag = 1 << 5
+)
+
+func ParseTransferFlags(s string) (TransferFlag, error) {
+	flags, err := parseFlags("TransferFlags", s, transferFlagsNames)
+	return TransferFlag(flags), err
+}
+
+func (f TransferFlag) Has(flags TransferFlag) bool {
+	return f&flags == flags
+}
+
+func (f TransferFlag) With(flags TransferFlag) TransferFlag {
+	return f | flags
+}
+
+func (f TransferFlag) Without(flags TransferFlag) TransferFlag {
+	return f &^ flags
+}
+
+func (f TransferFlag) ToUint16() uint16 {
+	return uint16(f)
+}
+
+func (f TransferFlag) String() string {
+	return formatFlags(uint64(f), transferFlagsNames)
+}
+
+func (f TransferFlag) Validate() error {
+	return validateFlags("TransferFlags", uint64(f), transferFlagsNames, []uint64{
+		uint64(TransferFlagPending | TransferFlagPostPendingTransfer | TransferFlagVoidPendingTransfer),
+		uint64(TransferFlagBalancingDebit | TransferFlagPostPendingTransfer | TransferFlagVoidPendingTransfer),
+		uint64(TransferFlagBalancingCredit | TransferFlagPostPendingTransfer | TransferFlagVoidPendingTransfer),
+	})
+}
+
 type AccountFilterFlags struct {
 	Debits   bool
 	Credits  bool
@@ -103,20 +301,110 @@ func (f AccountFilterFlags) ToUint32() uint32 {
 	return ret
 }
 
+var accountFilterFlagsNames = []string{
+	"debits",
+	"credits",
+	"reversed",
+}
+
+func (f AccountFilterFlags) Names() []string {
+	return flagNames(uint64(f.ToUint32()), accountFilterFlagsNames)
+}
+
+func (f AccountFilterFlags) MarshalText() ([]byte, error) {
+	return []byte(formatFlagNames(uint64(f.ToUint32()), accountFilterFlagsNames)), nil
+}
+
+func (f *AccountFilterFlags) UnmarshalText(text []byte) error {
+	flags, err := parseFlagNames("AccountFilterFlags", string(text), accountFilterFlagsNames)
+	if err != nil {
+		return err
+	}
+	*f = AccountFilter{Flags: uint32(flags)}.AccountFilterFlags()
+	return nil
+}
+
+func (f AccountFilterFlags) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Names())
+}
+
+func (f *AccountFilterFlags) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	flags, err := flagsFromNames("AccountFilterFlags", names, accountFilterFlagsNames)
+	if err != nil {
+		return err
+	}
+	*f = AccountFilter{Flags: uint32(flags)}.AccountFilterFlags()
+	return nil
+}
+
+func (f AccountFilterFlags) MarshalBinary() ([]byte, error) {
+	return marshalBinary(f.ToUint32())
+}
+
+func (f *AccountFilterFlags) UnmarshalBinary(data []byte) error {
+	var flags uint32
+	if err := unmarshalBinary(data, &flags); err != nil {
+		return err
+	}
+	*f = AccountFilter{Flags: flags}.AccountFilterFlags()
+	return nil
+}
+
+type AccountFilterFlag uint32
+
+const (
+	AccountFilterFlagDebits   AccountFilterFlag = 1 << 0
+	AccountFilterFlagCredits  AccountFilterFlag = 1 << 1
+	AccountFilterFlagReversed AccountFilterFlag = 1 << 2
+)
+
+func ParseAccountFilterFlags(s string) (AccountFilterFlag, error) {
+	flags, err := parseFlags("AccountFilterFlags", s, accountFilterFlagsNames)
+	return AccountFilterFlag(flags), err
+}
+
+func (f AccountFilterFlag) Has(flags AccountFilterFlag) bool {
+	return f&flags == flags
+}
+
+func (f AccountFilterFlag) With(flags AccountFilterFlag) AccountFilterFlag {
+	return f | flags
+}
+
+func (f AccountFilterFlag) Without(flags AccountFilterFlag) AccountFilterFlag {
+	return f &^ flags
+}
+
+func (f AccountFilterFlag) ToUint32() uint32 {
+	return uint32(f)
+}
+
+func (f AccountFilterFlag) String() string {
+	return formatFlags(uint64(f), accountFilterFlagsNames)
+}
+
+func (f AccountFilterFlag) Validate() error {
+	return validateFlags("AccountFilterFlags", uint64(f), accountFilterFlagsNames, nil)
+}
+
 type Account struct {
-	ID             Uint128
-	DebitsPending  Uint128
-	DebitsPosted   Uint128
-	CreditsPending Uint128
-	CreditsPosted  Uint128
-	UserData128    Uint128
-	UserData64     uint64
-	UserData32     uint32
-	Reserved       uint32
-	Ledger         uint32
-	Code           uint16
-	Flags          uint16
-	Timestamp      uint64
+	ID             Uint128 `json:"id"`
+	DebitsPending  Uint128 `json:"debits_pending"`
+	DebitsPosted   Uint128 `json:"debits_posted"`
+	CreditsPending Uint128 `json:"credits_pending"`
+	CreditsPosted  Uint128 `json:"credits_posted"`
+	UserData128    Uint128 `json:"user_data_128"`
+	UserData64     uint64  `json:"user_data_64"`
+	UserData32     uint32  `json:"user_data_32"`
+	Reserved       uint32  `json:"-"`
+	Ledger         uint32  `json:"ledger"`
+	Code           uint16  `json:"code"`
+	Flags          uint16  `json:"flags"`
+	Timestamp      uint64  `json:"timestamp"`
 }
 
 func (o Account) AccountFlags() AccountFlags {
@@ -128,20 +416,58 @@ func (o Account) AccountFlags() AccountFlags {
 	return f
 }
 
+func (o Account) MarshalJSON() ([]byte, error) {
+	type account Account
+	return json.Marshal(struct {
+		account
+		FlagNames AccountFlags `json:"flag_names"`
+	}{account(o), o.AccountFlags()})
+}
+
+func (o *Account) UnmarshalJSON(data []byte) error {
+	type account Account
+	// Decode into a zero value, so that no field of the target is kept.
+	var decoded Account
+	value := struct {
+		*account
+		FlagNames *AccountFlags `json:"flag_names"`
+	}{account: (*account)(&decoded)}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value.FlagNames != nil {
+		flags := value.FlagNames.ToUint16()
+		if decoded.Flags != 0 && decoded.Flags != flags {
+			return flagsMismatch("Account")
+		}
+		decoded.Flags = flags
+	}
+	*o = decoded
+	return nil
+}
+
+func (o Account) MarshalBinary() ([]byte, error) {
+	return marshalBinary(o)
+}
+
+func (o *Account) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, o)
+}
+
 type Transfer struct {
-	ID              Uint128
-	DebitAccountID  Uint128
-	CreditAccountID Uint128
-	Amount          Uint128
-	PendingID       Uint128
-	UserData128     Uint128
-	UserData64      uint64
-	UserData32      uint32
-	Timeout         uint32
-	Ledger          uint32
-	Code            uint16
-	Flags           uint16
-	Timestamp       uint64
+	ID              Uint128 `json:"id"`
+	DebitAccountID  Uint128 `json:"debit_account_id"`
+	CreditAccountID Uint128 `json:"credit_account_id"`
+	Amount          Uint128 `json:"amount"`
+	PendingID       Uint128 `json:"pending_id"`
+	UserData128     Uint128 `json:"user_data_128"`
+	UserData64      uint64  `json:"user_data_64"`
+	UserData32      uint32  `json:"user_data_32"`
+	Timeout         uint32  `json:"timeout"`
+	Ledger          uint32  `json:"ledger"`
+	Code            uint16  `json:"code"`
+	Flags           uint16  `json:"flags"`
+	Timestamp       uint64  `json:"timestamp"`
 }
 
 func (o Transfer) TransferFlags() TransferFlags {
@@ -155,6 +481,44 @@ func (o Transfer) TransferFlags() TransferFlags {
 	return f
 }
 
+func (o Transfer) MarshalJSON() ([]byte, error) {
+	type transfer Transfer
+	return json.Marshal(struct {
+		transfer
+		FlagNames TransferFlags `json:"flag_names"`
+	}{transfer(o), o.TransferFlags()})
+}
+
+func (o *Transfer) UnmarshalJSON(data []byte) error {
+	type transfer Transfer
+	// Decode into a zero value, so that no field of the target is kept.
+	var decoded Transfer
+	value := struct {
+		*transfer
+		FlagNames *TransferFlags `json:"flag_names"`
+	}{transfer: (*transfer)(&decoded)}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value.FlagNames != nil {
+		flags := value.FlagNames.ToUint16()
+		if decoded.Flags != 0 && decoded.Flags != flags {
+			return flagsMismatch("Transfer")
+		}
+		decoded.Flags = flags
+	}
+	*o = decoded
+	return nil
+}
+
+func (o Transfer) MarshalBinary() ([]byte, error) {
+	return marshalBinary(o)
+}
+
+func (o *Transfer) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, o)
+}
+
 type CreateAccountResult uint32
 
 const (
@@ -232,6 +596,77 @@ func (i CreateAccountResult) String() string {
 	return "CreateAccountResult(" + strconv.FormatInt(int64(i+1), 10) + ")"
 }
 
+func ParseCreateAccountResult(s string) (CreateAccountResult, error) {
+	switch s {
+	case "AccountOK":
+		return AccountOK, nil
+	case "AccountLinkedEventFailed":
+		return AccountLinkedEventFailed, nil
+	case "AccountLinkedEventChainOpen":
+		return AccountLinkedEventChainOpen, nil
+	case "AccountTimestampMustBeZero":
+		return AccountTimestampMustBeZero, nil
+	case "AccountReservedField":
+		return AccountReservedField, nil
+	case "AccountReservedFlag":
+		return AccountReservedFlag, nil
+	case "AccountIDMustNotBeZero":
+		return AccountIDMustNotBeZero, nil
+	case "AccountIDMustNotBeIntMax":
+		return AccountIDMustNotBeIntMax, nil
+	case "AccountFlagsAreMutuallyExclusive":
+		return AccountFlagsAreMutuallyExclusive, nil
+	case "AccountDebitsPendingMustBeZero":
+		return AccountDebitsPendingMustBeZero, nil
+	case "AccountDebitsPostedMustBeZero":
+		return AccountDebitsPostedMustBeZero, nil
+	case "AccountCreditsPendingMustBeZero":
+		return AccountCreditsPendingMustBeZero, nil
+	case "AccountCreditsPostedMustBeZero":
+		return AccountCreditsPostedMustBeZero, nil
+	case "AccountLedgerMustNotBeZero":
+		return AccountLedgerMustNotBeZero, nil
+	case "AccountCodeMustNotBeZero":
+		return AccountCodeMustNotBeZero, nil
+	case "AccountExistsWithDifferentFlags":
+		return AccountExistsWithDifferentFlags, nil
+	case "AccountExistsWithDifferentUserData128":
+		return AccountExistsWithDifferentUserData128, nil
+	case "AccountExistsWithDifferentUserData64":
+		return AccountExistsWithDifferentUserData64, nil
+	case "AccountExistsWithDifferentUserData32":
+		return AccountExistsWithDifferentUserData32, nil
+	case "AccountExistsWithDifferentLedger":
+		return AccountExistsWithDifferentLedger, nil
+	case "AccountExistsWithDifferentCode":
+		return AccountExistsWithDifferentCode, nil
+	case "AccountExists":
+		return AccountExists, nil
+	}
+	return 0, unknownName("CreateAccountResult", s)
+}
+
+func (i CreateAccountResult) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+func (i *CreateAccountResult) UnmarshalText(text []byte) error {
+	value, err := ParseCreateAccountResult(string(text))
+	if err != nil {
+		return err
+	}
+	*i = value
+	return nil
+}
+
+func (i CreateAccountResult) MarshalBinary() ([]byte, error) {
+	return marshalBinary(i)
+}
+
+func (i *CreateAccountResult) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, i)
+}
+
 type CreateTransferResult uint32
 
 const (
@@ -411,23 +846,178 @@ func (i CreateTransferResult) String() string {
 	return "CreateTransferResult(" + strconv.FormatInt(int64(i+1), 10) + ")"
 }
 
+func ParseCreateTransferResult(s string) (CreateTransferResult, error) {
+	switch s {
+	case "TransferOK":
+		return TransferOK, nil
+	case "TransferLinkedEventFailed":
+		return TransferLinkedEventFailed, nil
+	case "TransferLinkedEventChainOpen":
+		return TransferLinkedEventChainOpen, nil
+	case "TransferTimestampMustBeZero":
+		return TransferTimestampMustBeZero, nil
+	case "TransferReservedFlag":
+		return TransferReservedFlag, nil
+	case "TransferIDMustNotBeZero":
+		return TransferIDMustNotBeZero, nil
+	case "TransferIDMustNotBeIntMax":
+		return TransferIDMustNotBeIntMax, nil
+	case "TransferFlagsAreMutuallyExclusive":
+		return TransferFlagsAreMutuallyExclusive, nil
+	case "TransferDebitAccountIDMustNotBeZero":
+		return TransferDebitAccountIDMustNotBeZero, nil
+	case "TransferDebitAccountIDMustNotBeIntMax":
+		return TransferDebitAccountIDMustNotBeIntMax, nil
+	case "TransferCreditAccountIDMustNotBeZero":
+		return TransferCreditAccountIDMustNotBeZero, nil
+	case "TransferCreditAccountIDMustNotBeIntMax":
+		return TransferCreditAccountIDMustNotBeIntMax, nil
+	case "TransferAccountsMustBeDifferent":
+		return TransferAccountsMustBeDifferent, nil
+	case "TransferPendingIDMustBeZero":
+		return TransferPendingIDMustBeZero, nil
+	case "TransferPendingIDMustNotBeZero":
+		return TransferPendingIDMustNotBeZero, nil
+	case "TransferPendingIDMustNotBeIntMax":
+		return TransferPendingIDMustNotBeIntMax, nil
+	case "TransferPendingIDMustBeDifferent":
+		return TransferPendingIDMustBeDifferent, nil
+	case "TransferTimeoutReservedForPendingTransfer":
+		return TransferTimeoutReservedForPendingTransfer, nil
+	case "TransferAmountMustNotBeZero":
+		return TransferAmountMustNotBeZero, nil
+	case "TransferLedgerMustNotBeZero":
+		return TransferLedgerMustNotBeZero, nil
+	case "TransferCodeMustNotBeZero":
+		return TransferCodeMustNotBeZero, nil
+	case "TransferDebitAccountNotFound":
+		return TransferDebitAccountNotFound, nil
+	case "TransferCreditAccountNotFound":
+		return TransferCreditAccountNotFound, nil
+	case "TransferAccountsMustHaveTheSameLedger":
+		return TransferAccountsMustHaveTheSameLedger, nil
+	case "TransferTransferMustHaveTheSameLedgerAsAccounts":
+		return TransferTransferMustHaveTheSameLedgerAsAccounts, nil
+	case "TransferPendingTransferNotFound":
+		return TransferPendingTransferNotFound, nil
+	case "TransferPendingTransferNotPending":
+		return TransferPendingTransferNotPending, nil
+	case "TransferPendingTransferHasDifferentDebitAccountID":
+		return TransferPendingTransferHasDifferentDebitAccountID, nil
+	case "TransferPendingTransferHasDifferentCreditAccountID":
+		return TransferPendingTransferHasDifferentCreditAccountID, nil
+	case "TransferPendingTransferHasDifferentLedger":
+		return TransferPendingTransferHasDifferentLedger, nil
+	case "TransferPendingTransferHasDifferentCode":
+		return TransferPendingTransferHasDifferentCode, nil
+	case "TransferExceedsPendingTransferAmount":
+		return TransferExceedsPendingTransferAmount, nil
+	case "TransferPendingTransferHasDifferentAmount":
+		return TransferPendingTransferHasDifferentAmount, nil
+	case "TransferPendingTransferAlreadyPosted":
+		return TransferPendingTransferAlreadyPosted, nil
+	case "TransferPendingTransferAlreadyVoided":
+		return TransferPendingTransferAlreadyVoided, nil
+	case "TransferPendingTransferExpired":
+		return TransferPendingTransferExpired, nil
+	case "TransferExistsWithDifferentFlags":
+		return TransferExistsWithDifferentFlags, nil
+	case "TransferExistsWithDifferentDebitAccountID":
+		return TransferExistsWithDifferentDebitAccountID, nil
+	case "TransferExistsWithDifferentCreditAccountID":
+		return TransferExistsWithDifferentCreditAccountID, nil
+	case "TransferExistsWithDifferentAmount":
+		return TransferExistsWithDifferentAmount, nil
+	case "TransferExistsWithDifferentPendingID":
+		return TransferExistsWithDifferentPendingID, nil
+	case "TransferExistsWithDifferentUserData128":
+		return TransferExistsWithDifferentUserData128, nil
+	case "TransferExistsWithDifferentUserData64":
+		return TransferExistsWithDifferentUserData64, nil
+	case "TransferExistsWithDifferentUserData32":
+		return TransferExistsWithDifferentUserData32, nil
+	case "TransferExistsWithDifferentTimeout":
+		return TransferExistsWithDifferentTimeout, nil
+	case "TransferExistsWithDifferentCode":
+		return TransferExistsWithDifferentCode, nil
+	case "TransferExists":
+		return TransferExists, nil
+	case "TransferOverflowsDebitsPending":
+		return TransferOverflowsDebitsPending, nil
+	case "TransferOverflowsCreditsPending":
+		return TransferOverflowsCreditsPending, nil
+	case "TransferOverflowsDebitsPosted":
+		return TransferOverflowsDebitsPosted, nil
+	case "TransferOverflowsCreditsPosted":
+		return TransferOverflowsCreditsPosted, nil
+	case "TransferOverflowsDebits":
+		return TransferOverflowsDebits, nil
+	case "TransferOverflowsCredits":
+		return TransferOverflowsCredits, nil
+	case "TransferOverflowsTimeout":
+		return TransferOverflowsTimeout, nil
+	case "TransferExceedsCredits":
+		return TransferExceedsCredits, nil
+	case "TransferExceedsDebits":
+		return TransferExceedsDebits, nil
+	}
+	return 0, unknownName("CreateTransferResult", s)
+}
+
+func (i CreateTransferResult) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+func (i *CreateTransferResult) UnmarshalText(text []byte) error {
+	value, err := ParseCreateTransferResult(string(text))
+	if err != nil {
+		return err
+	}
+	*i = value
+	return nil
+}
+
+func (i CreateTransferResult) MarshalBinary() ([]byte, error) {
+	return marshalBinary(i)
+}
+
+func (i *CreateTransferResult) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, i)
+}
+
 type AccountEventResult struct {
-	Index  uint32
-	Result CreateAccountResult
+	Index  uint32              `json:"index"`
+	Result CreateAccountResult `json:"result"`
+}
+
+func (o AccountEventResult) MarshalBinary() ([]byte, error) {
+	return marshalBinary(o)
+}
+
+func (o *AccountEventResult) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, o)
 }
 
 type TransferEventResult struct {
-	Index  uint32
-	Result CreateTransferResult
+	Index  uint32               `json:"index"`
+	Result CreateTransferResult `json:"result"`
+}
+
+func (o TransferEventResult) MarshalBinary() ([]byte, error) {
+	return marshalBinary(o)
+}
+
+func (o *TransferEventResult) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, o)
 }
 
 type AccountFilter struct {
-	AccountID    Uint128
-	TimestampMin uint64
-	TimestampMax uint64
-	Limit        uint32
-	Flags        uint32
-	Reserved     [24]uint8
+	AccountID    Uint128   `json:"account_id"`
+	TimestampMin uint64    `json:"timestamp_min"`
+	TimestampMax uint64    `json:"timestamp_max"`
+	Limit        uint32    `json:"limit"`
+	Flags        uint32    `json:"flags"`
+	Reserved     [24]uint8 `json:"-"`
 }
 
 func (o AccountFilter) AccountFilterFlags() AccountFilterFlags {
@@ -438,12 +1028,58 @@ func (o AccountFilter) AccountFilterFlags() AccountFilterFlags {
 	return f
 }
 
+func (o AccountFilter) MarshalJSON() ([]byte, error) {
+	type accountFilter AccountFilter
+	return json.Marshal(struct {
+		accountFilter
+		FlagNames AccountFilterFlags `json:"flag_names"`
+	}{accountFilter(o), o.AccountFilterFlags()})
+}
+
+func (o *AccountFilter) UnmarshalJSON(data []byte) error {
+	type accountFilter AccountFilter
+	// Decode into a zero value, so that no field of the target is kept.
+	var decoded AccountFilter
+	value := struct {
+		*accountFilter
+		FlagNames *AccountFilterFlags `json:"flag_names"`
+	}{accountFilter: (*accountFilter)(&decoded)}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value.FlagNames != nil {
+		flags := value.FlagNames.ToUint32()
+		if decoded.Flags != 0 && decoded.Flags != flags {
+			return flagsMismatch("AccountFilter")
+		}
+		decoded.Flags = flags
+	}
+	*o = decoded
+	return nil
+}
+
+func (o AccountFilter) MarshalBinary() ([]byte, error) {
+	return marshalBinary(o)
+}
+
+func (o *AccountFilter) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, o)
+}
+
 type AccountBalance struct {
-	DebitsPending  Uint128
-	DebitsPosted   Uint128
-	CreditsPending Uint128
-	CreditsPosted  Uint128
-	Timestamp      uint64
-	Reserved       [56]uint8
+	DebitsPending  Uint128   `json:"debits_pending"`
+	DebitsPosted   Uint128   `json:"debits_posted"`
+	CreditsPending Uint128   `json:"credits_pending"`
+	CreditsPosted  Uint128   `json:"credits_posted"`
+	Timestamp      uint64    `json:"timestamp"`
+	Reserved       [56]uint8 `json:"-"`
+}
+
+func (o AccountBalance) MarshalBinary() ([]byte, error) {
+	return marshalBinary(o)
+}
+
+func (o *AccountBalance) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, o)
 }
 
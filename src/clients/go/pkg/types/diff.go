@@ -0,0 +1,138 @@
+package types
+
+import (
+	"fmt"
+)
+
+// FieldDiff is a field that differs between a submitted event and the one stored by the cluster.
+type FieldDiff struct {
+	// The field's name, as in JSON, e.g. "user_data_64".
+	Field string
+	// The values in decimal, or as flag names such as "linked|pending".
+	Submitted string
+	Stored    string
+}
+
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: submitted %s, stored %s", d.Field, d.Submitted, d.Stored)
+}
+
+type differ struct {
+	diffs []FieldDiff
+}
+
+func (d *differ) compare(field string, submitted interface{}, stored interface{}) {
+	if submitted == stored {
+		return
+	}
+	format := func(value interface{}) string {
+		switch value := value.(type) {
+		case Uint128:
+			return value.DecimalString()
+		case fmt.Stringer:
+			return value.String()
+		}
+		return fmt.Sprint(value)
+	}
+	d.diffs = append(d.diffs, FieldDiff{
+		Field:     field,
+		Submitted: format(submitted),
+		Stored:    format(stored),
+	})
+}
+
+// DiffAccounts compares the fields of a submitted account that must match the stored account
+// for `AccountExists`: the user data, ledger, code, and flags. The balances and timestamp are
+// set by the cluster, and are ignored.
+func DiffAccounts(submitted Account, stored Account) []FieldDiff {
+	var d differ
+	d.compare("user_data_128", submitted.UserData128, stored.UserData128)
+	d.compare("user_data_64", submitted.UserData64, stored.UserData64)
+	d.compare("user_data_32", submitted.UserData32, stored.UserData32)
+	d.compare("ledger", submitted.Ledger, stored.Ledger)
+	d.compare("code", submitted.Code, stored.Code)
+	d.compare("flags", AccountFlag(submitted.Flags), AccountFlag(stored.Flags))
+	return d.diffs
+}
+
+// DiffTransfers compares the fields of a submitted transfer that must match the stored transfer
+// for `TransferExists`. The timestamp is set by the cluster, and is ignored.
+//
+// Transfers that post or void a pending transfer may leave fields zero to inherit them from the
+// pending transfer, so those fields are only compared when they are set.
+func DiffTransfers(submitted Transfer, stored Transfer) []FieldDiff {
+	flags := submitted.TransferFlags()
+	inherits := flags.PostPendingTransfer || flags.VoidPendingTransfer
+	var d differ
+	compare := func(field string, submitted interface{}, stored interface{}, zero interface{}) {
+		if inherits && submitted == zero {
+			return
+		}
+		d.compare(field, submitted, stored)
+	}
+	compare("debit_account_id", submitted.DebitAccountID, stored.DebitAccountID, Uint128{})
+	compare("credit_account_id", submitted.CreditAccountID, stored.CreditAccountID, Uint128{})
+	compare("amount", submitted.Amount, stored.Amount, Uint128{})
+	d.compare("pending_id", submitted.PendingID, stored.PendingID)
+	compare("user_data_128", submitted.UserData128, stored.UserData128, Uint128{})
+	compare("user_data_64", submitted.UserData64, stored.UserData64, uint64(0))
+	compare("user_data_32", submitted.UserData32, stored.UserData32, uint32(0))
+	d.compare("timeout", submitted.Timeout, stored.Timeout)
+	compare("ledger", submitted.Ledger, stored.Ledger, uint32(0))
+	compare("code", submitted.Code, stored.Code, uint16(0))
+	d.compare("flags", TransferFlag(submitted.Flags), TransferFlag(stored.Flags))
+	return d.diffs
+}
+
+// ExistsWithDifferent returns whether the result is one of the `AccountExistsWithDifferent*`
+// results, for an account with the same ID but different fields.
+func (r CreateAccountResult) ExistsWithDifferent() bool {
+	switch r {
+	case AccountExistsWithDifferentFlags,
+		AccountExistsWithDifferentUserData128,
+		AccountExistsWithDifferentUserData64,
+		AccountExistsWithDifferentUserData32,
+		AccountExistsWithDifferentLedger,
+		AccountExistsWithDifferentCode:
+		return true
+	}
+	return false
+}
+
+// ExistsWithDifferent returns whether the result is one of the `TransferExistsWithDifferent*`
+// results, for a transfer with the same ID but different fields.
+func (r CreateTransferResult) ExistsWithDifferent() bool {
+	switch r {
+	case TransferExistsWithDifferentFlags,
+		TransferExistsWithDifferentDebitAccountID,
+		TransferExistsWithDifferentCreditAccountID,
+		TransferExistsWithDifferentAmount,
+		TransferExistsWithDifferentPendingID,
+		TransferExistsWithDifferentUserData128,
+		TransferExistsWithDifferentUserData64,
+		TransferExistsWithDifferentUserData32,
+		TransferExistsWithDifferentTimeout,
+		TransferExistsWithDifferentCode:
+		return true
+	}
+	return false
+}
+
+// EnsureAccountResult is the outcome of an account that was neither created nor found to exist
+// as submitted.
+type EnsureAccountResult struct {
+	Index  uint32
+	Result CreateAccountResult
+	// The fields that differ from the stored account, for `AccountExistsWithDifferent*` results.
+	Diff []FieldDiff
+}
+
+// EnsureTransferResult is the outcome of a transfer that was neither created nor found to exist
+// as submitted.
+type EnsureTransferResult struct {
+	Index  uint32
+	Result CreateTransferResult
+	// The fields that differ from the stored transfer, for `TransferExistsWithDifferent*`
+	// results.
+	Diff []FieldDiff
+}
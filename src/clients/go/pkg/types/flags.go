@@ -0,0 +1,51 @@
+package types
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+)
+
+// formatFlags joins the names of the bits set in `flags` with "|", followed by any reserved bits
+// in hexadecimal, or returns "none" if no bits are set.
+func formatFlags(flags uint64, names []string) string {
+	if flags == 0 {
+		return "none"
+	}
+	text := formatFlagNames(flags, names)
+	if reserved := flags &^ (1<<len(names) - 1); reserved != 0 {
+		if text != "" {
+			text += "|"
+		}
+		text += fmt.Sprintf("%#x", reserved)
+	}
+	return text
+}
+
+// parseFlags parses names joined with "|", as formatted by formatFlags, ignoring spaces around
+// the names. Both "" and "none" parse as no flags.
+func parseFlags(typeName string, s string, names []string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "none" {
+		return 0, nil
+	}
+	return parseFlagNames(typeName, s, names)
+}
+
+// validateFlags rejects reserved bits, and more than one of the bits of each exclusive group.
+func validateFlags(typeName string, flags uint64, names []string, exclusive []uint64) error {
+	if reserved := flags &^ (1<<len(names) - 1); reserved != 0 {
+		return errors.ErrReservedFlags{Type: typeName, Flags: reserved}
+	}
+	for _, group := range exclusive {
+		if bits.OnesCount64(flags&group) > 1 {
+			return errors.ErrFlagsMutuallyExclusive{
+				Type:  typeName,
+				Flags: flagNames(flags&group, names),
+			}
+		}
+	}
+	return nil
+}
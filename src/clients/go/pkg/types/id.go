@@ -0,0 +1,149 @@
+package types
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+)
+
+// idTimestampMax is the largest millisecond timestamp that fits in the 48 bits of an ID.
+const idTimestampMax = 1<<48 - 1
+
+// IDGeneratorOptions configures an IDGenerator. Zero values select the defaults.
+type IDGeneratorOptions struct {
+	// Now returns the current time, defaulting to time.Now.
+	Now func() time.Time
+	// Entropy provides the random bits, defaulting to crypto/rand.Reader.
+	// It must be safe for concurrent use if shared with a ShardedIDGenerator.
+	Entropy io.Reader
+}
+
+// IDGenerator generates Universally Unique and Sortable Identifiers based on
+// https://github.com/ulid/spec, as ID does, but with its own state, clock, and entropy source.
+//
+// IDs from the same generator are monotonically increasing, even if the clock moves backwards.
+// It is safe to call Next from multiple goroutines, with monotonicity being sequentially consistent.
+type IDGenerator struct {
+	now     func() time.Time
+	entropy io.Reader
+
+	mutex         sync.Mutex
+	lastTimestamp int64
+	lastRandom    [10]byte
+}
+
+func NewIDGenerator(options IDGeneratorOptions) *IDGenerator {
+	generator := &IDGenerator{now: options.Now, entropy: options.Entropy}
+	if generator.now == nil {
+		generator.now = time.Now
+	}
+	if generator.entropy == nil {
+		generator.entropy = rand.Reader
+	}
+	return generator
+}
+
+// Next returns the next ID. It fails if the clock is before the UNIX epoch or beyond the 48-bit
+// millisecond range, if the entropy source fails, or if more IDs are generated within a
+// millisecond than the 80 random bits can count (only plausible with broken entropy).
+// A failed call does not affect the IDs generated after it.
+func (g *IDGenerator) Next() (Uint128, error) {
+	timestamp := g.now().UnixMilli()
+	if timestamp < 0 || timestamp > idTimestampMax {
+		return Uint128{}, errors.ErrInvalidTimestamp{}
+	}
+
+	// Ensure lastTimestamp is monotonically increasing & lastRandom changes each millisecond.
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	random := g.lastRandom
+	if timestamp <= g.lastTimestamp {
+		timestamp = g.lastTimestamp
+	} else if _, err := io.ReadFull(g.entropy, random[:]); err != nil {
+		return Uint128{}, errors.ErrIDEntropy{Err: err}
+	}
+
+	// Increment the random bits as a uint80 together, checking for overflow.
+	// Go defines unsigned arithmetic to wrap around on overflow by default so check for zero.
+	randomLo := binary.LittleEndian.Uint64(random[:8]) + 1
+	randomHi := binary.LittleEndian.Uint16(random[8:])
+	if randomLo == 0 {
+		randomHi += 1
+		if randomHi == 0 {
+			return Uint128{}, errors.ErrIDOverflow{}
+		}
+	}
+
+	g.lastTimestamp = timestamp
+	binary.LittleEndian.PutUint64(g.lastRandom[:8], randomLo)
+	binary.LittleEndian.PutUint16(g.lastRandom[8:], randomHi)
+
+	// Create Uint128 from new timestamp and random.
+	var id [16]byte
+	binary.LittleEndian.PutUint64(id[:8], randomLo)
+	binary.LittleEndian.PutUint16(id[8:], randomHi)
+	binary.LittleEndian.PutUint16(id[10:], (uint16)(timestamp))     // timestamp lo
+	binary.LittleEndian.PutUint32(id[12:], (uint32)(timestamp>>16)) // timestamp hi
+	return BytesToUint128(id), nil
+}
+
+// ShardedIDGenerator spreads ID generation over independent IDGenerators to reduce contention.
+//
+// Each ID is still unique, but IDs are only monotonically increasing within a shard: across
+// shards, IDs are ordered by their millisecond timestamp, and arbitrarily within a millisecond.
+type ShardedIDGenerator struct {
+	shards []*IDGenerator
+	next   uint64
+}
+
+// NewShardedIDGenerator creates a generator with the given number of shards, which must be
+// positive. The options are shared by all shards.
+func NewShardedIDGenerator(shards int, options IDGeneratorOptions) *ShardedIDGenerator {
+	if shards <= 0 {
+		panic("shards must be positive")
+	}
+	generator := &ShardedIDGenerator{shards: make([]*IDGenerator, shards)}
+	for i := range generator.shards {
+		generator.shards[i] = NewIDGenerator(options)
+	}
+	return generator
+}
+
+// Next returns the next ID from the next shard, in round-robin order.
+func (g *ShardedIDGenerator) Next() (Uint128, error) {
+	shard := atomic.AddUint64(&g.next, 1) % uint64(len(g.shards))
+	return g.shards[shard].Next()
+}
+
+// Shard returns the i-th shard, for callers that need monotonic IDs from a fixed shard, e.g.
+// one shard per worker.
+func (g *ShardedIDGenerator) Shard(i int) *IDGenerator {
+	return g.shards[i]
+}
+
+var idGenerator = NewIDGenerator(IDGeneratorOptions{})
+
+// Generates a Universally Unique and Sortable Identifier based on https://github.com/ulid/spec.
+// Uint128 returned are guaranteed to be monotonically increasing when interpreted as little-endian.
+// `ID()` is safe to call from multiple goroutines with monotonicity being sequentially consistent.
+// It panics if IDGenerator.Next fails, see NewIDGenerator for a generator that returns errors.
+func ID() Uint128 {
+	id, err := idGenerator.Next()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// IDTime returns the millisecond timestamp embedded in an ID generated by ID or an IDGenerator.
+// IDs have no type of their own, ID being a function, so this is a method of Uint128.
+func (value Uint128) IDTime() time.Time {
+	_, hi := value.uint64s()
+	return time.UnixMilli(int64(hi >> 16))
+}
@@ -5,14 +5,19 @@ package types
 */
 import "C"
 import (
-	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"math/big"
-	"sync"
-	"time"
+	"math/bits"
+	"strconv"
+	"strings"
 	"unsafe"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
 )
 
 type Uint128 C.tb_uint128_t
@@ -57,6 +62,103 @@ func BytesToUint128(value [16]byte) Uint128 {
 	return *(*Uint128)(unsafe.Pointer(&value[0]))
 }
 
+// BigEndianBytes returns the value as 16 bytes, most significant byte first.
+// Unlike Bytes, which returns the native little-endian layout, byte-wise ordering of the result
+// matches numeric ordering.
+func (value Uint128) BigEndianBytes() [16]byte {
+	bytes := value.Bytes()
+	swapEndian(bytes[:])
+	return bytes
+}
+
+// BigEndianBytesToUint128 converts 16 bytes, most significant byte first, to a Uint128.
+func BigEndianBytesToUint128(value [16]byte) Uint128 {
+	swapEndian(value[:])
+	return BytesToUint128(value)
+}
+
+// UUIDString formats the value as an RFC 4122 UUID, in lower case. The UUID's bytes are the
+// value's big-endian bytes, so 42 is "00000000-0000-0000-0000-00000000002a", and UUIDs compare
+// as their Uint128 values do.
+func (value Uint128) UUIDString() string {
+	bytes := value.BigEndianBytes()
+	var text [36]byte
+	hex.Encode(text[0:8], bytes[0:4])
+	text[8] = '-'
+	hex.Encode(text[9:13], bytes[4:6])
+	text[13] = '-'
+	hex.Encode(text[14:18], bytes[6:8])
+	text[18] = '-'
+	hex.Encode(text[19:23], bytes[8:10])
+	text[23] = '-'
+	hex.Encode(text[24:36], bytes[10:16])
+	return string(text[:])
+}
+
+// Uint128FromUUID parses a UUID in its canonical hyphenated form, in either case, optionally
+// wrapped in braces or prefixed with "urn:uuid:". It is the inverse of UUIDString.
+func Uint128FromUUID(value string) (Uint128, error) {
+	text := value
+	if strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}") {
+		text = text[1 : len(text)-1]
+	} else if len(text) > 9 && strings.EqualFold(text[:9], "urn:uuid:") {
+		text = text[9:]
+	}
+	if len(text) != 36 || text[8] != '-' || text[13] != '-' || text[18] != '-' || text[23] != '-' {
+		return Uint128{}, errors.ErrInvalidUUID{Value: value}
+	}
+	digits := text[0:8] + text[9:13] + text[14:18] + text[19:23] + text[24:36]
+	var bytes [16]byte
+	if _, err := hex.Decode(bytes[:], []byte(digits)); err != nil {
+		return Uint128{}, errors.ErrInvalidUUID{Value: value}
+	}
+	return BigEndianBytesToUint128(bytes), nil
+}
+
+// Namespaces for UUIDv5 and UUIDv8, from RFC 4122 appendix C.
+var (
+	UUIDNamespaceDNS  = mustUint128FromUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	UUIDNamespaceURL  = mustUint128FromUUID("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	UUIDNamespaceOID  = mustUint128FromUUID("6ba7b812-9dad-11d1-80b4-00c04fd430c8")
+	UUIDNamespaceX500 = mustUint128FromUUID("6ba7b814-9dad-11d1-80b4-00c04fd430c8")
+)
+
+func mustUint128FromUUID(value string) Uint128 {
+	id, err := Uint128FromUUID(value)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// UUIDv5 derives a name-based UUID (RFC 4122, version 5, SHA-1) from a namespace and a name,
+// such as an external business key. The same namespace and name always derive the same ID, so
+// retries can resubmit an event with the same ID. The version and variant bits ensure that the
+// result is neither zero nor `2^128 - 1`, and so always a valid ID.
+//
+// Use a distinct namespace, for example a random UUID, for each kind of external key.
+func UUIDv5(namespace Uint128, name []byte) Uint128 {
+	return derive(sha1.New(), 5, namespace, name)
+}
+
+// UUIDv8 is like UUIDv5, but hashes with SHA-256 instead of SHA-1, following the name-based
+// example of RFC 9562 for version 8.
+func UUIDv8(namespace Uint128, name []byte) Uint128 {
+	return derive(sha256.New(), 8, namespace, name)
+}
+
+func derive(hash hash.Hash, version byte, namespace Uint128, name []byte) Uint128 {
+	namespaceBytes := namespace.BigEndianBytes()
+	hash.Write(namespaceBytes[:])
+	hash.Write(name)
+
+	var bytes [16]byte
+	copy(bytes[:], hash.Sum(nil))
+	bytes[6] = (bytes[6] & 0x0f) | version<<4
+	bytes[8] = (bytes[8] & 0x3f) | 0x80
+	return BigEndianBytesToUint128(bytes)
+}
+
 // HexStringToUint128 converts a hex-encoded integer to a Uint128.
 func HexStringToUint128(value string) (Uint128, error) {
 	if len(value) > 32 {
@@ -82,6 +184,7 @@ func HexStringToUint128(value string) (Uint128, error) {
 }
 
 // BigIntToUint128 converts a [math/big.Int] to a Uint128.
+// Values wider than 128 bits are truncated and the sign is ignored, see BigIntToUint128Checked.
 func BigIntToUint128(value big.Int) Uint128 {
 	// big.Int bytes are big-endian so convert them to little-endian for Uint128 bytes.
 	bytes := value.Bytes()
@@ -97,61 +200,479 @@ func BigIntToUint128(value big.Int) Uint128 {
 	return BytesToUint128(zeroPadded)
 }
 
+// BigIntToUint128Checked converts a [math/big.Int] to a Uint128, or returns an error if the
+// value is negative or does not fit in 128 bits.
+func BigIntToUint128Checked(value big.Int) (Uint128, error) {
+	if value.Sign() < 0 {
+		return Uint128{}, errors.ErrUint128Negative{}
+	}
+	if value.BitLen() > 128 {
+		return Uint128{}, errors.ErrUint128Overflow{}
+	}
+	return BigIntToUint128(value), nil
+}
+
 // ToUint128 converts a integer to a Uint128.
 func ToUint128(value uint64) Uint128 {
 	values := [2]uint64{value, 0}
 	return *(*Uint128)(unsafe.Pointer(&values[0]))
 }
 
-var idLastTimestamp int64
-var idLastRandom [10]byte
-var idMutex sync.Mutex
-
-// Generates a Universally Unique and Sortable Identifier based on https://github.com/ulid/spec.
-// Uint128 returned are guaranteed to be monotonically increasing when interpreted as little-endian.
-// `ID()` is safe to call from multiple goroutines with monotonicity being sequentially consistent. 
-func ID() Uint128 {
-	timestamp := time.Now().UnixMilli()
-
-	// Lock the mutex for global id variables.
-	// Then ensure lastTimestamp is monotonically increasing & lastRandom changes each millisecond 
-	idMutex.Lock()
-	if timestamp <= idLastTimestamp {
-		timestamp = idLastTimestamp
-	} else {
-		idLastTimestamp = timestamp
-		_, err := rand.Read(idLastRandom[:])
+// uint64s splits a Uint128 into its low and high 64-bit halves.
+func (value Uint128) uint64s() (lo uint64, hi uint64) {
+	bytes := value.Bytes()
+	return binary.LittleEndian.Uint64(bytes[:8]), binary.LittleEndian.Uint64(bytes[8:])
+}
+
+// uint64sToUint128 joins the low and high 64-bit halves of a Uint128.
+func uint64sToUint128(lo uint64, hi uint64) Uint128 {
+	var bytes [16]byte
+	binary.LittleEndian.PutUint64(bytes[:8], lo)
+	binary.LittleEndian.PutUint64(bytes[8:], hi)
+	return BytesToUint128(bytes)
+}
+
+// IsZero reports whether the value is zero.
+func (value Uint128) IsZero() bool {
+	return value == Uint128{}
+}
+
+// Cmp compares two values and returns -1, 0, or +1, like [math/big.Int.Cmp].
+func (value Uint128) Cmp(other Uint128) int {
+	lo, hi := value.uint64s()
+	otherLo, otherHi := other.uint64s()
+	switch {
+	case hi < otherHi:
+		return -1
+	case hi > otherHi:
+		return 1
+	case lo < otherLo:
+		return -1
+	case lo > otherLo:
+		return 1
+	}
+	return 0
+}
+
+// Add returns `value + other`, wrapping around on overflow.
+func (value Uint128) Add(other Uint128) Uint128 {
+	sum, _ := value.add(other)
+	return sum
+}
+
+// AddChecked returns `value + other`, or an error on overflow.
+func (value Uint128) AddChecked(other Uint128) (Uint128, error) {
+	sum, carry := value.add(other)
+	if carry != 0 {
+		return Uint128{}, errors.ErrUint128Overflow{}
+	}
+	return sum, nil
+}
+
+func (value Uint128) add(other Uint128) (Uint128, uint64) {
+	lo, hi := value.uint64s()
+	otherLo, otherHi := other.uint64s()
+	lo, carry := bits.Add64(lo, otherLo, 0)
+	hi, carry = bits.Add64(hi, otherHi, carry)
+	return uint64sToUint128(lo, hi), carry
+}
+
+// Sub returns `value - other`, wrapping around on underflow.
+func (value Uint128) Sub(other Uint128) Uint128 {
+	difference, _ := value.sub(other)
+	return difference
+}
+
+// SubChecked returns `value - other`, or an error on underflow.
+func (value Uint128) SubChecked(other Uint128) (Uint128, error) {
+	difference, borrow := value.sub(other)
+	if borrow != 0 {
+		return Uint128{}, errors.ErrUint128Overflow{}
+	}
+	return difference, nil
+}
+
+func (value Uint128) sub(other Uint128) (Uint128, uint64) {
+	lo, hi := value.uint64s()
+	otherLo, otherHi := other.uint64s()
+	lo, borrow := bits.Sub64(lo, otherLo, 0)
+	hi, borrow = bits.Sub64(hi, otherHi, borrow)
+	return uint64sToUint128(lo, hi), borrow
+}
+
+// Mul returns `value * other`, wrapping around on overflow.
+func (value Uint128) Mul(other Uint128) Uint128 {
+	product, _ := value.mul(other)
+	return product
+}
+
+// MulChecked returns `value * other`, or an error on overflow.
+func (value Uint128) MulChecked(other Uint128) (Uint128, error) {
+	product, overflow := value.mul(other)
+	if overflow {
+		return Uint128{}, errors.ErrUint128Overflow{}
+	}
+	return product, nil
+}
+
+func (value Uint128) mul(other Uint128) (Uint128, bool) {
+	lo, hi := value.uint64s()
+	otherLo, otherHi := other.uint64s()
+
+	productHi, productLo := bits.Mul64(lo, otherLo)
+	crossHi1, crossLo1 := bits.Mul64(hi, otherLo)
+	crossHi2, crossLo2 := bits.Mul64(lo, otherHi)
+	productHi, carry1 := bits.Add64(productHi, crossLo1, 0)
+	productHi, carry2 := bits.Add64(productHi, crossLo2, 0)
+
+	overflow := (hi != 0 && otherHi != 0) ||
+		crossHi1 != 0 || crossHi2 != 0 ||
+		carry1 != 0 || carry2 != 0
+	return uint64sToUint128(productLo, productHi), overflow
+}
+
+// Div returns `value / other`, rounding towards zero. It panics if `other` is zero.
+func (value Uint128) Div(other Uint128) Uint128 {
+	quotient, _ := value.QuoRem(other)
+	return quotient
+}
+
+// DivChecked returns `value / other`, or an error if `other` is zero.
+func (value Uint128) DivChecked(other Uint128) (Uint128, error) {
+	if other.IsZero() {
+		return Uint128{}, errors.ErrUint128DivisionByZero{}
+	}
+	return value.Div(other), nil
+}
+
+// Rem returns `value % other`. It panics if `other` is zero.
+func (value Uint128) Rem(other Uint128) Uint128 {
+	_, remainder := value.QuoRem(other)
+	return remainder
+}
+
+// QuoRem returns both `value / other` and `value % other`. It panics if `other` is zero.
+func (value Uint128) QuoRem(other Uint128) (quotient Uint128, remainder Uint128) {
+	lo, hi := value.uint64s()
+	otherLo, otherHi := other.uint64s()
+
+	if otherHi == 0 {
+		if otherLo == 0 {
+			panic("Uint128: division by zero")
+		}
+		// A 128-bit dividend and a 64-bit divisor: two long division steps.
+		quotientHi, r := bits.Div64(0, hi, otherLo)
+		quotientLo, r := bits.Div64(r, lo, otherLo)
+		return uint64sToUint128(quotientLo, quotientHi), uint64sToUint128(r, 0)
+	}
+
+	// The quotient fits in 64 bits. Estimate it from the normalized divisor's top 64 bits
+	// (Hacker's Delight, section 9-5), which is either exact or one too large.
+	shift := uint(bits.LeadingZeros64(otherHi))
+	_, normalizedHi := other.Lsh(shift).uint64s()
+	dividendLo, dividendHi := value.Rsh(1).uint64s()
+	estimate, _ := bits.Div64(dividendHi, dividendLo, normalizedHi)
+	estimate >>= 63 - shift
+	if estimate != 0 {
+		estimate--
+	}
+
+	quotient = uint64sToUint128(estimate, 0)
+	remainder = value.Sub(other.Mul(quotient))
+	if remainder.Cmp(other) >= 0 {
+		quotient = quotient.Add(ToUint128(1))
+		remainder = remainder.Sub(other)
+	}
+	return quotient, remainder
+}
+
+// Lsh returns `value << shift`, discarding the bits shifted out.
+func (value Uint128) Lsh(shift uint) Uint128 {
+	lo, hi := value.uint64s()
+	switch {
+	case shift >= 128:
+		return Uint128{}
+	case shift >= 64:
+		return uint64sToUint128(0, lo<<(shift-64))
+	}
+	return uint64sToUint128(lo<<shift, hi<<shift|lo>>(64-shift))
+}
+
+// LshChecked returns `value << shift`, or an error if any set bit would be shifted out.
+func (value Uint128) LshChecked(shift uint) (Uint128, error) {
+	if value.IsZero() {
+		return value, nil
+	}
+	if shift >= 128 || value.Rsh(128-shift) != (Uint128{}) {
+		return Uint128{}, errors.ErrUint128Overflow{}
+	}
+	return value.Lsh(shift), nil
+}
+
+// Rsh returns `value >> shift`.
+// There is no checked variant, since a right shift cannot overflow.
+func (value Uint128) Rsh(shift uint) Uint128 {
+	lo, hi := value.uint64s()
+	switch {
+	case shift >= 128:
+		return Uint128{}
+	case shift >= 64:
+		return uint64sToUint128(hi>>(shift-64), 0)
+	}
+	return uint64sToUint128(lo>>shift|hi<<(64-shift), hi>>shift)
+}
+
+// ParseUint128 interprets a string in the given base (0, or 2 to 36) and returns the
+// corresponding value, following the same rules as [strconv.ParseUint]: for base 0 the base is
+// implied by the prefix ("0b", "0o" or "0", "0x", otherwise 10) and underscores may separate
+// digits. Errors are of type [*strconv.NumError].
+func ParseUint128(s string, base int) (Uint128, error) {
+	const fn = "ParseUint128"
+	syntaxError := &strconv.NumError{Func: fn, Num: s, Err: strconv.ErrSyntax}
+	if s == "" {
+		return Uint128{}, syntaxError
+	}
+
+	original := s
+	underscores := false
+	switch {
+	case base == 0:
+		base = 10
+		if s[0] == '0' {
+			switch {
+			case len(s) >= 3 && lower(s[1]) == 'b':
+				base = 2
+				s = s[2:]
+			case len(s) >= 3 && lower(s[1]) == 'o':
+				base = 8
+				s = s[2:]
+			case len(s) >= 3 && lower(s[1]) == 'x':
+				base = 16
+				s = s[2:]
+			default:
+				base = 8
+				s = s[1:]
+			}
+		}
+		underscores = true
+	case base < 2 || base > 36:
+		return Uint128{}, &strconv.NumError{
+			Func: fn,
+			Num:  original,
+			Err:  fmt.Errorf("invalid base %d", base),
+		}
+	}
+
+	value := Uint128{}
+	radix := ToUint128(uint64(base))
+	sawUnderscore := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		var digit byte
+		switch {
+		case c == '_' && underscores:
+			sawUnderscore = true
+			continue
+		case '0' <= c && c <= '9':
+			digit = c - '0'
+		case 'a' <= lower(c) && lower(c) <= 'z':
+			digit = lower(c) - 'a' + 10
+		default:
+			return Uint128{}, syntaxError
+		}
+		if int(digit) >= base {
+			return Uint128{}, syntaxError
+		}
+
+		var err error
+		if value, err = value.MulChecked(radix); err == nil {
+			value, err = value.AddChecked(ToUint128(uint64(digit)))
+		}
 		if err != nil {
-			idMutex.Unlock()
-			panic("crypto.rand failed to provide random bytes")
+			return maxUint128(), &strconv.NumError{Func: fn, Num: original, Err: strconv.ErrRange}
+		}
+	}
+
+	if sawUnderscore && !underscoreOK(original) {
+		return Uint128{}, syntaxError
+	}
+	return value, nil
+}
+
+func lower(c byte) byte {
+	return c | ('x' - 'X')
+}
+
+// underscoreOK reports whether the underscores in s are allowed, as in [strconv.ParseUint]:
+// an underscore must appear only between digits or between a base prefix and a digit.
+func underscoreOK(s string) bool {
+	// saw tracks the last character (class) we saw:
+	// ^ for beginning of number,
+	// 0 for a digit or base prefix,
+	// _ for an underscore,
+	// ! for none of the above.
+	saw := '^'
+	i := 0
+
+	hex := false
+	if len(s) >= 2 && s[0] == '0' && (lower(s[1]) == 'b' || lower(s[1]) == 'o' || lower(s[1]) == 'x') {
+		i = 2
+		saw = '0' // The base prefix counts as a digit for "underscore as digit separator".
+		hex = lower(s[1]) == 'x'
+	}
+
+	for ; i < len(s); i++ {
+		// Digits are always okay.
+		if '0' <= s[i] && s[i] <= '9' || hex && 'a' <= lower(s[i]) && lower(s[i]) <= 'f' {
+			saw = '0'
+			continue
+		}
+		// An underscore must follow a digit.
+		if s[i] == '_' {
+			if saw != '0' {
+				return false
+			}
+			saw = '_'
+			continue
+		}
+		// An underscore must also be followed by a digit.
+		if saw == '_' {
+			return false
+		}
+		// Saw a non-digit, non-underscore.
+		saw = '!'
+	}
+	return saw != '_'
+}
+
+func maxUint128() Uint128 {
+	return uint64sToUint128(^uint64(0), ^uint64(0))
+}
+
+// Text returns the value in the given base (2 to 36), using lower-case letters for digits
+// above 9 and without any leading zeros, like [math/big.Int.Text].
+func (value Uint128) Text(base int) string {
+	if base < 2 || base > 36 {
+		panic("Uint128: invalid base")
+	}
+	const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	var buffer [128]byte
+	i := len(buffer)
+	lo, hi := value.uint64s()
+	for hi != 0 || lo != 0 || i == len(buffer) {
+		var remainder uint64
+		hi, remainder = bits.Div64(0, hi, uint64(base))
+		lo, remainder = bits.Div64(remainder, lo, uint64(base))
+		i--
+		buffer[i] = digits[remainder]
+	}
+	return string(buffer[i:])
+}
+
+// DecimalString returns the value in base 10.
+// Note that String() returns the value in base 16, for compatibility.
+func (value Uint128) DecimalString() string {
+	return value.Text(10)
+}
+
+// Format implements [fmt.Formatter]. It accepts the formats 'b' (binary), 'o' (octal with
+// '0' prefix), 'O' (octal with '0o' prefix), 'd' (decimal), 'x' (lower-case hexadecimal),
+// and 'X' (upper-case hexadecimal), together with the flags, width, and precision accepted by
+// [math/big.Int.Format]. The formats 's' and 'v' print String(), as before.
+func (value Uint128) Format(s fmt.State, ch rune) {
+	var base int
+	switch ch {
+	case 'b':
+		base = 2
+	case 'o', 'O':
+		base = 8
+	case 'd':
+		base = 10
+	case 'x', 'X':
+		base = 16
+	case 's', 'v':
+		if ch == 'v' && s.Flag('#') {
+			fmt.Fprint(s, "types.Uint128"+strings.TrimPrefix(fmt.Sprintf("%#v", [16]byte(value)), "[16]uint8"))
+			return
 		}
+		text := value.String()
+		if width, ok := s.Width(); ok && len(text) < width {
+			padding := strings.Repeat(" ", width-len(text))
+			if s.Flag('-') {
+				text = text + padding
+			} else {
+				text = padding + text
+			}
+		}
+		fmt.Fprint(s, text)
+		return
+	default:
+		fmt.Fprintf(s, "%%!%c(types.Uint128=%s)", ch, value.String())
+		return
 	}
 
-	// Read out a uint80 from lastRandom as a uint64 and uint16.
-	randomLo := binary.LittleEndian.Uint64(idLastRandom[:8])
-	randomHi := binary.LittleEndian.Uint16(idLastRandom[8:])
+	sign := ""
+	switch {
+	case s.Flag('+'):
+		sign = "+"
+	case s.Flag(' '):
+		sign = " "
+	}
 
-	// Increment the random bits as a uint80 together, checking for overflow.
-	// Go defines unsigned arithmetic to wrap around on overflow by default so check for zero.
-	randomLo += 1
-	if randomLo == 0 {
-		randomHi += 1
-		if randomHi == 0 {
-			idMutex.Unlock()
-			panic("random bits overflow on monotonic increment")
+	prefix := ""
+	if s.Flag('#') {
+		switch ch {
+		case 'b':
+			prefix = "0b"
+		case 'o':
+			prefix = "0"
+		case 'x':
+			prefix = "0x"
+		case 'X':
+			prefix = "0X"
+		}
+	}
+	if ch == 'O' {
+		prefix = "0o"
+	}
+
+	digits := value.Text(base)
+	if ch == 'X' {
+		digits = strings.ToUpper(digits)
+	}
+
+	// The number is printed as [left pad][sign][prefix][zero pad][digits][right pad].
+	var left, zeros, right int
+	precision, precisionSet := s.Precision()
+	if precisionSet {
+		switch {
+		case len(digits) < precision:
+			zeros = precision - len(digits)
+		case digits == "0" && precision == 0:
+			// Print nothing for a zero value with zero precision ("." or ".0").
+			return
 		}
 	}
 
-	// Write incremented uint80 back to lastRandom and stop mutating global id variables.
-	binary.LittleEndian.PutUint64(idLastRandom[:8], randomLo)
-	binary.LittleEndian.PutUint16(idLastRandom[8:], randomHi)
-	idMutex.Unlock()
+	length := len(sign) + len(prefix) + zeros + len(digits)
+	if width, widthSet := s.Width(); widthSet && length < width {
+		switch padding := width - length; {
+		case s.Flag('-'):
+			right = padding
+		case s.Flag('0') && !precisionSet:
+			zeros = padding
+		default:
+			left = padding
+		}
+	}
 
-	// Create Uint128 from new timestamp and random.
-	var id [16]byte
-	binary.LittleEndian.PutUint64(id[:8], randomLo)
-	binary.LittleEndian.PutUint16(id[8:], randomHi)
-	binary.LittleEndian.PutUint16(id[10:], (uint16)(timestamp)) // timestamp lo
-	binary.LittleEndian.PutUint32(id[12:], (uint32)(timestamp >> 16)) // timestamp hi
-	return BytesToUint128(id)
+	fmt.Fprint(s,
+		strings.Repeat(" ", left),
+		sign,
+		prefix,
+		strings.Repeat("0", zeros),
+		digits,
+		strings.Repeat(" ", right),
+	)
 }
@@ -1,9 +1,23 @@
 package types
 
 import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
+
+	tb_errors "github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
 )
 
 func Test_HexStringToUint128(t *testing.T) {
@@ -76,13 +90,13 @@ func Test_ID(t *testing.T) {
 	verifier := func() {
 		idA := ID()
 		for i := 0; i < 1_000_000; i++ {
-			if i % 1_000 == 0 {
+			if i%1_000 == 0 {
 				time.Sleep(1 * time.Millisecond)
 			}
 
 			idB := ID()
-			
-			// Verify idB and idA are monotonic using BigInts. 
+
+			// Verify idB and idA are monotonic using BigInts.
 			a := idA.BigInt()
 			b := idB.BigInt()
 			if b.Cmp(&a) != 1 {
@@ -100,10 +114,10 @@ func Test_ID(t *testing.T) {
 	var barrier, finish sync.WaitGroup
 	concurrency := 10
 	barrier.Add(concurrency) // To sync up all goroutines before verifier() to maximize contetion.
-	finish.Add(concurrency) // To wait for all goroutines to finish running verifier().
+	finish.Add(concurrency)  // To wait for all goroutines to finish running verifier().
 
 	for i := 0; i < concurrency; i++ {
-		go func(){
+		go func() {
 			barrier.Done()
 			barrier.Wait()
 			verifier()
@@ -112,4 +126,1104 @@ func Test_ID(t *testing.T) {
 	}
 
 	finish.Wait()
-}
\ No newline at end of file
+}
+
+type constantReader byte
+
+func (r constantReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func Test_IDGenerator(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	generator := NewIDGenerator(IDGeneratorOptions{
+		Now:     func() time.Time { return now },
+		Entropy: constantReader(0),
+	})
+	next := func() Uint128 {
+		t.Helper()
+		id, err := generator.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	a := next()
+	if a != uint64sToUint128(1, uint64(now.UnixMilli())<<16) {
+		t.Fatalf("Unexpected ID %s", a)
+	}
+	if !a.IDTime().Equal(now) {
+		t.Fatalf("Expected ID time %v, got %v", now, a.IDTime())
+	}
+
+	// Within the same millisecond, and when the clock moves backwards, the random bits increment.
+	b := next()
+	now = now.Add(-time.Second)
+	c := next()
+	if b.Cmp(a) != 1 || c.Cmp(b) != 1 || !c.IDTime().Equal(a.IDTime()) {
+		t.Fatalf("Expected %s < %s < %s within the same millisecond", a, b, c)
+	}
+
+	now = now.Add(2 * time.Second)
+	d := next()
+	if d.Cmp(c) != 1 || !d.IDTime().Equal(now.Truncate(time.Millisecond)) {
+		t.Fatalf("Expected %s to be after %s", d, c)
+	}
+
+	// Failures are returned rather than panicking.
+	now = now.Add(time.Millisecond)
+	generator.entropy = iotest.ErrReader(io.ErrUnexpectedEOF)
+	if _, err := generator.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected an entropy error, got %v", err)
+	}
+	generator.entropy = constantReader(0xff)
+	if _, err := generator.Next(); !errors.Is(err, tb_errors.ErrIDOverflow{}) {
+		t.Fatalf("Expected an overflow error, got %v", err)
+	}
+	now = time.Unix(-1, 0)
+	if _, err := generator.Next(); !errors.Is(err, tb_errors.ErrInvalidTimestamp{}) {
+		t.Fatalf("Expected a timestamp error, got %v", err)
+	}
+
+	// The failures left the generator intact.
+	now = time.UnixMilli(1_800_000_000_000)
+	generator.entropy = constantReader(0)
+	if e := next(); e.Cmp(d) != 1 {
+		t.Fatalf("Expected %s to be after %s", e, d)
+	}
+}
+
+func Test_ShardedIDGenerator(t *testing.T) {
+	generator := NewShardedIDGenerator(4, IDGeneratorOptions{})
+
+	var mutex sync.Mutex
+	var finish sync.WaitGroup
+	seen := map[Uint128]struct{}{}
+	concurrency := 8
+	finish.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer finish.Done()
+			ids := make([]Uint128, 0, 10_000)
+			for j := 0; j < cap(ids); j++ {
+				id, err := generator.Next()
+				if err != nil {
+					panic(err)
+				}
+				ids = append(ids, id)
+			}
+			mutex.Lock()
+			for _, id := range ids {
+				seen[id] = struct{}{}
+			}
+			mutex.Unlock()
+		}()
+	}
+	finish.Wait()
+	if len(seen) != concurrency*10_000 {
+		t.Fatalf("Expected %d unique IDs, got %d", concurrency*10_000, len(seen))
+	}
+
+	shard := generator.Shard(0)
+	a, _ := shard.Next()
+	b, _ := shard.Next()
+	if b.Cmp(a) != 1 {
+		t.Fatalf("Expected IDs of a shard to be monotonic")
+	}
+}
+
+func Test_TimestampConversions(t *testing.T) {
+	now := time.Date(2024, 2, 29, 12, 30, 15, 123456789, time.UTC)
+	timestamp, err := TimestampFromTime(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp != uint64(now.UnixNano()) {
+		t.Fatalf("Expected timestamp %d, got %d", now.UnixNano(), timestamp)
+	}
+	if !TimestampToTime(timestamp).Equal(now) {
+		t.Fatalf("Expected %v to round trip, got %v", now, TimestampToTime(timestamp))
+	}
+
+	if _, err := TimestampFromTime(time.Unix(0, 0)); !errors.Is(err, tb_errors.ErrInvalidTimestamp{}) {
+		t.Fatalf("Expected the UNIX epoch to be rejected, got %v", err)
+	}
+	if _, err := TimestampFromTime(time.Date(2263, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Fatalf("Expected times after 2262 to be rejected")
+	}
+
+	// Timestamps beyond math.MaxInt64 still convert.
+	if TimestampToTime(^uint64(0)).Before(TimestampToTime(1 << 63)) {
+		t.Fatalf("Expected TimestampToTime to be monotonic")
+	}
+}
+
+func Test_TimeoutFromDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		timeout  uint32
+		valid    bool
+	}{
+		{0, 0, true},
+		{time.Second, 1, true},
+		{1500 * time.Millisecond, 2, true},
+		{time.Nanosecond, 1, true},
+		{time.Hour, 3600, true},
+		{time.Duration(math.MaxUint32) * time.Second, math.MaxUint32, true},
+		{time.Duration(math.MaxUint32)*time.Second + 1, 0, false},
+		{-time.Second, 0, false},
+	}
+
+	for _, test := range tests {
+		timeout, err := TimeoutFromDuration(test.duration)
+		if test.valid && (err != nil || timeout != test.timeout) {
+			t.Fatalf("Expected %v to be %d seconds, got %d (%v)", test.duration, test.timeout, timeout, err)
+		}
+		if !test.valid && !errors.Is(err, tb_errors.ErrInvalidTimeout{}) {
+			t.Fatalf("Expected %v to be an invalid timeout, got %v", test.duration, err)
+		}
+	}
+}
+
+func Test_PendingExpiresAt(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	transfer := Transfer{
+		Flags:     TransferFlags{Pending: true}.ToUint16(),
+		Timestamp: uint64(created.UnixNano()),
+	}
+	if err := transfer.SetTimeout(time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !transfer.Time().Equal(created) {
+		t.Fatalf("Expected transfer time %v, got %v", created, transfer.Time())
+	}
+
+	expiresAt, ok := transfer.PendingExpiresAt()
+	if !ok || !expiresAt.Equal(created.Add(time.Minute)) {
+		t.Fatalf("Expected pending transfer to expire at %v, got %v", created.Add(time.Minute), expiresAt)
+	}
+
+	transfer.Timeout = 0
+	if _, ok := transfer.PendingExpiresAt(); ok {
+		t.Fatalf("Expected pending transfer without timeout to never expire")
+	}
+
+	transfer.Timeout = 60
+	transfer.Flags = 0
+	if _, ok := transfer.PendingExpiresAt(); ok {
+		t.Fatalf("Expected posted transfer to never expire")
+	}
+}
+
+func Test_AccountFilterBuilder(t *testing.T) {
+	accountID := ToUint128(42)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	filter, err := NewAccountFilter(accountID).
+		Debits().
+		Credits().
+		Reversed().
+		TimeMin(from).
+		TimeMax(to).
+		Limit(10).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AccountFilter{
+		AccountID:    accountID,
+		TimestampMin: uint64(from.UnixNano()),
+		TimestampMax: uint64(to.UnixNano()),
+		Limit:        10,
+		Flags:        AccountFilterFlags{Debits: true, Credits: true, Reversed: true}.ToUint32(),
+	}
+	if filter != expected {
+		t.Fatalf("Expected filter %+v, got %+v", expected, filter)
+	}
+
+	invalid := []*AccountFilterBuilder{
+		NewAccountFilter(Uint128{}).Debits().Limit(1),
+		NewAccountFilter(maxUint128()).Debits().Limit(1),
+		NewAccountFilter(accountID).Limit(1),
+		NewAccountFilter(accountID).Debits(),
+		NewAccountFilter(accountID).Debits().Limit(1).TimeMin(to).TimeMax(from),
+		NewAccountFilter(accountID).Debits().Limit(1).TimestampMax(^uint64(0)),
+		NewAccountFilter(accountID).Debits().Limit(1).TimeMin(time.Unix(0, 0)),
+	}
+	for i, builder := range invalid {
+		if _, err := builder.Build(); err == nil {
+			t.Fatalf("Expected filter %d to be invalid", i)
+		}
+	}
+}
+
+var bigUint128Max = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+func bigFromUint64s(lo uint64, hi uint64) *big.Int {
+	value := new(big.Int).SetUint64(hi)
+	value.Lsh(value, 64)
+	return value.Or(value, new(big.Int).SetUint64(lo))
+}
+
+func assertBig(t *testing.T, op string, expected *big.Int, got Uint128) {
+	t.Helper()
+	gotBig := got.BigInt()
+	if expected.Cmp(&gotBig) != 0 {
+		t.Fatalf("%s: expected %s, got %s", op, expected.Text(16), gotBig.Text(16))
+	}
+}
+
+func assertOverflow(t *testing.T, op string, expected *big.Int, got Uint128, err error) {
+	t.Helper()
+	overflows := expected.Sign() < 0 || expected.Cmp(bigUint128Max) > 0
+	if overflows {
+		if !errors.Is(err, tb_errors.ErrUint128Overflow{}) {
+			t.Fatalf("%s: expected overflow of %s, got %v", op, expected.Text(16), err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("%s: unexpected error %v", op, err)
+	}
+	assertBig(t, op, expected, got)
+}
+
+func FuzzUint128Arithmetic(f *testing.F) {
+	f.Add(uint64(0), uint64(0), uint64(0), uint64(0), uint8(0))
+	f.Add(uint64(1), uint64(0), uint64(1), uint64(0), uint8(1))
+	f.Add(^uint64(0), ^uint64(0), uint64(1), uint64(0), uint8(127))
+	f.Add(^uint64(0), uint64(0), ^uint64(0), uint64(0), uint8(64))
+	f.Add(uint64(0), uint64(1), uint64(0), uint64(1), uint8(63))
+	f.Add(uint64(12345), uint64(1)<<63, uint64(7), uint64(3), uint8(65))
+	f.Add(^uint64(0), ^uint64(0), ^uint64(0), uint64(1)<<63, uint8(128))
+	f.Add(uint64(0), ^uint64(0)>>1, uint64(0), uint64(1)<<62, uint8(200))
+
+	f.Fuzz(func(t *testing.T, aLo uint64, aHi uint64, bLo uint64, bHi uint64, shift uint8) {
+		a := uint64sToUint128(aLo, aHi)
+		b := uint64sToUint128(bLo, bHi)
+		x := bigFromUint64s(aLo, aHi)
+		y := bigFromUint64s(bLo, bHi)
+		modulus := new(big.Int).Add(bigUint128Max, big.NewInt(1))
+
+		assertBig(t, "BigIntToUint128", x, a)
+		if a.Cmp(b) != x.Cmp(y) {
+			t.Fatalf("Cmp: expected %d, got %d", x.Cmp(y), a.Cmp(b))
+		}
+		if a.IsZero() != (x.Sign() == 0) {
+			t.Fatalf("IsZero: expected %v", x.Sign() == 0)
+		}
+
+		sum := new(big.Int).Add(x, y)
+		assertBig(t, "Add", new(big.Int).Mod(sum, modulus), a.Add(b))
+		got, err := a.AddChecked(b)
+		assertOverflow(t, "AddChecked", sum, got, err)
+
+		difference := new(big.Int).Sub(x, y)
+		assertBig(t, "Sub", new(big.Int).Mod(difference, modulus), a.Sub(b))
+		got, err = a.SubChecked(b)
+		assertOverflow(t, "SubChecked", difference, got, err)
+
+		product := new(big.Int).Mul(x, y)
+		assertBig(t, "Mul", new(big.Int).Mod(product, modulus), a.Mul(b))
+		got, err = a.MulChecked(b)
+		assertOverflow(t, "MulChecked", product, got, err)
+
+		if y.Sign() == 0 {
+			if _, err := a.DivChecked(b); !errors.Is(err, tb_errors.ErrUint128DivisionByZero{}) {
+				t.Fatalf("DivChecked: expected division by zero, got %v", err)
+			}
+		} else {
+			quotient, remainder := new(big.Int).QuoRem(x, y, new(big.Int))
+			gotQuotient, gotRemainder := a.QuoRem(b)
+			assertBig(t, "QuoRem quotient", quotient, gotQuotient)
+			assertBig(t, "QuoRem remainder", remainder, gotRemainder)
+			assertBig(t, "Div", quotient, a.Div(b))
+			assertBig(t, "Rem", remainder, a.Rem(b))
+			got, err = a.DivChecked(b)
+			assertOverflow(t, "DivChecked", quotient, got, err)
+		}
+
+		shifted := new(big.Int).Lsh(x, uint(shift))
+		assertBig(t, "Lsh", new(big.Int).Mod(shifted, modulus), a.Lsh(uint(shift)))
+		got, err = a.LshChecked(uint(shift))
+		assertOverflow(t, "LshChecked", shifted, got, err)
+		assertBig(t, "Rsh", new(big.Int).Rsh(x, uint(shift)), a.Rsh(uint(shift)))
+	})
+}
+
+func Test_Uint128_Format(t *testing.T) {
+	value := ToUint128(255)
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"%d", "255"},
+		{"%x", "ff"},
+		{"%X", "FF"},
+		{"%#x", "0xff"},
+		{"%08d", "00000255"},
+		{"%-6d|", "255   |"},
+		{"%v", "ff"},
+		{"%s", "ff"},
+		{"%4v", "  ff"},
+		{"%#v", "types.Uint128{0xff, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}"},
+		{"%q", "%!q(types.Uint128=ff)"},
+	}
+	for _, test := range tests {
+		got := fmt.Sprintf(test.format, value)
+		if got != test.expected {
+			t.Fatalf("Expected %q to format as %q, got %q", test.format, test.expected, got)
+		}
+	}
+
+	max := uint64sToUint128(^uint64(0), ^uint64(0))
+	if max.DecimalString() != "340282366920938463463374607431768211455" {
+		t.Fatalf("Unexpected decimal string %s", max.DecimalString())
+	}
+}
+
+func Test_Marshal_JSON(t *testing.T) {
+	account := Account{
+		ID:          uint64sToUint128(^uint64(0), ^uint64(0)),
+		UserData128: ToUint128(42),
+		UserData64:  7,
+		Ledger:      1,
+		Code:        10,
+		Flags:       AccountFlags{Linked: true, History: true}.ToUint16(),
+		Timestamp:   1,
+	}
+	data, err := json.Marshal(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"id":"340282366920938463463374607431768211455",` +
+		`"debits_pending":"0","debits_posted":"0","credits_pending":"0","credits_posted":"0",` +
+		`"user_data_128":"42","user_data_64":7,"user_data_32":0,"ledger":1,"code":10,` +
+		`"flags":9,"timestamp":1,"flag_names":["linked","history"]}`
+	if string(data) != expected {
+		t.Fatalf("Unexpected JSON %s", data)
+	}
+
+	var decoded Account
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != account {
+		t.Fatalf("Expected %+v to round trip, got %+v", account, decoded)
+	}
+
+	// Flags may be given by name alone, and Uint128 as a number.
+	var transfer Transfer
+	err = json.Unmarshal([]byte(`{"amount":100,"flag_names":["pending","linked"]}`), &transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.Amount != ToUint128(100) || transfer.Flags != 0b11 {
+		t.Fatalf("Unexpected transfer %+v", transfer)
+	}
+
+	// Decoding replaces the target, rather than merging into it.
+	err = json.Unmarshal([]byte(`{"flag_names":["pending"]}`), &transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer != (Transfer{Flags: TransferFlagPending.ToUint16()}) {
+		t.Fatalf("Unexpected transfer %+v", transfer)
+	}
+
+	err = json.Unmarshal([]byte(`{"flags":1,"flag_names":["pending"]}`), &transfer)
+	if !errors.Is(err, tb_errors.ErrFlagsMismatch{Type: "Transfer"}) {
+		t.Fatalf("Expected mismatched flags to be rejected, got %v", err)
+	}
+	err = json.Unmarshal([]byte(`{"flag_names":["pending","expired"]}`), &transfer)
+	if !errors.Is(err, tb_errors.ErrUnknownName{Type: "TransferFlags", Name: "expired"}) {
+		t.Fatalf("Expected unknown flags to be rejected, got %v", err)
+	}
+
+	results := []TransferEventResult{{Index: 1, Result: TransferExceedsCredits}}
+	data, err = json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[{"index":1,"result":"TransferExceedsCredits"}]` {
+		t.Fatalf("Unexpected JSON %s", data)
+	}
+	var decodedResults []TransferEventResult
+	if err := json.Unmarshal(data, &decodedResults); err != nil {
+		t.Fatal(err)
+	}
+	if decodedResults[0] != results[0] {
+		t.Fatalf("Expected %+v to round trip, got %+v", results, decodedResults)
+	}
+
+	// Reserved fields are left out.
+	data, err = json.Marshal(AccountBalance{Timestamp: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("reserved")) {
+		t.Fatalf("Unexpected JSON %s", data)
+	}
+}
+
+func Test_Marshal_Text(t *testing.T) {
+	for _, result := range []CreateAccountResult{AccountOK, AccountExistsWithDifferentFlags, AccountExists} {
+		parsed, err := ParseCreateAccountResult(result.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != result {
+			t.Fatalf("Expected %s to round trip, got %s", result, parsed)
+		}
+	}
+	if _, err := ParseCreateTransferResult("Exists"); err == nil {
+		t.Fatalf("Expected unprefixed names to be rejected")
+	}
+
+	flags := TransferFlags{Linked: true, PostPendingTransfer: true}
+	text, err := flags.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "linked|post_pending_transfer" {
+		t.Fatalf("Unexpected text %s", text)
+	}
+	var parsed TransferFlags
+	if err := parsed.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if parsed != flags {
+		t.Fatalf("Expected %+v to round trip, got %+v", flags, parsed)
+	}
+	if err := parsed.UnmarshalText(nil); err != nil || parsed != (TransferFlags{}) {
+		t.Fatalf("Expected empty text to parse as no flags, got %+v, %v", parsed, err)
+	}
+
+	var value Uint128
+	if err := value.UnmarshalText([]byte("ff")); err == nil {
+		t.Fatalf("Expected hexadecimal text to be rejected")
+	}
+}
+
+func Test_Marshal_Binary(t *testing.T) {
+	transfer := Transfer{
+		ID:              ToUint128(1),
+		DebitAccountID:  ToUint128(2),
+		CreditAccountID: ToUint128(3),
+		Amount:          uint64sToUint128(4, 5),
+		UserData64:      6,
+		Timeout:         7,
+		Ledger:          8,
+		Code:            9,
+		Flags:           TransferFlags{Pending: true}.ToUint16(),
+		Timestamp:       10,
+	}
+	data, err := transfer.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 128 {
+		t.Fatalf("Expected 128 bytes, got %d", len(data))
+	}
+	// The layout matches the native struct.
+	if data[0] != 1 || data[48] != 4 || data[56] != 5 || data[118] != 2 || data[120] != 10 {
+		t.Fatalf("Unexpected layout %x", data)
+	}
+
+	var decoded Transfer
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != transfer {
+		t.Fatalf("Expected %+v to round trip, got %+v", transfer, decoded)
+	}
+	err = decoded.UnmarshalBinary(data[1:])
+	if !errors.Is(err, tb_errors.ErrInvalidBinaryLength{Expected: 128, Got: 127}) {
+		t.Fatalf("Expected a short buffer to be rejected, got %v", err)
+	}
+
+	data, err = TransferExceedsDebits.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{55, 0, 0, 0}) {
+		t.Fatalf("Unexpected encoding %x", data)
+	}
+	data, err = AccountFlags{History: true}.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{0b1000, 0}) {
+		t.Fatalf("Unexpected encoding %x", data)
+	}
+
+	var flags AccountFilterFlags
+	if err := flags.UnmarshalBinary([]byte{0b101, 0, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if flags != (AccountFilterFlags{Debits: true, Reversed: true}) {
+		t.Fatalf("Unexpected flags %+v", flags)
+	}
+}
+
+func Test_UUID(t *testing.T) {
+	value := uint64sToUint128(0x0807060504030201, 0x100f0e0d0c0b0a09)
+	if value.UUIDString() != "100f0e0d-0c0b-0a09-0807-060504030201" {
+		t.Fatalf("Unexpected UUID %s", value.UUIDString())
+	}
+	bytes := value.BigEndianBytes()
+	if bytes[0] != 0x10 || bytes[15] != 0x01 || BigEndianBytesToUint128(bytes) != value {
+		t.Fatalf("Unexpected big-endian bytes %x", bytes)
+	}
+
+	for _, text := range []string{
+		"100f0e0d-0c0b-0a09-0807-060504030201",
+		"100F0E0D-0C0B-0A09-0807-060504030201",
+		"{100f0e0d-0c0b-0a09-0807-060504030201}",
+		"urn:uuid:100f0e0d-0c0b-0a09-0807-060504030201",
+	} {
+		parsed, err := Uint128FromUUID(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != value {
+			t.Fatalf("Expected %s to parse as %s, got %s", text, value, parsed)
+		}
+	}
+	for _, text := range []string{
+		"",
+		"100f0e0d0c0b0a090807060504030201",
+		"100f0e0d-0c0b-0a09-0807-06050403020",
+		"100f0e0d-0c0b-0a09-0807-06050403020g",
+		"{100f0e0d-0c0b-0a09-0807-060504030201",
+	} {
+		if _, err := Uint128FromUUID(text); !errors.Is(err, tb_errors.ErrInvalidUUID{Value: text}) {
+			t.Fatalf("Expected %q to be rejected, got %v", text, err)
+		}
+	}
+
+	// Known values from Python's uuid.uuid5 and the RFC 9562 example for version 8.
+	name := []byte("www.example.com")
+	if id := UUIDv5(UUIDNamespaceDNS, name); id.UUIDString() != "2ed6657d-e927-568b-95e1-2665a8aea6a2" {
+		t.Fatalf("Unexpected UUIDv5 %s", id.UUIDString())
+	}
+	if id := UUIDv8(UUIDNamespaceDNS, name); id.UUIDString() != "5c146b14-3c52-8afd-938a-375d0df1fbf6" {
+		t.Fatalf("Unexpected UUIDv8 %s", id.UUIDString())
+	}
+	if UUIDv8(UUIDNamespaceDNS, name) != UUIDv8(UUIDNamespaceDNS, name) {
+		t.Fatalf("Expected UUIDv8 to be deterministic")
+	}
+	if UUIDv8(UUIDNamespaceDNS, name) == UUIDv8(UUIDNamespaceURL, name) {
+		t.Fatalf("Expected UUIDv8 to depend on the namespace")
+	}
+}
+
+func Test_SQL(t *testing.T) {
+	values := []Uint128{
+		{},
+		ToUint128(42),
+		uint64sToUint128(0x0807060504030201, 0x100f0e0d0c0b0a09),
+		uint64sToUint128(^uint64(0), ^uint64(0)),
+	}
+	tests := []struct {
+		encoding SQLEncoding
+		expected func(Uint128) driver.Value
+	}{
+		{SQLBytes, func(value Uint128) driver.Value {
+			bytes := value.Bytes()
+			swapEndian(bytes[:])
+			return bytes[:]
+		}},
+		{SQLNumeric, func(value Uint128) driver.Value {
+			return value.DecimalString()
+		}},
+		{SQLUUID, func(value Uint128) driver.Value {
+			hex := fmt.Sprintf("%032x", value)
+			return hex[0:8] + "-" + hex[8:12] + "-" + hex[12:16] + "-" + hex[16:20] + "-" + hex[20:]
+		}},
+	}
+	for _, test := range tests {
+		for _, value := range values {
+			encoded, err := value.SQL(test.encoding).Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(encoded, test.expected(value)) {
+				t.Fatalf("Expected %s to encode %s as %v, got %v", test.encoding, value, test.expected(value), encoded)
+			}
+
+			// Drivers may return text columns as either string or []byte.
+			sources := []interface{}{encoded}
+			if text, ok := encoded.(string); ok {
+				sources = append(sources, []byte(strings.ToUpper(text)))
+			}
+			for _, source := range sources {
+				var decoded Uint128
+				if err := decoded.SQL(test.encoding).Scan(source); err != nil {
+					t.Fatal(err)
+				}
+				if decoded != value {
+					t.Fatalf("Expected %s to round trip %s, got %s", test.encoding, value, decoded)
+				}
+			}
+		}
+	}
+
+	// SQLBytes preserves ordering.
+	a, _ := ToUint128(255).Value()
+	b, _ := ToUint128(256).Value()
+	if bytes.Compare(a.([]byte), b.([]byte)) >= 0 {
+		t.Fatalf("Expected %x < %x", a, b)
+	}
+
+	var value Uint128
+	if err := value.SQL(SQLNumeric).Scan(int64(7)); err != nil || value != ToUint128(7) {
+		t.Fatalf("Expected an integer to scan, got %s, %v", value, err)
+	}
+	invalid := []struct {
+		encoding SQLEncoding
+		src      interface{}
+	}{
+		{SQLBytes, nil},
+		{SQLBytes, []byte{1, 2, 3}},
+		{SQLNumeric, int64(-1)},
+		{SQLNumeric, "-1"},
+		{SQLNumeric, 1.5},
+		{SQLUUID, "0000000000000000000000000000002a"},
+		{SQLUUID, "00000000-0000-0000-0000-00000000002g"},
+	}
+	for _, test := range invalid {
+		if err := value.SQL(test.encoding).Scan(test.src); err == nil {
+			t.Fatalf("Expected %s to reject %#v", test.encoding, test.src)
+		}
+	}
+	if value != ToUint128(7) {
+		t.Fatalf("Expected a failed scan to leave the value unchanged, got %s", value)
+	}
+
+	var null *Uint128
+	if encoded, err := null.SQL(SQLNumeric).Value(); encoded != nil || err != nil {
+		t.Fatalf("Expected a nil Uint128 to be NULL, got %#v, %v", encoded, err)
+	}
+	if err := null.SQL(SQLNumeric).Scan("7"); err != (tb_errors.ErrNilSQLTarget{}) {
+		t.Fatalf("Expected scanning into a nil Uint128 to fail, got %v", err)
+	}
+}
+
+func FuzzParseUint128(f *testing.F) {
+	f.Add("0", 10)
+	f.Add("340282366920938463463374607431768211455", 10)
+	f.Add("340282366920938463463374607431768211456", 10)
+	f.Add("ffffffffffffffffffffffffffffffff", 16)
+	f.Add("0x_dead_BEEF", 0)
+	f.Add("0b101", 0)
+	f.Add("0o17", 0)
+	f.Add("017", 0)
+	f.Add("1__2", 0)
+	f.Add("-1", 10)
+	f.Add("+1", 10)
+	f.Add("zz", 36)
+	f.Add("", 10)
+	f.Add("1", 1)
+
+	f.Fuzz(func(t *testing.T, s string, base int) {
+		value, err := ParseUint128(s, base)
+
+		if base == 0 {
+			// Cross-check the prefix and underscore rules against strconv.
+			expected, expectedErr := strconv.ParseUint(s, 0, 64)
+			if expectedErr == nil {
+				if err != nil || value != ToUint128(expected) {
+					t.Fatalf("Expected %q to parse as %d, got %v (%v)", s, expected, value, err)
+				}
+			} else if errors.Is(expectedErr, strconv.ErrSyntax) && err == nil {
+				t.Fatalf("Expected %q to be a syntax error, got %v", s, value)
+			}
+			return
+		}
+
+		if base < 2 || base > 36 {
+			if err == nil {
+				t.Fatalf("Expected base %d to be rejected", base)
+			}
+			return
+		}
+
+		var expected big.Int
+		_, ok := expected.SetString(s, base)
+		if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+			ok = false
+		}
+		switch {
+		case !ok:
+			// Like strconv, overflow is reported as soon as the valid digits before a syntax
+			// error overflow.
+			prefix := s
+			for i := 0; i < len(s); i++ {
+				var digit big.Int
+				if _, valid := digit.SetString(s[i:i+1], base); !valid || s[i] == '-' || s[i] == '+' || s[i] == '_' {
+					prefix = s[:i]
+					break
+				}
+			}
+			var prefixValue big.Int
+			if _, valid := prefixValue.SetString(prefix, base); valid && prefixValue.BitLen() > 128 {
+				if !errors.Is(err, strconv.ErrRange) {
+					t.Fatalf("Expected %q in base %d to be a range error, got %v (%v)", s, base, value, err)
+				}
+			} else if !errors.Is(err, strconv.ErrSyntax) {
+				t.Fatalf("Expected %q in base %d to be a syntax error, got %v (%v)", s, base, value, err)
+			}
+		case expected.BitLen() > 128:
+			if !errors.Is(err, strconv.ErrRange) {
+				t.Fatalf("Expected %q in base %d to be a range error, got %v (%v)", s, base, value, err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("Expected %q in base %d to parse, got %v", s, base, err)
+			}
+			assertBig(t, "ParseUint128", &expected, value)
+			if value.Text(base) != expected.Text(base) {
+				t.Fatalf("Text: expected %s, got %s", expected.Text(base), value.Text(base))
+			}
+		}
+	})
+}
+
+func FuzzUint128Conversions(f *testing.F) {
+	f.Add([]byte{}, false)
+	f.Add([]byte{1}, true)
+	f.Add(bytes.Repeat([]byte{0xff}, 16), false)
+	f.Add(append([]byte{1}, make([]byte, 16)...), false)
+
+	f.Fuzz(func(t *testing.T, magnitude []byte, negative bool) {
+		var value big.Int
+		value.SetBytes(magnitude)
+		if negative {
+			value.Neg(&value)
+		}
+
+		got, err := BigIntToUint128Checked(value)
+		switch {
+		case value.Sign() < 0:
+			if !errors.Is(err, tb_errors.ErrUint128Negative{}) {
+				t.Fatalf("Expected %s to be rejected as negative, got %v", value.String(), err)
+			}
+			return
+		case value.BitLen() > 128:
+			if !errors.Is(err, tb_errors.ErrUint128Overflow{}) {
+				t.Fatalf("Expected %s to overflow, got %v", value.String(), err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertBig(t, "BigIntToUint128Checked", &value, got)
+
+		for _, verb := range []string{"%d", "%x", "%X", "%#x", "%40d", "%-40x|", "%040X", "%.50d", "%+d"} {
+			expected := fmt.Sprintf(verb, &value)
+			if formatted := fmt.Sprintf(verb, got); formatted != expected {
+				t.Fatalf("Format %s: expected %q, got %q", verb, expected, formatted)
+			}
+		}
+
+		decimal, err := ParseUint128(got.DecimalString(), 10)
+		if err != nil || decimal != got {
+			t.Fatalf("Expected %s to round trip, got %v (%v)", got.DecimalString(), decimal, err)
+		}
+	})
+}
+
+func Test_Diff(t *testing.T) {
+	check := func(expected []FieldDiff, diffs []FieldDiff) {
+		t.Helper()
+		if len(expected) == 0 && len(diffs) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(expected, diffs) {
+			t.Fatalf("Expected %v, got %v", expected, diffs)
+		}
+	}
+
+	account := Account{ID: ToUint128(1), Ledger: 1, Code: 1, Flags: AccountFlags{History: true}.ToUint16()}
+	stored := account
+	stored.CreditsPosted = ToUint128(100)
+	stored.Timestamp = 1
+	check(nil, DiffAccounts(account, stored))
+
+	stored.UserData128 = ToUint128(1 << 40)
+	stored.Flags = 0
+	check([]FieldDiff{
+		{Field: "user_data_128", Submitted: "0", Stored: "1099511627776"},
+		{Field: "flags", Submitted: "history", Stored: "none"},
+	}, DiffAccounts(account, stored))
+
+	pending := Transfer{
+		ID:              ToUint128(2),
+		DebitAccountID:  ToUint128(1),
+		CreditAccountID: ToUint128(3),
+		Amount:          ToUint128(10),
+		Timeout:         60,
+		Ledger:          1,
+		Code:            1,
+	}
+	changed := pending
+	changed.CreditAccountID = ToUint128(4)
+	changed.Timeout = 0
+	check([]FieldDiff{
+		{Field: "credit_account_id", Submitted: "4", Stored: "3"},
+		{Field: "timeout", Submitted: "0", Stored: "60"},
+	}, DiffTransfers(changed, pending))
+
+	// Posting inherits the fields it leaves zero.
+	post := Transfer{
+		ID:        ToUint128(5),
+		PendingID: pending.ID,
+		Flags:     TransferFlags{PostPendingTransfer: true}.ToUint16(),
+	}
+	storedPost := pending
+	storedPost.ID, storedPost.PendingID, storedPost.Flags, storedPost.Timeout = post.ID, post.PendingID, post.Flags, 0
+	check(nil, DiffTransfers(post, storedPost))
+	post.Amount = ToUint128(5)
+	check([]FieldDiff{
+		{Field: "amount", Submitted: "5", Stored: "10"},
+	}, DiffTransfers(post, storedPost))
+
+	if !TransferExistsWithDifferentCode.ExistsWithDifferent() || TransferExists.ExistsWithDifferent() {
+		t.Fatal("Expected only TransferExistsWithDifferent* results to exist with different fields")
+	}
+	if !AccountExistsWithDifferentLedger.ExistsWithDifferent() || AccountExists.ExistsWithDifferent() {
+		t.Fatal("Expected only AccountExistsWithDifferent* results to exist with different fields")
+	}
+}
+
+func Test_FlagBitsets(t *testing.T) {
+	flags := TransferFlagLinked.With(TransferFlagPending)
+	if flags.ToUint16() != (TransferFlags{Linked: true, Pending: true}).ToUint16() {
+		t.Fatalf("Expected constants to match the flag struct, got %#x", flags.ToUint16())
+	}
+	if !flags.Has(TransferFlagPending) || !flags.Has(TransferFlagLinked|TransferFlagPending) {
+		t.Fatalf("Expected %s to have linked and pending", flags)
+	}
+	if flags.Has(TransferFlagPending | TransferFlagBalancingDebit) {
+		t.Fatalf("Expected %s not to have balancing_debit", flags)
+	}
+	if flags.Without(TransferFlagLinked) != TransferFlagPending {
+		t.Fatalf("Expected %s without linked to be pending", flags)
+	}
+
+	for _, test := range []struct {
+		flags TransferFlag
+		text  string
+	}{
+		{0, "none"},
+		{flags, "linked|pending"},
+		{TransferFlagBalancingCredit, "balancing_credit"},
+		{TransferFlagLinked | 1<<10, "linked|0x400"},
+	} {
+		if test.flags.String() != test.text {
+			t.Fatalf("Expected %#x to format as %q, got %q", uint16(test.flags), test.text, test.flags.String())
+		}
+	}
+
+	for text, expected := range map[string]TransferFlag{
+		"":                                 0,
+		"none":                             0,
+		"linked|pending":                   flags,
+		" post_pending_transfer | linked ": TransferFlagPostPendingTransfer | TransferFlagLinked,
+	} {
+		parsed, err := ParseTransferFlags(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != expected {
+			t.Fatalf("Expected %q to parse as %s, got %s", text, expected, parsed)
+		}
+	}
+	if _, err := ParseTransferFlags("linked|pendng"); !errors.Is(err, tb_errors.ErrUnknownName{Type: "TransferFlags", Name: "pendng"}) {
+		t.Fatalf("Expected an unknown name, got %v", err)
+	}
+	filter, err := ParseAccountFilterFlags("debits|reversed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.ToUint32() != (AccountFilterFlags{Debits: true, Reversed: true}).ToUint32() {
+		t.Fatalf("Expected debits|reversed, got %s", filter)
+	}
+
+	for _, valid := range []TransferFlag{
+		0,
+		TransferFlagPending | TransferFlagBalancingDebit | TransferFlagBalancingCredit,
+		TransferFlagPostPendingTransfer | TransferFlagLinked,
+	} {
+		if err := valid.Validate(); err != nil {
+			t.Fatalf("Expected %s to be valid, got %v", valid, err)
+		}
+	}
+	for _, test := range []struct {
+		flags    TransferFlag
+		expected []string
+	}{
+		{TransferFlagPending | TransferFlagVoidPendingTransfer, []string{"pending", "void_pending_transfer"}},
+		{TransferFlagPostPendingTransfer | TransferFlagVoidPendingTransfer, []string{"post_pending_transfer", "void_pending_transfer"}},
+		{TransferFlagBalancingCredit | TransferFlagPostPendingTransfer, []string{"post_pending_transfer", "balancing_credit"}},
+	} {
+		var exclusive tb_errors.ErrFlagsMutuallyExclusive
+		if err := test.flags.Validate(); !errors.As(err, &exclusive) || !reflect.DeepEqual(exclusive.Flags, test.expected) {
+			t.Fatalf("Expected %s to be mutually exclusive, got %v", test.flags, err)
+		}
+	}
+	if err := (TransferFlagLinked | 1<<6).Validate(); err != (tb_errors.ErrReservedFlags{Type: "TransferFlags", Flags: 1 << 6}) {
+		t.Fatalf("Expected reserved bits to be rejected, got %v", err)
+	}
+	if err := (AccountFlagDebitsMustNotExceedCredits | AccountFlagCreditsMustNotExceedDebits).Validate(); err == nil {
+		t.Fatal("Expected mutually exclusive account flags to be rejected")
+	}
+	if err := AccountFilterFlag(1 << 3).Validate(); err == nil {
+		t.Fatal("Expected reserved account filter flags to be rejected")
+	}
+}
+
+func Test_Balance(t *testing.T) {
+	liability := Account{
+		ID:             ToUint128(1),
+		DebitsPending:  ToUint128(30),
+		DebitsPosted:   ToUint128(20),
+		CreditsPending: ToUint128(5),
+		CreditsPosted:  ToUint128(100),
+		Flags:          AccountFlagDebitsMustNotExceedCredits.ToUint16(),
+	}
+	for _, test := range []struct {
+		name     string
+		actual   Net
+		expected string
+	}{
+		{"NetPosted", liability.NetPosted(), "80"},
+		{"NetPending", liability.NetPending(), "-25"},
+		{"Available", liability.Available(), "50"},
+	} {
+		if test.actual.String() != test.expected {
+			t.Fatalf("Expected %s to be %s, got %s", test.name, test.expected, test.actual)
+		}
+	}
+	if headroom := liability.HeadroomForDebit(); headroom != ToUint128(50) {
+		t.Fatalf("Expected debit headroom of 50, got %s", headroom.DecimalString())
+	}
+	if headroom := liability.HeadroomForCredit(); headroom != maxUint128().Sub(ToUint128(105)) {
+		t.Fatalf("Expected credit headroom up to overflow, got %s", headroom.DecimalString())
+	}
+
+	// An asset account is debit-normal.
+	asset := Account{
+		ID:            ToUint128(2),
+		DebitsPosted:  ToUint128(10),
+		CreditsPosted: ToUint128(40),
+		Flags:         AccountFlagCreditsMustNotExceedDebits.ToUint16(),
+	}
+	if net := asset.NetPosted(); net.String() != "-30" || net.Sign() != -1 {
+		t.Fatalf("Expected a negative net posted balance, got %s", net)
+	}
+	if net := asset.NetPosted().BigInt(); net.Cmp(big.NewInt(-30)) != 0 {
+		t.Fatalf("Expected -30, got %s", net.String())
+	}
+	if headroom := asset.HeadroomForCredit(); !headroom.IsZero() {
+		t.Fatalf("Expected no credit headroom, got %s", headroom.DecimalString())
+	}
+	if net := asset.Available(); net.String() != "-30" {
+		t.Fatalf("Expected -30 available, got %s", net)
+	}
+
+	// Without flags, only overflow limits a transfer.
+	full := Account{ID: ToUint128(3), DebitsPosted: maxUint128().Sub(ToUint128(1))}
+	if net := full.NetPosted(); !net.Negative || net.Magnitude != full.DebitsPosted {
+		t.Fatalf("Expected -(2^128-2), got %s", net)
+	}
+	if net := (Net{}); net.Sign() != 0 || net.String() != "0" {
+		t.Fatalf("Expected zero, got %s", net)
+	}
+	ordered := []Net{
+		{Negative: true, Magnitude: ToUint128(30)},
+		{Negative: true, Magnitude: ToUint128(2)},
+		{},
+		{Magnitude: ToUint128(2)},
+		{Magnitude: ToUint128(30)},
+	}
+	for i := range ordered {
+		for j := range ordered {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			if cmp := ordered[i].Cmp(ordered[j]); cmp != expected {
+				t.Fatalf("Expected %s cmp %s = %d, got %d", ordered[i], ordered[j], expected, cmp)
+			}
+		}
+	}
+	if cmp := (Net{Negative: true}).Cmp(Net{}); cmp != 0 {
+		t.Fatalf("Expected negative zero to equal zero, got %d", cmp)
+	}
+
+	transfer := Transfer{
+		DebitAccountID:  liability.ID,
+		CreditAccountID: asset.ID,
+		Amount:          ToUint128(50),
+	}
+	if liability.WouldExceed(transfer) {
+		t.Fatal("Expected a debit within the headroom not to exceed")
+	}
+	transfer.Amount = ToUint128(51)
+	if !liability.WouldExceed(transfer) {
+		t.Fatal("Expected a debit beyond the headroom to exceed")
+	}
+	if !asset.WouldExceed(transfer) {
+		t.Fatal("Expected any credit to exceed the asset's debits")
+	}
+	transfer.Flags = TransferFlagPostPendingTransfer.ToUint16()
+	if liability.WouldExceed(transfer) || asset.WouldExceed(transfer) {
+		t.Fatal("Expected posting a pending transfer never to exceed")
+	}
+	transfer = Transfer{DebitAccountID: full.ID, CreditAccountID: asset.ID, Amount: ToUint128(2)}
+	if !full.WouldExceed(transfer) {
+		t.Fatal("Expected an overflowing debit to exceed")
+	}
+
+	// Balancing transfers are clamped to the headroom, and exceed if there is none.
+	transfer = Transfer{
+		DebitAccountID:  liability.ID,
+		CreditAccountID: asset.ID,
+		Flags:           TransferFlagBalancingDebit.ToUint16(),
+	}
+	if liability.WouldExceed(transfer) {
+		t.Fatal("Expected a balancing debit within the headroom not to exceed")
+	}
+	if !asset.WouldExceed(transfer) {
+		t.Fatal("Expected the clamped amount to exceed the credit account's limit")
+	}
+	exhausted := liability
+	exhausted.DebitsPosted = ToUint128(70)
+	if !exhausted.WouldExceed(transfer) {
+		t.Fatal("Expected a balancing debit without headroom to exceed")
+	}
+	transfer = Transfer{
+		DebitAccountID:  liability.ID,
+		CreditAccountID: full.ID,
+		Amount:          ToUint128(5),
+		Flags:           TransferFlagBalancingCredit.ToUint16(),
+	}
+	if full.WouldExceed(transfer) {
+		t.Fatal("Expected a balancing credit within the debits not to exceed")
+	}
+	unlimited := Account{ID: ToUint128(4)}
+	transfer.CreditAccountID = unlimited.ID
+	if !unlimited.WouldExceed(transfer) {
+		t.Fatal("Expected a balancing credit without debits to exceed, even without limits")
+	}
+}
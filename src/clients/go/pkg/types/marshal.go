@@ -0,0 +1,129 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+)
+
+// MarshalText implements [encoding.TextMarshaler], encoding the value in decimal.
+// JSON therefore encodes a Uint128 as a decimal string, which is not subject to the precision
+// loss of JSON numbers in many decoders.
+func (value Uint128) MarshalText() ([]byte, error) {
+	return []byte(value.DecimalString()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler], decoding a decimal value.
+func (value *Uint128) UnmarshalText(text []byte) error {
+	parsed, err := ParseUint128(string(text), 10)
+	if err != nil {
+		return err
+	}
+	*value = parsed
+	return nil
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], accepting both a decimal string and a number.
+func (value *Uint128) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return err
+		}
+		text = number.String()
+	}
+	return value.UnmarshalText([]byte(text))
+}
+
+// MarshalBinary implements [encoding.BinaryMarshaler], encoding the value in 16 bytes,
+// little-endian, as in the client's wire format.
+func (value Uint128) MarshalBinary() ([]byte, error) {
+	bytes := value.Bytes()
+	return bytes[:], nil
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler].
+func (value *Uint128) UnmarshalBinary(data []byte) error {
+	if len(data) != 16 {
+		return errors.ErrInvalidBinaryLength{Expected: 16, Got: len(data)}
+	}
+	*value = BytesToUint128(*(*[16]byte)(data))
+	return nil
+}
+
+// marshalBinary encodes a fixed-size value in the client's wire format.
+// The extern structs have no implicit padding, so this matches their C layout.
+func marshalBinary(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.Grow(binary.Size(value))
+	if err := binary.Write(&buffer, binary.LittleEndian, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// unmarshalBinary decodes a fixed-size value from the client's wire format.
+func unmarshalBinary(data []byte, value interface{}) error {
+	if size := binary.Size(value); len(data) != size {
+		return errors.ErrInvalidBinaryLength{Expected: size, Got: len(data)}
+	}
+	return binary.Read(bytes.NewReader(data), binary.LittleEndian, value)
+}
+
+// flagNames returns the names of the bits set in `flags`, where `names[i]` names bit i.
+// Unnamed bits are ignored.
+func flagNames(flags uint64, names []string) []string {
+	result := []string{}
+	for i, name := range names {
+		if flags&(1<<i) != 0 {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// formatFlagNames joins the names of the bits set in `flags` with "|".
+func formatFlagNames(flags uint64, names []string) string {
+	return strings.Join(flagNames(flags, names), "|")
+}
+
+// parseFlagNames parses names joined with "|", as formatted by formatFlagNames.
+func parseFlagNames(typeName string, text string, names []string) (uint64, error) {
+	if text == "" {
+		return 0, nil
+	}
+	return flagsFromNames(typeName, strings.Split(text, "|"), names)
+}
+
+func flagsFromNames(typeName string, flagNames []string, names []string) (uint64, error) {
+	var flags uint64
+	for _, flagName := range flagNames {
+		found := false
+		for i, name := range names {
+			if strings.TrimSpace(flagName) == name {
+				flags |= 1 << i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, unknownName(typeName, flagName)
+		}
+	}
+	return flags, nil
+}
+
+func unknownName(typeName string, name string) error {
+	return errors.ErrUnknownName{Type: typeName, Name: name}
+}
+
+func flagsMismatch(typeName string) error {
+	return errors.ErrFlagsMismatch{Type: typeName}
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+)
+
+// SQLEncoding selects how a Uint128 is stored in a database column.
+type SQLEncoding int
+
+const (
+	// SQLBytes stores 16 bytes, big-endian, so that byte-wise ordering (e.g. `BYTEA`, `BLOB`)
+	// matches numeric ordering. This is the encoding used by Uint128 itself.
+	SQLBytes SQLEncoding = iota
+	// SQLNumeric stores decimal text, for `NUMERIC(39, 0)` or text columns.
+	SQLNumeric
+	// SQLUUID stores UUID text, for `UUID` columns. See UUIDString.
+	SQLUUID
+)
+
+func (encoding SQLEncoding) String() string {
+	switch encoding {
+	case SQLBytes:
+		return "SQLBytes"
+	case SQLNumeric:
+		return "SQLNumeric"
+	case SQLUUID:
+		return "SQLUUID"
+	}
+	return "SQLEncoding(" + strconv.Itoa(int(encoding)) + ")"
+}
+
+// SQLUint128 adapts a Uint128 to [database/sql] with the given encoding, for example
+// `db.Exec(query, id.SQL(types.SQLNumeric))` or `rows.Scan(id.SQL(types.SQLNumeric))`.
+type SQLUint128 struct {
+	Uint128  *Uint128
+	Encoding SQLEncoding
+}
+
+// SQL returns an adapter that reads and writes the value with the given encoding.
+func (value *Uint128) SQL(encoding SQLEncoding) SQLUint128 {
+	return SQLUint128{Uint128: value, Encoding: encoding}
+}
+
+// Value implements [database/sql/driver.Valuer], using SQLBytes.
+func (value Uint128) Value() (driver.Value, error) {
+	return value.SQL(SQLBytes).Value()
+}
+
+// Scan implements [database/sql.Scanner], using SQLBytes.
+func (value *Uint128) Scan(src interface{}) error {
+	return value.SQL(SQLBytes).Scan(src)
+}
+
+// Value implements [database/sql/driver.Valuer]. A nil Uint128 is written as NULL.
+func (s SQLUint128) Value() (driver.Value, error) {
+	if s.Uint128 == nil {
+		return nil, nil
+	}
+	switch s.Encoding {
+	case SQLBytes:
+		bytes := s.Uint128.BigEndianBytes()
+		return bytes[:], nil
+	case SQLNumeric:
+		return s.Uint128.DecimalString(), nil
+	case SQLUUID:
+		return s.Uint128.UUIDString(), nil
+	}
+	return nil, errors.ErrInvalidSQLValue{Encoding: s.Encoding.String(), Type: "Uint128"}
+}
+
+// Scan implements [database/sql.Scanner]. NULL is rejected, since there is no NULL Uint128, and
+// so is a nil Uint128.
+func (s SQLUint128) Scan(src interface{}) error {
+	if s.Uint128 == nil {
+		return errors.ErrNilSQLTarget{}
+	}
+	value, err := scanUint128(src, s.Encoding)
+	if err != nil {
+		return err
+	}
+	*s.Uint128 = value
+	return nil
+}
+
+func scanUint128(src interface{}, encoding SQLEncoding) (Uint128, error) {
+	switch encoding {
+	case SQLBytes:
+		if src, ok := src.([]byte); ok && len(src) == 16 {
+			return BigEndianBytesToUint128(*(*[16]byte)(src)), nil
+		}
+	case SQLNumeric:
+		switch src := src.(type) {
+		case int64:
+			if src >= 0 {
+				return ToUint128(uint64(src)), nil
+			}
+		case []byte:
+			return ParseUint128(string(src), 10)
+		case string:
+			return ParseUint128(src, 10)
+		}
+	case SQLUUID:
+		switch src := src.(type) {
+		case []byte:
+			if value, err := Uint128FromUUID(string(src)); err == nil {
+				return value, nil
+			}
+		case string:
+			if value, err := Uint128FromUUID(src); err == nil {
+				return value, nil
+			}
+		}
+	}
+	return Uint128{}, errors.ErrInvalidSQLValue{
+		Encoding: encoding.String(),
+		Type:     fmt.Sprintf("%T", src),
+	}
+}
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"math"
+	"time"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
+)
+
+// TimestampFromTime converts a wall-clock time to a TigerBeetle timestamp, in nanoseconds since
+// the UNIX epoch. Times before the epoch (or at it), and after the year 2262, are rejected.
+func TimestampFromTime(t time.Time) (uint64, error) {
+	if t.Before(time.Unix(0, 1)) || t.After(time.Unix(0, math.MaxInt64)) {
+		return 0, errors.ErrInvalidTimestamp{}
+	}
+	return uint64(t.UnixNano()), nil
+}
+
+// TimestampToTime converts a TigerBeetle timestamp, in nanoseconds since the UNIX epoch,
+// to a wall-clock time.
+func TimestampToTime(timestamp uint64) time.Time {
+	const nsPerSecond = uint64(time.Second)
+	return time.Unix(int64(timestamp/nsPerSecond), int64(timestamp%nsPerSecond))
+}
+
+// TimeoutFromDuration converts a duration to a pending transfer timeout, in whole seconds.
+// Fractions of a second are rounded up, so that a pending transfer is never held for less
+// than the requested duration.
+func TimeoutFromDuration(d time.Duration) (uint32, error) {
+	if d < 0 {
+		return 0, errors.ErrInvalidTimeout{}
+	}
+	seconds := d / time.Second
+	if d%time.Second != 0 {
+		seconds++
+	}
+	if seconds > math.MaxUint32 {
+		return 0, errors.ErrInvalidTimeout{}
+	}
+	return uint32(seconds), nil
+}
+
+// Time returns the time at which the account was created.
+func (o Account) Time() time.Time {
+	return TimestampToTime(o.Timestamp)
+}
+
+// Time returns the time at which the transfer was committed.
+func (o Transfer) Time() time.Time {
+	return TimestampToTime(o.Timestamp)
+}
+
+// Time returns the time of the transfer that produced this balance.
+func (o AccountBalance) Time() time.Time {
+	return TimestampToTime(o.Timestamp)
+}
+
+// TimeoutDuration returns the transfer's timeout as a duration.
+func (o Transfer) TimeoutDuration() time.Duration {
+	return time.Duration(o.Timeout) * time.Second
+}
+
+// SetTimeout sets the transfer's timeout, see TimeoutFromDuration.
+func (o *Transfer) SetTimeout(d time.Duration) error {
+	timeout, err := TimeoutFromDuration(d)
+	if err != nil {
+		return err
+	}
+	o.Timeout = timeout
+	return nil
+}
+
+// PendingExpiresAt returns when a committed pending transfer expires, that is,
+// `Timestamp + Timeout`. It returns false for transfers that never expire: transfers which are
+// not pending, without a timeout, or not yet committed.
+func (o Transfer) PendingExpiresAt() (time.Time, bool) {
+	if !o.TransferFlags().Pending || o.Timeout == 0 || o.Timestamp == 0 {
+		return time.Time{}, false
+	}
+	return o.Time().Add(o.TimeoutDuration()), true
+}
+
+// SetTimestampMin sets the filter's inclusive lower bound.
+func (o *AccountFilter) SetTimestampMin(t time.Time) error {
+	timestamp, err := TimestampFromTime(t)
+	if err != nil {
+		return err
+	}
+	o.TimestampMin = timestamp
+	return nil
+}
+
+// SetTimestampMax sets the filter's inclusive upper bound.
+func (o *AccountFilter) SetTimestampMax(t time.Time) error {
+	timestamp, err := TimestampFromTime(t)
+	if err != nil {
+		return err
+	}
+	o.TimestampMax = timestamp
+	return nil
+}
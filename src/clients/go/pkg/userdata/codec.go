@@ -0,0 +1,250 @@
+package userdata
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+var uint128Type = reflect.TypeOf(types.Uint128{})
+
+// Codec encodes and decodes a struct type to and from user data.
+type Codec struct {
+	structType reflect.Type
+	fields     []codecField
+}
+
+type codecField struct {
+	index  int
+	name   string
+	target Field
+	start  uint
+	end    uint
+}
+
+type ErrInvalidTag struct {
+	Struct string
+	Field  string
+	Reason string
+}
+
+func (e ErrInvalidTag) Error() string {
+	return fmt.Sprintf("Invalid tb tag on %s.%s: %s.", e.Struct, e.Field, e.Reason)
+}
+
+// NewCodec builds a codec for the type of `prototype`, a struct or a pointer to one.
+// Fields without a `tb` tag, or with `tb:"-"`, are ignored.
+func NewCodec(prototype interface{}) (*Codec, error) {
+	structType := reflect.TypeOf(prototype)
+	if structType != nil && structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("userdata: %v is not a struct", structType)
+	}
+
+	codec := &Codec{structType: structType}
+	used := map[Field]word{}
+	for i := 0; i < structType.NumField(); i++ {
+		structField := structType.Field(i)
+		tag, ok := structField.Tag.Lookup("tb")
+		if !ok || tag == "-" {
+			continue
+		}
+		invalid := func(reason string, args ...interface{}) error {
+			return ErrInvalidTag{structType.Name(), structField.Name, fmt.Sprintf(reason, args...)}
+		}
+		if structField.PkgPath != "" {
+			return nil, invalid("field is not exported")
+		}
+
+		field, err := parseTag(tag)
+		if err != nil {
+			return nil, invalid("%v", err)
+		}
+		field.index = i
+		field.name = structField.Name
+
+		width := field.end - field.start
+		if widthMax := kindBits(structField.Type); widthMax == 0 {
+			return nil, invalid("unsupported type %s", structField.Type)
+		} else if structField.Type.Kind() == reflect.Bool && width != 1 {
+			return nil, invalid("bool must be a single bit")
+		} else if width > widthMax {
+			return nil, invalid("%d bits do not fit in %s", width, structField.Type)
+		}
+
+		bits := mask(width).shl(field.start)
+		if used[field.target].and(bits) != (word{}) {
+			return nil, invalid("bits overlap another field")
+		}
+		used[field.target] = used[field.target].or(bits)
+		codec.fields = append(codec.fields, field)
+	}
+	return codec, nil
+}
+
+// parseTag parses `user_data_64` or `user_data_64,bits=0:32`.
+func parseTag(tag string) (codecField, error) {
+	parts := strings.Split(tag, ",")
+	target, ok := parseField(parts[0])
+	if !ok {
+		return codecField{}, fmt.Errorf("unknown field %q", parts[0])
+	}
+	field := codecField{target: target, start: 0, end: target.Bits()}
+	for _, option := range parts[1:] {
+		bits := strings.TrimPrefix(option, "bits=")
+		if bits == option {
+			return codecField{}, fmt.Errorf("unknown option %q", option)
+		}
+		bounds := strings.Split(bits, ":")
+		if len(bounds) != 2 {
+			return codecField{}, fmt.Errorf("bits must be start:end")
+		}
+		start, err := strconv.ParseUint(bounds[0], 10, 8)
+		if err != nil {
+			return codecField{}, fmt.Errorf("bits must be start:end")
+		}
+		end, err := strconv.ParseUint(bounds[1], 10, 8)
+		if err != nil {
+			return codecField{}, fmt.Errorf("bits must be start:end")
+		}
+		field.start, field.end = uint(start), uint(end)
+	}
+	if field.start >= field.end || field.end > target.Bits() {
+		return codecField{}, fmt.Errorf("bits %d:%d are not within %s", field.start, field.end, target)
+	}
+	return field, nil
+}
+
+// kindBits returns the number of bits a Go type can hold, or zero if it is not supported.
+func kindBits(t reflect.Type) uint {
+	if t == uint128Type {
+		return 128
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return 1
+	case reflect.Uint8, reflect.Int8:
+		return 8
+	case reflect.Uint16, reflect.Int16:
+		return 16
+	case reflect.Uint32, reflect.Int32:
+		return 32
+	case reflect.Uint64, reflect.Int64, reflect.Uint, reflect.Int:
+		return 64
+	}
+	return 0
+}
+
+func isSigned(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func (c *Codec) structValue(value interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type() != c.structType {
+		return reflect.Value{}, fmt.Errorf("userdata: expected %s, got %T", c.structType, value)
+	}
+	return v, nil
+}
+
+// Encode returns the user data of `value`, a struct or a pointer to one, of the codec's type.
+// Bits not covered by the codec are zero.
+func (c *Codec) Encode(value interface{}) (UserData, error) {
+	var data UserData
+	err := c.EncodeInto(&data, value)
+	return data, err
+}
+
+// EncodeInto writes `value` into the codec's bits of `data`, leaving any other bits as they are.
+func (c *Codec) EncodeInto(data *UserData, value interface{}) error {
+	v, err := c.structValue(value)
+	if err != nil {
+		return err
+	}
+	result := *data
+	for _, field := range c.fields {
+		fieldValue := v.Field(field.index)
+		var err error
+		switch {
+		case fieldValue.Type() == uint128Type:
+			err = SetUint128(&result, field.target, field.start, field.end, fieldValue.Interface().(types.Uint128))
+		case fieldValue.Kind() == reflect.Bool:
+			err = SetBool(&result, field.target, field.start, field.end, fieldValue.Bool())
+		case isSigned(fieldValue.Kind()):
+			err = SetInt(&result, field.target, field.start, field.end, fieldValue.Int())
+		default:
+			err = SetUint(&result, field.target, field.start, field.end, fieldValue.Uint())
+		}
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", c.structType.Name(), field.name, err)
+		}
+	}
+	*data = result
+	return nil
+}
+
+// Decode reads the codec's bits of `data` into `out`, a pointer to a struct of the codec's type.
+func (c *Codec) Decode(data UserData, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Type() != c.structType {
+		return fmt.Errorf("userdata: expected *%s, got %T", c.structType, out)
+	}
+	v = v.Elem()
+	for _, field := range c.fields {
+		fieldValue := v.Field(field.index)
+		switch {
+		case fieldValue.Type() == uint128Type:
+			value := GetUint128(data, field.target, field.start, field.end)
+			fieldValue.Set(reflect.ValueOf(value))
+		case fieldValue.Kind() == reflect.Bool:
+			fieldValue.SetBool(GetBool(data, field.target, field.start, field.end))
+		case isSigned(fieldValue.Kind()):
+			fieldValue.SetInt(GetInt(data, field.target, field.start, field.end))
+		default:
+			fieldValue.SetUint(GetUint(data, field.target, field.start, field.end))
+		}
+	}
+	return nil
+}
+
+// EncodeAccount writes `value` into the account's user data, see EncodeInto.
+func (c *Codec) EncodeAccount(value interface{}, account *types.Account) error {
+	data := FromAccount(*account)
+	if err := c.EncodeInto(&data, value); err != nil {
+		return err
+	}
+	data.ToAccount(account)
+	return nil
+}
+
+// DecodeAccount reads the account's user data into `out`, see Decode.
+func (c *Codec) DecodeAccount(account types.Account, out interface{}) error {
+	return c.Decode(FromAccount(account), out)
+}
+
+// EncodeTransfer writes `value` into the transfer's user data, see EncodeInto.
+func (c *Codec) EncodeTransfer(value interface{}, transfer *types.Transfer) error {
+	data := FromTransfer(*transfer)
+	if err := c.EncodeInto(&data, value); err != nil {
+		return err
+	}
+	data.ToTransfer(transfer)
+	return nil
+}
+
+// DecodeTransfer reads the transfer's user data into `out`, see Decode.
+func (c *Codec) DecodeTransfer(transfer types.Transfer, out interface{}) error {
+	return c.Decode(FromTransfer(transfer), out)
+}
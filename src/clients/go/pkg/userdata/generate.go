@@ -0,0 +1,80 @@
+package userdata
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+)
+
+// GenerateAccessors writes Go source for package `packageName`, with a getter and a setter for
+// each tagged field of the prototype's struct type, for example:
+//
+//	func PaymentTenantID(data userdata.UserData) uint32
+//	func SetPaymentTenantID(data *userdata.UserData, value uint32) error
+//
+// The accessors use the helpers of this package directly, without reflection. The struct must
+// be declared in the generated package, and its fields' types must be builtin, types.Uint128,
+// or declared in the same package. Call it from a small program run by `go generate`.
+func GenerateAccessors(w io.Writer, packageName string, prototype interface{}) error {
+	codec, err := NewCodec(prototype)
+	if err != nil {
+		return err
+	}
+	structType := codec.structType
+
+	var body bytes.Buffer
+	importTypes := false
+	for _, field := range codec.fields {
+		fieldType := structType.Field(field.index).Type
+		typeName := fieldType.Name()
+		switch {
+		case fieldType == uint128Type:
+			typeName = "types.Uint128"
+			importTypes = true
+		case fieldType.PkgPath() != "" && fieldType.PkgPath() != structType.PkgPath():
+			return fmt.Errorf("userdata: %s.%s has type %s from another package",
+				structType.Name(), field.name, fieldType)
+		}
+
+		var get, set string
+		switch {
+		case fieldType == uint128Type:
+			get, set = "GetUint128", "value"
+		case fieldType.Kind() == reflect.Bool:
+			get, set = "GetBool", "bool(value)"
+		case isSigned(fieldType.Kind()):
+			get, set = "GetInt", "int64(value)"
+		default:
+			get, set = "GetUint", "uint64(value)"
+		}
+		name := structType.Name() + field.name
+		arguments := fmt.Sprintf("userdata.%s, %d, %d", field.target.goName(), field.start, field.end)
+
+		fmt.Fprintf(&body, "\n// %s returns %s.%s, from %s bits %d:%d.\n",
+			name, structType.Name(), field.name, field.target, field.start, field.end)
+		fmt.Fprintf(&body, "func %s(data userdata.UserData) %s {\n", name, typeName)
+		fmt.Fprintf(&body, "\treturn %s(userdata.%s(data, %s))\n}\n", typeName, get, arguments)
+		fmt.Fprintf(&body, "\n// Set%s sets %s.%s, in %s bits %d:%d.\n",
+			name, structType.Name(), field.name, field.target, field.start, field.end)
+		fmt.Fprintf(&body, "func Set%s(data *userdata.UserData, value %s) error {\n", name, typeName)
+		fmt.Fprintf(&body, "\treturn userdata.Set%s(data, %s, %s)\n}\n", get[len("Get"):], arguments, set)
+	}
+
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "// Code generated by userdata.GenerateAccessors. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n\nimport (\n", packageName)
+	if importTypes {
+		fmt.Fprintf(&source, "\t%q\n", "github.com/tigerbeetle/tigerbeetle-go/pkg/types")
+	}
+	fmt.Fprintf(&source, "\t%q\n)\n", "github.com/tigerbeetle/tigerbeetle-go/pkg/userdata")
+	source.Write(body.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
@@ -0,0 +1,214 @@
+// Package userdata packs typed metadata into the `UserData128`, `UserData64`, and `UserData32`
+// fields of accounts and transfers.
+//
+// A Codec maps the fields of a Go struct onto bit ranges of those fields with struct tags:
+//
+//	type Payment struct {
+//		TenantID uint32        `tb:"user_data_64,bits=0:32"`
+//		Channel  Channel       `tb:"user_data_64,bits=32:40"`
+//		Refund   bool          `tb:"user_data_64,bits=40:41"`
+//		OrderID  types.Uint128 `tb:"user_data_128"`
+//	}
+//
+// Bit ranges are half-open, `start:end`, counted from the least significant bit, and default to
+// the whole field. Ranges must not overlap. Signed integers are stored in two's complement
+// within their range. Values that do not fit in their range are rejected when encoding.
+//
+// GenerateAccessors writes typed getters and setters for the same struct, which avoid reflection.
+package userdata
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Field identifies one of the user data fields.
+type Field uint8
+
+const (
+	UserData128 Field = iota
+	UserData64
+	UserData32
+)
+
+func (f Field) String() string {
+	switch f {
+	case UserData128:
+		return "user_data_128"
+	case UserData64:
+		return "user_data_64"
+	case UserData32:
+		return "user_data_32"
+	}
+	return "Field(" + strconv.Itoa(int(f)) + ")"
+}
+
+func (f Field) goName() string {
+	switch f {
+	case UserData128:
+		return "UserData128"
+	case UserData64:
+		return "UserData64"
+	case UserData32:
+		return "UserData32"
+	}
+	return f.String()
+}
+
+// Bits returns the width of the field.
+func (f Field) Bits() uint {
+	switch f {
+	case UserData128:
+		return 128
+	case UserData64:
+		return 64
+	case UserData32:
+		return 32
+	}
+	return 0
+}
+
+func parseField(name string) (Field, bool) {
+	for _, field := range []Field{UserData128, UserData64, UserData32} {
+		if field.String() == name {
+			return field, true
+		}
+	}
+	return 0, false
+}
+
+// UserData holds the user data fields of an account or transfer.
+type UserData struct {
+	UserData128 types.Uint128
+	UserData64  uint64
+	UserData32  uint32
+}
+
+func FromAccount(account types.Account) UserData {
+	return UserData{
+		UserData128: account.UserData128,
+		UserData64:  account.UserData64,
+		UserData32:  account.UserData32,
+	}
+}
+
+func FromTransfer(transfer types.Transfer) UserData {
+	return UserData{
+		UserData128: transfer.UserData128,
+		UserData64:  transfer.UserData64,
+		UserData32:  transfer.UserData32,
+	}
+}
+
+func (d UserData) ToAccount(account *types.Account) {
+	account.UserData128 = d.UserData128
+	account.UserData64 = d.UserData64
+	account.UserData32 = d.UserData32
+}
+
+func (d UserData) ToTransfer(transfer *types.Transfer) {
+	transfer.UserData128 = d.UserData128
+	transfer.UserData64 = d.UserData64
+	transfer.UserData32 = d.UserData32
+}
+
+type ErrOutOfRange struct {
+	Field Field
+	Start uint
+	End   uint
+	// The rejected value, in decimal.
+	Value string
+}
+
+func (e ErrOutOfRange) Error() string {
+	return fmt.Sprintf("Value %s does not fit in %s bits %d:%d.", e.Value, e.Field, e.Start, e.End)
+}
+
+// The helpers below read and write a bit range [start, end) of a field, and are used by both the
+// Codec and generated accessors. They panic if the range is not within the field, as that is a
+// programming error rather than a data error.
+
+// GetUint returns the bits as an unsigned integer. The range must be at most 64 bits wide.
+func GetUint(data UserData, field Field, start uint, end uint) uint64 {
+	checkRange(field, start, end, 64)
+	lo, _ := data.get(field).extract(start, end)
+	return lo
+}
+
+// SetUint writes an unsigned integer to the bits, or returns ErrOutOfRange if it does not fit.
+func SetUint(data *UserData, field Field, start uint, end uint, value uint64) error {
+	checkRange(field, start, end, 64)
+	if end-start < 64 && value>>(end-start) != 0 {
+		return ErrOutOfRange{Field: field, Start: start, End: end, Value: strconv.FormatUint(value, 10)}
+	}
+	data.set(field, data.get(field).insert(start, end, word{value, 0}))
+	return nil
+}
+
+// GetInt returns the bits as a signed integer in two's complement.
+// The range must be at most 64 bits wide.
+func GetInt(data UserData, field Field, start uint, end uint) int64 {
+	width := end - start
+	value := GetUint(data, field, start, end)
+	if width < 64 && value>>(width-1) != 0 {
+		value |= ^uint64(0) << width // Sign extend.
+	}
+	return int64(value)
+}
+
+// SetInt writes a signed integer to the bits in two's complement, or returns ErrOutOfRange if it
+// does not fit.
+func SetInt(data *UserData, field Field, start uint, end uint, value int64) error {
+	checkRange(field, start, end, 64)
+	width := end - start
+	if width < 64 {
+		min, max := -int64(1)<<(width-1), int64(1)<<(width-1)-1
+		if value < min || value > max {
+			return ErrOutOfRange{Field: field, Start: start, End: end, Value: strconv.FormatInt(value, 10)}
+		}
+	}
+	data.set(field, data.get(field).insert(start, end, word{uint64(value) & mask(width).lo, 0}))
+	return nil
+}
+
+// GetBool returns whether the bit is set. The range must be one bit wide.
+func GetBool(data UserData, field Field, start uint, end uint) bool {
+	checkRange(field, start, end, 1)
+	return GetUint(data, field, start, end) == 1
+}
+
+// SetBool sets or clears the bit. The range must be one bit wide.
+func SetBool(data *UserData, field Field, start uint, end uint, value bool) error {
+	checkRange(field, start, end, 1)
+	var bit uint64
+	if value {
+		bit = 1
+	}
+	return SetUint(data, field, start, end, bit)
+}
+
+// GetUint128 returns the bits as a Uint128.
+func GetUint128(data UserData, field Field, start uint, end uint) types.Uint128 {
+	checkRange(field, start, end, 128)
+	lo, hi := data.get(field).extract(start, end)
+	return word{lo, hi}.uint128()
+}
+
+// SetUint128 writes a Uint128 to the bits, or returns ErrOutOfRange if it does not fit.
+func SetUint128(data *UserData, field Field, start uint, end uint, value types.Uint128) error {
+	checkRange(field, start, end, 128)
+	w := wordFromUint128(value)
+	if w.shr(end-start) != (word{}) {
+		return ErrOutOfRange{Field: field, Start: start, End: end, Value: value.DecimalString()}
+	}
+	data.set(field, data.get(field).insert(start, end, w))
+	return nil
+}
+
+func checkRange(field Field, start uint, end uint, widthMax uint) {
+	if start >= end || end > field.Bits() || end-start > widthMax {
+		panic(fmt.Sprintf("invalid bit range %s bits=%d:%d", field, start, end))
+	}
+}
@@ -0,0 +1,184 @@
+package userdata
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+type Channel uint8
+
+type Payment struct {
+	TenantID uint32        `tb:"user_data_64,bits=0:32"`
+	Channel  Channel       `tb:"user_data_64,bits=32:40"`
+	Refund   bool          `tb:"user_data_64,bits=40:41"`
+	Offset   int16         `tb:"user_data_32,bits=4:16"`
+	OrderID  types.Uint128 `tb:"user_data_128,bits=64:128"`
+	Note     string
+}
+
+func Test_Codec(t *testing.T) {
+	codec, err := NewCodec(Payment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := Payment{
+		TenantID: 0xdeadbeef,
+		Channel:  7,
+		Refund:   true,
+		Offset:   -2048,
+		OrderID:  types.ToUint128(42),
+		Note:     "ignored",
+	}
+	transfer := types.Transfer{UserData32: 0xf, UserData128: types.ToUint128(1)}
+	if err := codec.EncodeTransfer(&payment, &transfer); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(1<<40|7<<32|0xdeadbeef), transfer.UserData64)
+	// Bits outside of the codec's ranges are preserved.
+	assert.Equal(t, uint32(0x800f), transfer.UserData32)
+	orderID, err := types.ParseUint128("0x2a0000000000000001", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, orderID, transfer.UserData128)
+
+	var decoded Payment
+	if err := codec.DecodeTransfer(transfer, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	payment.Note = ""
+	assert.Equal(t, payment, decoded)
+
+	var account types.Account
+	if err := codec.EncodeAccount(payment, &account); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, transfer.UserData64, account.UserData64)
+
+	// Values must fit their range.
+	for _, payment := range []Payment{
+		{Offset: 2048},
+		{Offset: -2049},
+		{OrderID: types.BigEndianBytesToUint128([16]byte{1})},
+	} {
+		_, err := codec.Encode(payment)
+		var outOfRange ErrOutOfRange
+		if !errors.As(err, &outOfRange) {
+			t.Fatalf("Expected %+v to be out of range, got %v", payment, err)
+		}
+	}
+	data, err := codec.Encode(Payment{Offset: 2047})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint32(2047<<4), data.UserData32)
+
+	if err := codec.Decode(data, Payment{}); err == nil {
+		t.Fatal("Expected decoding into a non-pointer to fail")
+	}
+	if _, err := codec.Encode(nil); err == nil {
+		t.Fatal("Expected encoding nil to fail")
+	}
+}
+
+func Test_NewCodec_Invalid(t *testing.T) {
+	tests := []struct {
+		prototype interface{}
+		reason    string
+	}{
+		{struct {
+			A uint32 `tb:"user_data_16"`
+		}{}, "unknown field"},
+		{struct {
+			A uint32 `tb:"user_data_32,bits=0:33"`
+		}{}, "not within"},
+		{struct {
+			A uint8 `tb:"user_data_32,bits=0:9"`
+		}{}, "do not fit"},
+		{struct {
+			A bool `tb:"user_data_32,bits=0:2"`
+		}{}, "single bit"},
+		{struct {
+			A string `tb:"user_data_32"`
+		}{}, "unsupported type"},
+		{struct {
+			A uint32 `tb:"user_data_64,bits=0:32"`
+			B uint32 `tb:"user_data_64,bits=31:63"`
+		}{}, "overlap"},
+		{struct {
+			A uint32 `tb:"user_data_64,width=32"`
+		}{}, "unknown option"},
+	}
+	for _, test := range tests {
+		_, err := NewCodec(test.prototype)
+		var invalid ErrInvalidTag
+		if !errors.As(err, &invalid) || !strings.Contains(invalid.Reason, test.reason) {
+			t.Fatalf("Expected %T to be invalid with %q, got %v", test.prototype, test.reason, err)
+		}
+	}
+	if _, err := NewCodec(42); err == nil {
+		t.Fatal("Expected a non-struct prototype to be rejected")
+	}
+}
+
+func Test_Helpers(t *testing.T) {
+	var data UserData
+	if err := SetUint128(&data, UserData128, 0, 128, types.BigEndianBytesToUint128([16]byte{
+		0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff,
+	})); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(0xff), GetUint(data, UserData128, 0, 64))
+	assert.Equal(t, uint64(0xff), GetUint(data, UserData128, 120, 128))
+	assert.Equal(t, uint64(0xf), GetUint(data, UserData128, 4, 12))
+	assert.Equal(t, int64(-1), GetInt(data, UserData128, 124, 128))
+
+	if err := SetInt(&data, UserData64, 0, 64, -1); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ^uint64(0), data.UserData64)
+	if err := SetBool(&data, UserData64, 63, 64, false); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ^uint64(0)>>1, data.UserData64)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected an invalid range to panic")
+		}
+	}()
+	GetUint(data, UserData32, 0, 33)
+}
+
+func Test_GenerateAccessors(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := GenerateAccessors(&buffer, "payments", Payment{}); err != nil {
+		t.Fatal(err)
+	}
+	source := buffer.String()
+	for _, expected := range []string{
+		"// Code generated by userdata.GenerateAccessors. DO NOT EDIT.\n\npackage payments\n",
+		"\t\"github.com/tigerbeetle/tigerbeetle-go/pkg/types\"\n",
+		"// PaymentChannel returns Payment.Channel, from user_data_64 bits 32:40.\n" +
+			"func PaymentChannel(data userdata.UserData) Channel {\n" +
+			"\treturn Channel(userdata.GetUint(data, userdata.UserData64, 32, 40))\n" +
+			"}\n",
+		"func SetPaymentOffset(data *userdata.UserData, value int16) error {\n" +
+			"\treturn userdata.SetInt(data, userdata.UserData32, 4, 16, int64(value))\n" +
+			"}\n",
+		"func SetPaymentOrderID(data *userdata.UserData, value types.Uint128) error {\n",
+	} {
+		if !strings.Contains(source, expected) {
+			t.Fatalf("Expected generated source to contain %q, got:\n%s", expected, source)
+		}
+	}
+	if strings.Contains(source, "Note") {
+		t.Fatalf("Expected untagged fields to be skipped, got:\n%s", source)
+	}
+}
@@ -0,0 +1,106 @@
+package userdata
+
+import (
+	"encoding/binary"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// word is a field's value as 128 bits, so that all three fields share the same bit operations.
+type word struct {
+	lo uint64
+	hi uint64
+}
+
+func wordFromUint128(value types.Uint128) word {
+	bytes := value.Bytes()
+	return word{binary.LittleEndian.Uint64(bytes[:8]), binary.LittleEndian.Uint64(bytes[8:])}
+}
+
+func (w word) uint128() types.Uint128 {
+	var bytes [16]byte
+	binary.LittleEndian.PutUint64(bytes[:8], w.lo)
+	binary.LittleEndian.PutUint64(bytes[8:], w.hi)
+	return types.BytesToUint128(bytes)
+}
+
+func (d UserData) get(field Field) word {
+	switch field {
+	case UserData128:
+		return wordFromUint128(d.UserData128)
+	case UserData64:
+		return word{d.UserData64, 0}
+	case UserData32:
+		return word{uint64(d.UserData32), 0}
+	}
+	panic("unknown field")
+}
+
+func (d *UserData) set(field Field, w word) {
+	switch field {
+	case UserData128:
+		d.UserData128 = w.uint128()
+	case UserData64:
+		d.UserData64 = w.lo
+	case UserData32:
+		d.UserData32 = uint32(w.lo)
+	default:
+		panic("unknown field")
+	}
+}
+
+// mask returns a word with the lowest `width` bits set.
+func mask(width uint) word {
+	switch {
+	case width >= 128:
+		return word{^uint64(0), ^uint64(0)}
+	case width >= 64:
+		return word{^uint64(0), ^uint64(0) >> (128 - width)}
+	case width == 0:
+		return word{}
+	default:
+		return word{^uint64(0) >> (64 - width), 0}
+	}
+}
+
+func (w word) and(other word) word { return word{w.lo & other.lo, w.hi & other.hi} }
+func (w word) or(other word) word  { return word{w.lo | other.lo, w.hi | other.hi} }
+func (w word) not() word           { return word{^w.lo, ^w.hi} }
+
+func (w word) shl(n uint) word {
+	switch {
+	case n >= 128:
+		return word{}
+	case n >= 64:
+		return word{0, w.lo << (n - 64)}
+	case n == 0:
+		return w
+	default:
+		return word{w.lo << n, w.hi<<n | w.lo>>(64-n)}
+	}
+}
+
+func (w word) shr(n uint) word {
+	switch {
+	case n >= 128:
+		return word{}
+	case n >= 64:
+		return word{w.hi >> (n - 64), 0}
+	case n == 0:
+		return w
+	default:
+		return word{w.lo>>n | w.hi<<(64-n), w.hi >> n}
+	}
+}
+
+// extract returns the bits [start, end), shifted down to bit 0.
+func (w word) extract(start uint, end uint) (lo uint64, hi uint64) {
+	result := w.shr(start).and(mask(end - start))
+	return result.lo, result.hi
+}
+
+// insert replaces the bits [start, end) with the low bits of value.
+func (w word) insert(start uint, end uint, value word) word {
+	bits := mask(end - start).shl(start)
+	return w.and(bits.not()).or(value.shl(start).and(bits))
+}
@@ -29,8 +29,10 @@ extern __declspec(dllexport) void onGoPacketCompletion(
 import "C"
 import (
 	e "errors"
+	"fmt"
 	"runtime"
 	"strings"
+	"time"
 	"unsafe"
 
 	"github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
@@ -47,6 +49,13 @@ type Client interface {
 	GetAccountTransfers(filter types.AccountFilter) ([]types.Transfer, error)
 	GetAccountBalances(filter types.AccountFilter) ([]types.AccountBalance, error)
 
+	// The methods below are built on the operations above.
+	BalanceAt(accountID types.Uint128, at time.Time) (types.AccountBalance, error)
+	BalancesAt(accountIDs []types.Uint128, at time.Time) ([]types.AccountBalance, error)
+
+	EnsureAccounts(accounts []types.Account) ([]types.EnsureAccountResult, error)
+	EnsureTransfers(transfers []types.Transfer) ([]types.EnsureTransferResult, error)
+
 	Nop() error
 	Close()
 }
@@ -392,6 +401,157 @@ func (c *c_client) GetAccountBalances(filter types.AccountFilter) ([]types.Accou
 	return results[0:resultCount], nil
 }
 
+// BalanceAt returns the balance of an account as of `at`, that is, after the last transfer
+// committed at or before `at`. The account must have been created with `AccountFlags.History`.
+// The returned balance is zero, with a zero timestamp, when no transfer had been committed yet.
+func (c *c_client) BalanceAt(accountID types.Uint128, at time.Time) (types.AccountBalance, error) {
+	balances, err := c.BalancesAt([]types.Uint128{accountID}, at)
+	if err != nil {
+		return types.AccountBalance{}, err
+	}
+	return balances[0], nil
+}
+
+// BalancesAt returns the balances of several accounts as of `at`, in the same order as
+// `accountIDs`. See BalanceAt. The accounts are looked up in batches, but their balances are
+// queried with one GetAccountBalances request per account, in sequence, so the latency grows
+// with the number of accounts.
+func (c *c_client) BalancesAt(accountIDs []types.Uint128, at time.Time) ([]types.AccountBalance, error) {
+	timestamp, err := types.TimestampFromTime(at)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookups are chunked to fit in a request.
+	const lookupMax = 8190
+	found := make(map[types.Uint128]types.Account, len(accountIDs))
+	for start := 0; start < len(accountIDs); start += lookupMax {
+		end := start + lookupMax
+		if end > len(accountIDs) {
+			end = len(accountIDs)
+		}
+		accounts, err := c.LookupAccounts(accountIDs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range accounts {
+			found[account.ID] = account
+		}
+	}
+
+	results := make([]types.AccountBalance, len(accountIDs))
+	for i, accountID := range accountIDs {
+		account, ok := found[accountID]
+		if !ok {
+			return nil, fmt.Errorf("account %s: %w", accountID, errors.ErrAccountNotFound{})
+		}
+		if !account.AccountFlags().History {
+			return nil, fmt.Errorf("account %s: %w", accountID, errors.ErrAccountHistoryDisabled{})
+		}
+
+		balances, err := c.GetAccountBalances(types.AccountFilter{
+			AccountID:    accountID,
+			TimestampMin: 0,
+			TimestampMax: timestamp,
+			Limit:        1,
+			Flags: types.AccountFilterFlags{
+				Debits:   true,
+				Credits:  true,
+				Reversed: true,
+			}.ToUint32(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(balances) > 0 {
+			results[i] = balances[0]
+		}
+	}
+	return results, nil
+}
+
+// EnsureAccounts creates the accounts, and verifies those that already exist. It returns a
+// result for each account that was neither created nor already existed as submitted, and for
+// `AccountExistsWithDifferent*` results, looks up the stored account to report every differing
+// field (see types.DiffAccounts).
+func (c *c_client) EnsureAccounts(accounts []types.Account) ([]types.EnsureAccountResult, error) {
+	results, err := c.CreateAccounts(accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []types.Uint128
+	for _, result := range results {
+		if result.Result.ExistsWithDifferent() {
+			ids = append(ids, accounts[result.Index].ID)
+		}
+	}
+	stored := make(map[types.Uint128]types.Account, len(ids))
+	if len(ids) > 0 {
+		found, err := c.LookupAccounts(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range found {
+			stored[account.ID] = account
+		}
+	}
+
+	ensured := make([]types.EnsureAccountResult, 0, len(results))
+	for _, result := range results {
+		if result.Result == types.AccountExists {
+			continue
+		}
+		ensure := types.EnsureAccountResult{Index: result.Index, Result: result.Result}
+		if account, ok := stored[accounts[result.Index].ID]; ok && result.Result.ExistsWithDifferent() {
+			ensure.Diff = types.DiffAccounts(accounts[result.Index], account)
+		}
+		ensured = append(ensured, ensure)
+	}
+	return ensured, nil
+}
+
+// EnsureTransfers creates the transfers, and verifies those that already exist. It returns a
+// result for each transfer that was neither created nor already existed as submitted, and for
+// `TransferExistsWithDifferent*` results, looks up the stored transfer to report every differing
+// field (see types.DiffTransfers).
+func (c *c_client) EnsureTransfers(transfers []types.Transfer) ([]types.EnsureTransferResult, error) {
+	results, err := c.CreateTransfers(transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []types.Uint128
+	for _, result := range results {
+		if result.Result.ExistsWithDifferent() {
+			ids = append(ids, transfers[result.Index].ID)
+		}
+	}
+	stored := make(map[types.Uint128]types.Transfer, len(ids))
+	if len(ids) > 0 {
+		found, err := c.LookupTransfers(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, transfer := range found {
+			stored[transfer.ID] = transfer
+		}
+	}
+
+	ensured := make([]types.EnsureTransferResult, 0, len(results))
+	for _, result := range results {
+		if result.Result == types.TransferExists {
+			continue
+		}
+		ensure := types.EnsureTransferResult{Index: result.Index, Result: result.Result}
+		if transfer, ok := stored[transfers[result.Index].ID]; ok && result.Result.ExistsWithDifferent() {
+			ensure.Diff = types.DiffTransfers(transfers[result.Index], transfer)
+		}
+		ensured = append(ensured, ensure)
+	}
+	return ensured, nil
+}
+
 func (c *c_client) Nop() error {
 	const dataSize = 256
 	var dummyData [dataSize]C.uint8_t
@@ -2,6 +2,7 @@ package tigerbeetle_go
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -9,9 +10,11 @@ import (
 	"runtime"
 	"sync"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/tigerbeetle/tigerbeetle-go/assert"
+	tb_errors "github.com/tigerbeetle/tigerbeetle-go/pkg/errors"
 	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
@@ -789,6 +792,138 @@ func doTestClient(t *testing.T, client Client) {
 		assert.Len(t, account_balances, len(transfers_retrieved))
 	})
 
+	t.Run("can query balances at a point in time", func(t *testing.T) {
+		t.Parallel()
+		accountA, _ := createTwoAccounts(t)
+
+		accountC := types.Account{
+			ID:     types.ID(),
+			Ledger: 1,
+			Code:   1,
+			Flags: types.AccountFlags{
+				History: true,
+			}.ToUint16(),
+		}
+		account_results, err := client.CreateAccounts([]types.Account{accountC})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, account_results, 0)
+
+		transfer_ids := []types.Uint128{types.ID(), types.ID()}
+		for _, transfer_id := range transfer_ids {
+			transfer_results, err := client.CreateTransfers([]types.Transfer{
+				{
+					ID:              transfer_id,
+					CreditAccountID: accountC.ID,
+					DebitAccountID:  accountA.ID,
+					Amount:          types.ToUint128(10),
+					Code:            1,
+					Ledger:          1,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Len(t, transfer_results, 0)
+		}
+
+		transfers, err := client.LookupTransfers(transfer_ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, transfers, 2)
+
+		// Before the first transfer:
+		balance, err := client.BalanceAt(accountC.ID, time.Unix(0, int64(transfers[0].Timestamp-1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, types.AccountBalance{}, balance)
+
+		// Between the transfers:
+		balance, err = client.BalanceAt(accountC.ID, time.Unix(0, int64(transfers[1].Timestamp-1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, transfers[0].Timestamp, balance.Timestamp)
+		assert.Equal(t, types.ToUint128(10), balance.CreditsPosted)
+
+		// After both transfers:
+		balances, err := client.BalancesAt([]types.Uint128{accountC.ID}, time.Unix(0, int64(transfers[1].Timestamp)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, balances, 1)
+		assert.Equal(t, transfers[1].Timestamp, balances[0].Timestamp)
+		assert.Equal(t, types.ToUint128(20), balances[0].CreditsPosted)
+
+		// Accounts without history:
+		_, err = client.BalanceAt(accountA.ID, time.Now())
+		assert.True(t, errors.Is(err, tb_errors.ErrAccountHistoryDisabled{}))
+
+		_, err = client.BalanceAt(types.ID(), time.Now())
+		assert.True(t, errors.Is(err, tb_errors.ErrAccountNotFound{}))
+
+		_, err = client.BalanceAt(accountC.ID, time.Unix(0, 0))
+		assert.True(t, errors.Is(err, tb_errors.ErrInvalidTimestamp{}))
+	})
+
+	t.Run("can ensure accounts and transfers", func(t *testing.T) {
+		t.Parallel()
+		accountA, accountB := createTwoAccounts(t)
+
+		// Existing accounts are verified, and differences reported field by field:
+		accountC := types.Account{ID: types.ID(), Ledger: 1, Code: 1}
+		changed := accountB
+		changed.Code = 3
+		changed.UserData64 = 7
+		account_results, err := client.EnsureAccounts([]types.Account{accountA, changed, accountC})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, account_results, 1)
+		assert.Equal(t, uint32(1), account_results[0].Index)
+		assert.Equal(t, types.AccountExistsWithDifferentUserData64, account_results[0].Result)
+		assert.Equal(t, []types.FieldDiff{
+			{Field: "user_data_64", Submitted: "7", Stored: "0"},
+			{Field: "code", Submitted: "3", Stored: "2"},
+		}, account_results[0].Diff)
+
+		transfer := types.Transfer{
+			ID:              types.ID(),
+			DebitAccountID:  accountA.ID,
+			CreditAccountID: accountB.ID,
+			Amount:          types.ToUint128(10),
+			Ledger:          1,
+			Code:            1,
+		}
+		transfer_results, err := client.EnsureTransfers([]types.Transfer{transfer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, transfer_results, 0)
+
+		transfer_results, err = client.EnsureTransfers([]types.Transfer{transfer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, transfer_results, 0)
+
+		retried := transfer
+		retried.Amount = types.ToUint128(11)
+		retried.Flags = types.TransferFlags{Pending: true}.ToUint16()
+		transfer_results, err = client.EnsureTransfers([]types.Transfer{retried})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, transfer_results, 1)
+		assert.Equal(t, types.TransferExistsWithDifferentFlags, transfer_results[0].Result)
+		assert.Equal(t, []types.FieldDiff{
+			{Field: "amount", Submitted: "11", Stored: "10"},
+			{Field: "flags", Submitted: "pending", Stored: "none"},
+		}, transfer_results[0].Diff)
+	})
 }
 
 func BenchmarkNop(b *testing.B) {
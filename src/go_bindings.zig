@@ -74,6 +74,19 @@ fn to_pascal_case(comptime input: []const u8, comptime min_len: ?usize) []const
     };
 }
 
+fn to_lower_camel_case(comptime input: []const u8) []const u8 {
+    return comptime blk: {
+        const pascal_case = to_pascal_case(input, null);
+        var output: [pascal_case.len]u8 = pascal_case[0..pascal_case.len].*;
+        output[0] = std.ascii.toLower(output[0]);
+        break :blk output[0..];
+    };
+}
+
+fn pad_right(comptime input: []const u8, comptime len: usize) []const u8 {
+    return comptime input ++ [_]u8{' '} ** (len - input.len);
+}
+
 fn calculate_min_len(comptime type_info: anytype) comptime_int {
     comptime {
         comptime var min_len: comptime_int = 0;
@@ -87,6 +100,26 @@ fn calculate_min_len(comptime type_info: anytype) comptime_int {
     }
 }
 
+fn calculate_type_min_len(comptime type_info: anytype) comptime_int {
+    comptime {
+        comptime var min_len: comptime_int = 0;
+        inline for (type_info.fields) |field| {
+            const field_len = go_field_type(field.type).len;
+            if (field_len > min_len) {
+                min_len = field_len;
+            }
+        }
+        return min_len;
+    }
+}
+
+fn go_field_type(comptime Type: type) []const u8 {
+    return comptime switch (@typeInfo(Type)) {
+        .Array => |array| std.fmt.comptimePrint("[{d}]{s}", .{ array.len, go_type(array.child) }),
+        else => go_type(Type),
+    };
+}
+
 fn is_upper_case(comptime word: []const u8) bool {
     // https://github.com/golang/go/wiki/CodeReviewComments#initialisms
     const initialisms = .{ "id", "ok" };
@@ -97,6 +130,20 @@ fn is_upper_case(comptime word: []const u8) bool {
     } else return false;
 }
 
+/// Groups of flags of which at most one may be set, as enforced by the state machine
+/// (`flags_are_mutually_exclusive`).
+fn exclusive_flags(comptime name: []const u8) []const []const []const u8 {
+    if (std.mem.eql(u8, name, "AccountFlags")) return &.{
+        &.{ "debits_must_not_exceed_credits", "credits_must_not_exceed_debits" },
+    };
+    if (std.mem.eql(u8, name, "TransferFlags")) return &.{
+        &.{ "pending", "post_pending_transfer", "void_pending_transfer" },
+        &.{ "balancing_debit", "post_pending_transfer", "void_pending_transfer" },
+        &.{ "balancing_credit", "post_pending_transfer", "void_pending_transfer" },
+    };
+    return &.{};
+}
+
 fn emit_enum(
     buffer: *std.ArrayList(u8),
     comptime Type: type,
@@ -173,6 +220,59 @@ fn emit_enum(
     }
 
     try buffer.writer().print("}}\n\n", .{});
+
+    if (type_info.tag_type != u1) {
+        // Parsing from the name returned by String() (e.g. ParseCreateAccountResult()).
+        try buffer.writer().print("func Parse{s}(s string) ({s}, error) {{\n" ++
+            "\tswitch s {{\n", .{
+            name,
+            name,
+        });
+
+        inline for (type_info.fields) |field| {
+            const enum_name = prefix ++ comptime to_pascal_case(field.name, null);
+            try buffer.writer().print("\tcase \"{s}\":\n" ++
+                "\t\treturn {s}, nil\n", .{
+                enum_name,
+                enum_name,
+            });
+        }
+
+        try buffer.writer().print("\t}}\n" ++
+            "\treturn 0, unknownName(\"{s}\", s)\n" ++
+            "}}\n\n", .{name});
+
+        try emit_enum_marshal(buffer, name);
+    }
+}
+
+fn emit_enum_marshal(
+    buffer: *std.ArrayList(u8),
+    comptime name: []const u8,
+) !void {
+    try buffer.writer().print("func (i {s}) MarshalText() ([]byte, error) {{\n" ++
+        "\treturn []byte(i.String()), nil\n" ++
+        "}}\n\n" ++
+        "func (i *{s}) UnmarshalText(text []byte) error {{\n" ++
+        "\tvalue, err := Parse{s}(string(text))\n" ++
+        "\tif err != nil {{\n" ++
+        "\t\treturn err\n" ++
+        "\t}}\n" ++
+        "\t*i = value\n" ++
+        "\treturn nil\n" ++
+        "}}\n\n" ++
+        "func (i {s}) MarshalBinary() ([]byte, error) {{\n" ++
+        "\treturn marshalBinary(i)\n" ++
+        "}}\n\n" ++
+        "func (i *{s}) UnmarshalBinary(data []byte) error {{\n" ++
+        "\treturn unmarshalBinary(data, i)\n" ++
+        "}}\n\n", .{
+        name,
+        name,
+        name,
+        name,
+        name,
+    });
 }
 
 fn emit_packed_struct(
@@ -217,6 +317,176 @@ fn emit_packed_struct(
 
     try buffer.writer().print("\treturn ret\n" ++
         "}}\n\n", .{});
+
+    // The names of the flags, indexed by bit (e.g. accountFlagsNames).
+    const names = comptime to_lower_camel_case(name) ++ "Names";
+    try buffer.writer().print("var {s} = []string{{\n", .{names});
+    inline for (type_info.fields) |field| {
+        if (comptime std.mem.eql(u8, "padding", field.name)) continue;
+        try buffer.writer().print("\t\"{s}\",\n", .{field.name});
+    }
+    try buffer.writer().print("}}\n\n", .{});
+
+    // Conversion from packed to struct goes through the owning struct (e.g. Account.AccountFlags()).
+    const owner = comptime name[0 .. name.len - "Flags".len];
+    const to_int = "To" ++ comptime to_pascal_case(int_type, null);
+    try buffer.writer().print("func (f {s}) Names() []string {{\n" ++
+        "\treturn flagNames(uint64(f.{s}()), {s})\n" ++
+        "}}\n\n" ++
+        "func (f {s}) MarshalText() ([]byte, error) {{\n" ++
+        "\treturn []byte(formatFlagNames(uint64(f.{s}()), {s})), nil\n" ++
+        "}}\n\n" ++
+        "func (f *{s}) UnmarshalText(text []byte) error {{\n" ++
+        "\tflags, err := parseFlagNames(\"{s}\", string(text), {s})\n" ++
+        "\tif err != nil {{\n" ++
+        "\t\treturn err\n" ++
+        "\t}}\n" ++
+        "\t*f = {s}{{Flags: {s}(flags)}}.{s}()\n" ++
+        "\treturn nil\n" ++
+        "}}\n\n", .{
+        name,
+        to_int,
+        names,
+        name,
+        to_int,
+        names,
+        name,
+        name,
+        names,
+        owner,
+        int_type,
+        name,
+    });
+
+    try buffer.writer().print("func (f {s}) MarshalJSON() ([]byte, error) {{\n" ++
+        "\treturn json.Marshal(f.Names())\n" ++
+        "}}\n\n" ++
+        "func (f *{s}) UnmarshalJSON(data []byte) error {{\n" ++
+        "\tvar names []string\n" ++
+        "\tif err := json.Unmarshal(data, &names); err != nil {{\n" ++
+        "\t\treturn err\n" ++
+        "\t}}\n" ++
+        "\tflags, err := flagsFromNames(\"{s}\", names, {s})\n" ++
+        "\tif err != nil {{\n" ++
+        "\t\treturn err\n" ++
+        "\t}}\n" ++
+        "\t*f = {s}{{Flags: {s}(flags)}}.{s}()\n" ++
+        "\treturn nil\n" ++
+        "}}\n\n", .{
+        name,
+        name,
+        name,
+        names,
+        owner,
+        int_type,
+        name,
+    });
+
+    try buffer.writer().print("func (f {s}) MarshalBinary() ([]byte, error) {{\n" ++
+        "\treturn marshalBinary(f.{s}())\n" ++
+        "}}\n\n" ++
+        "func (f *{s}) UnmarshalBinary(data []byte) error {{\n" ++
+        "\tvar flags {s}\n" ++
+        "\tif err := unmarshalBinary(data, &flags); err != nil {{\n" ++
+        "\t\treturn err\n" ++
+        "\t}}\n" ++
+        "\t*f = {s}{{Flags: flags}}.{s}()\n" ++
+        "\treturn nil\n" ++
+        "}}\n\n", .{
+        name,
+        to_int,
+        name,
+        int_type,
+        owner,
+        name,
+    });
+
+    // A typed bitset of the flags (e.g. TransferFlag), with a constant for each flag.
+    const bitset = comptime name[0 .. name.len - 1];
+    try buffer.writer().print("type {s} {s}\n\n" ++
+        "const (\n", .{
+        bitset,
+        int_type,
+    });
+    inline for (type_info.fields, 0..) |field, i| {
+        if (comptime std.mem.eql(u8, "padding", field.name)) continue;
+        try buffer.writer().print("\t{s}{s} {s} = 1 << {d}\n", .{
+            bitset,
+            to_pascal_case(field.name, min_len),
+            bitset,
+            i,
+        });
+    }
+    try buffer.writer().print(")\n\n", .{});
+
+    try buffer.writer().print("func Parse{s}(s string) ({s}, error) {{\n" ++
+        "\tflags, err := parseFlags(\"{s}\", s, {s})\n" ++
+        "\treturn {s}(flags), err\n" ++
+        "}}\n\n" ++
+        "func (f {s}) Has(flags {s}) bool {{\n" ++
+        "\treturn f&flags == flags\n" ++
+        "}}\n\n" ++
+        "func (f {s}) With(flags {s}) {s} {{\n" ++
+        "\treturn f | flags\n" ++
+        "}}\n\n" ++
+        "func (f {s}) Without(flags {s}) {s} {{\n" ++
+        "\treturn f &^ flags\n" ++
+        "}}\n\n" ++
+        "func (f {s}) {s}() {s} {{\n" ++
+        "\treturn {s}(f)\n" ++
+        "}}\n\n" ++
+        "func (f {s}) String() string {{\n" ++
+        "\treturn formatFlags(uint64(f), {s})\n" ++
+        "}}\n\n", .{
+        name,
+        bitset,
+        name,
+        names,
+        bitset,
+        bitset,
+        bitset,
+        bitset,
+        bitset,
+        bitset,
+        bitset,
+        bitset,
+        bitset,
+        bitset,
+        to_int,
+        int_type,
+        int_type,
+        bitset,
+        names,
+    });
+
+    // Validation rejects reserved bits, and more than one flag of each mutually exclusive group.
+    const exclusive = comptime exclusive_flags(name);
+    if (exclusive.len == 0) {
+        try buffer.writer().print("func (f {s}) Validate() error {{\n" ++
+            "\treturn validateFlags(\"{s}\", uint64(f), {s}, nil)\n" ++
+            "}}\n\n", .{
+            bitset,
+            name,
+            names,
+        });
+    } else {
+        try buffer.writer().print("func (f {s}) Validate() error {{\n" ++
+            "\treturn validateFlags(\"{s}\", uint64(f), {s}, []uint64{{\n", .{
+            bitset,
+            name,
+            names,
+        });
+        inline for (exclusive) |group| {
+            try buffer.writer().print("\t\tuint64(", .{});
+            inline for (group, 0..) |flag, i| {
+                if (i > 0) try buffer.writer().print(" | ", .{});
+                try buffer.writer().print("{s}{s}", .{ bitset, to_pascal_case(flag, null) });
+            }
+            try buffer.writer().print("),\n", .{});
+        }
+        try buffer.writer().print("\t}})\n" ++
+            "}}\n\n", .{});
+    }
 }
 
 fn emit_struct(
@@ -229,30 +499,22 @@ fn emit_struct(
     });
 
     const min_len = calculate_min_len(type_info);
+    const type_min_len = calculate_type_min_len(type_info);
     comptime var flagsField = false;
     inline for (type_info.fields) |field| {
-        switch (@typeInfo(field.type)) {
-            .Array => |array| {
-                try buffer.writer().print("\t{s} [{d}]{s}\n", .{
-                    to_pascal_case(field.name, min_len),
-                    array.len,
-                    go_type(array.child),
-                });
-            },
-            else => {
-                if (comptime std.mem.eql(u8, field.name, "flags")) {
-                    flagsField = true;
-                }
-
-                try buffer.writer().print(
-                    "\t{s} {s}\n",
-                    .{
-                        to_pascal_case(field.name, min_len),
-                        go_type(field.type),
-                    },
-                );
-            },
+        if (comptime std.mem.eql(u8, field.name, "flags")) {
+            flagsField = true;
         }
+
+        // Reserved fields must be zero, so they are left out of JSON.
+        try buffer.writer().print(
+            "\t{s} {s} `json:\"{s}\"`\n",
+            .{
+                to_pascal_case(field.name, min_len),
+                pad_right(go_field_type(field.type), type_min_len),
+                if (comptime std.mem.eql(u8, field.name, "reserved")) "-" else field.name,
+            },
+        );
     }
 
     try buffer.writer().print("}}\n\n", .{});
@@ -295,7 +557,69 @@ fn emit_struct(
 
         try buffer.writer().print("\treturn f\n" ++
             "}}\n\n", .{});
+
+        // JSON with the flags both as a raw integer and as names (e.g. "flags", "flag_names").
+        const plain = comptime to_lower_camel_case(name);
+        const to_int = "To" ++ comptime to_pascal_case(go_type(flagType), null);
+        try buffer.writer().print("func (o {s}) MarshalJSON() ([]byte, error) {{\n" ++
+            "\ttype {s} {s}\n" ++
+            "\treturn json.Marshal(struct {{\n" ++
+            "\t\t{s}\n" ++
+            "\t\tFlagNames {s}Flags `json:\"flag_names\"`\n" ++
+            "\t}}{{{s}(o), o.{s}Flags()}})\n" ++
+            "}}\n\n", .{
+            name,
+            plain,
+            name,
+            plain,
+            name,
+            plain,
+            name,
+        });
+
+        try buffer.writer().print("func (o *{s}) UnmarshalJSON(data []byte) error {{\n" ++
+            "\ttype {s} {s}\n" ++
+            "\t// Decode into a zero value, so that no field of the target is kept.\n" ++
+            "\tvar decoded {s}\n" ++
+            "\tvalue := struct {{\n" ++
+            "\t\t*{s}\n" ++
+            "\t\tFlagNames *{s}Flags `json:\"flag_names\"`\n" ++
+            "\t}}{{{s}: (*{s})(&decoded)}}\n" ++
+            "\tif err := json.Unmarshal(data, &value); err != nil {{\n" ++
+            "\t\treturn err\n" ++
+            "\t}}\n" ++
+            "\tif value.FlagNames != nil {{\n" ++
+            "\t\tflags := value.FlagNames.{s}()\n" ++
+            "\t\tif decoded.Flags != 0 && decoded.Flags != flags {{\n" ++
+            "\t\t\treturn flagsMismatch(\"{s}\")\n" ++
+            "\t\t}}\n" ++
+            "\t\tdecoded.Flags = flags\n" ++
+            "\t}}\n" ++
+            "\t*o = decoded\n" ++
+            "\treturn nil\n" ++
+            "}}\n\n", .{
+            name,
+            plain,
+            name,
+            name,
+            plain,
+            name,
+            plain,
+            plain,
+            to_int,
+            name,
+        });
     }
+
+    try buffer.writer().print("func (o {s}) MarshalBinary() ([]byte, error) {{\n" ++
+        "\treturn marshalBinary(o)\n" ++
+        "}}\n\n" ++
+        "func (o *{s}) UnmarshalBinary(data []byte) error {{\n" ++
+        "\treturn unmarshalBinary(data, o)\n" ++
+        "}}\n\n", .{
+        name,
+        name,
+    });
 }
 
 pub fn generate_bindings(buffer: *std.ArrayList(u8)) !void {
@@ -313,11 +637,15 @@ pub fn generate_bindings(buffer: *std.ArrayList(u8)) !void {
         \\#include "../native/tb_client.h"
         \\*/
         \\import "C"
-        \\import "strconv"
         \\
         \\
     , .{});
 
+    try buffer.writer().print("import (\n" ++
+        "\t\"encoding/json\"\n" ++
+        "\t\"strconv\"\n" ++
+        ")\n\n", .{});
+
     // Emit Go declarations.
     inline for (type_mappings) |type_mapping| {
         const ZigType = type_mapping[0];